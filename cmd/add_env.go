@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var (
+	addEnvDescription string
+	addEnvFromEnv     []string
+	addEnvReplace     bool
+)
+
+var addEnvCmd = &cobra.Command{
+	Use:   "add-env <name> [KEY=VALUE...]",
+	Short: "Save a profile made of environment variables (API key, Bedrock, Vertex)",
+	Long: `Add-env creates a profile from environment variables instead of a
+claude.ai login — for Anthropic API keys, Amazon Bedrock, Google Vertex AI
+or an LLM gateway:
+
+  cs add-env api ANTHROPIC_API_KEY=sk-ant-... ANTHROPIC_MODEL=claude-sonnet-4-5
+  cs add-env bedrock CLAUDE_CODE_USE_BEDROCK=1 AWS_PROFILE=prod AWS_REGION=us-east-1
+  cs add-env gw ANTHROPIC_BASE_URL=https://llm.internal --from-env ANTHROPIC_AUTH_TOKEN
+
+Values are stored in the profile directory (encrypted if the vault is
+enabled), never in config.json. Use --from-env to copy a value from your
+current environment so secrets stay out of shell history.
+
+Environment profiles are used with 'cs exec <name>' and 'cs alias'; they
+cannot be made the global active profile.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		vars, err := profile.ParseEnvAssignments(args[1:])
+		if err != nil {
+			return err
+		}
+		for _, key := range addEnvFromEnv {
+			value, ok := os.LookupEnv(key)
+			if !ok {
+				return fmt.Errorf("--from-env %s: variable is not set in the current environment", key)
+			}
+			vars[key] = value
+		}
+
+		if err := config.EnsureDirs(); err != nil {
+			return err
+		}
+
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		mgr := profile.NewManager(cfg)
+		if err := mgr.AddEnv(name, addEnvDescription, vars, addEnvReplace); err != nil {
+			return err
+		}
+
+		p := cfg.Profiles[name]
+		ui.Success("Profile %q saved (%s, %d variable(s))", name, p.Provider, len(p.EnvVars))
+		if verbose {
+			ui.Info("Variables: %s", strings.Join(p.EnvVars, ", "))
+		}
+		ui.Info("Run it with: cs exec %s", name)
+		return nil
+	},
+}
+
+func init() {
+	addEnvCmd.Flags().StringVarP(&addEnvDescription, "description", "d", "", "Description for this profile")
+	addEnvCmd.Flags().StringSliceVar(&addEnvFromEnv, "from-env", nil, "Copy a variable's value from the current environment (repeatable)")
+	addEnvCmd.Flags().BoolVar(&addEnvReplace, "replace", false, "Overwrite an existing environment profile")
+	rootCmd.AddCommand(addEnvCmd)
+}
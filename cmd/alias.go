@@ -61,6 +61,11 @@ func printBashAliases(profiles []config.ProfileEntry) {
 	fmt.Println()
 
 	for _, p := range profiles {
+		if p.IsEnv() {
+			// Env profiles can't be switched to; run claude through exec.
+			fmt.Printf("alias claude-%s='claude-switch exec %s --'\n", p.Name, p.Name)
+			continue
+		}
 		safeName := strings.ReplaceAll(p.Name, "-", "_")
 		// Switch alias: cs-<name> switches profile
 		fmt.Printf("alias cs-%s='claude-switch use %s'\n", p.Name, p.Name)
@@ -88,6 +93,10 @@ func printFishAliases(profiles []config.ProfileEntry) {
 	fmt.Println()
 
 	for _, p := range profiles {
+		if p.IsEnv() {
+			fmt.Printf("alias claude-%s 'claude-switch exec %s --'\n", p.Name, p.Name)
+			continue
+		}
 		fmt.Printf("alias cs-%s 'claude-switch use %s'\n", p.Name, p.Name)
 		fmt.Printf("alias claude-%s 'claude-switch use %s; and claude'\n", p.Name, p.Name)
 		fmt.Printf("alias claude-%s-exec 'claude-switch exec %s --'\n", p.Name, p.Name)
@@ -102,6 +111,10 @@ func printPowerShellAliases(profiles []config.ProfileEntry) {
 	for _, p := range profiles {
 		funcName := strings.ReplaceAll(p.Name, "-", "")
 		titled := strings.ToUpper(funcName[:1]) + funcName[1:]
+		if p.IsEnv() {
+			fmt.Printf("function Claude%s { claude-switch exec %s -- @args }\n", titled, p.Name)
+			continue
+		}
 		fmt.Printf("function Switch-Claude%s { claude-switch use %s }\n", titled, p.Name)
 		fmt.Printf("function Claude%s { claude-switch use %s; claude @args }\n", titled, p.Name)
 	}
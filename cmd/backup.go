@@ -29,11 +29,7 @@ var backupListCmd = &cobra.Command{
 		}
 
 		entries, err := os.ReadDir(backupsDir)
-		if err != nil {
-			if os.IsNotExist(err) {
-				ui.Info("No backups found.")
-				return nil
-			}
+		if err != nil && !os.IsNotExist(err) {
 			return err
 		}
 
@@ -45,19 +41,13 @@ var backupListCmd = &cobra.Command{
 			}
 		}
 
-		if len(dirs) == 0 {
-			ui.Info("No backups found.")
-			return nil
-		}
-
 		// Sort by name (timestamp) descending (most recent first)
 		sort.Slice(dirs, func(i, j int) bool {
 			return dirs[i].Name() > dirs[j].Name()
 		})
 
-		table := ui.NewTable("#", "TIMESTAMP", "FILES")
-		for i, d := range dirs {
-			// Count files in backup
+		doc := backupListDoc{Backups: make([]backupDoc, 0, len(dirs))}
+		for _, d := range dirs {
 			files, _ := os.ReadDir(filepath.Join(backupsDir, d.Name()))
 			fileNames := make([]string, 0, len(files))
 			for _, f := range files {
@@ -65,11 +55,24 @@ var backupListCmd = &cobra.Command{
 					fileNames = append(fileNames, f.Name())
 				}
 			}
+			doc.Backups = append(doc.Backups, backupDoc{Timestamp: d.Name(), Files: fileNames})
+		}
+
+		if machineOutput() {
+			return writeOutput("BackupList", doc)
+		}
+
+		if len(dirs) == 0 {
+			ui.Info("No backups found.")
+			return nil
+		}
 
+		table := ui.NewTable("#", "TIMESTAMP", "FILES")
+		for i, b := range doc.Backups {
 			table.AddRow(
 				fmt.Sprintf("%d", i+1),
-				d.Name(),
-				strings.Join(fileNames, ", "),
+				b.Timestamp,
+				strings.Join(b.Files, ", "),
 			)
 		}
 
@@ -89,6 +92,12 @@ the specified backup. The timestamp should match one from 'cs backup list'.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		timestamp := args[0]
 
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
 		backupsDir, err := config.BackupsDir()
 		if err != nil {
 			return err
@@ -99,45 +108,16 @@ the specified backup. The timestamp should match one from 'cs backup list'.`,
 			return fmt.Errorf("backup %q not found — use 'cs backup list' to see available backups", timestamp)
 		}
 
-		claudeDir, err := config.ClaudeConfigDir()
+		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
-		// Restore credential files
-		restored := 0
-		for _, fname := range profile.CredentialFiles {
-			src := filepath.Join(backupDir, fname)
-			if !profile.FileExists(src) {
-				continue
-			}
-			dst := filepath.Join(claudeDir, fname)
-			if err := profile.CopyFile(src, dst); err != nil {
-				return fmt.Errorf("cannot restore %s: %w", fname, err)
-			}
-			restored++
-			if verbose {
-				ui.Info("Restored %s", fname)
-			}
-		}
-
-		// Restore home-level files
-		home, err := os.UserHomeDir()
-		if err == nil {
-			for _, fname := range profile.HomeCredentialFiles {
-				src := filepath.Join(backupDir, "home_"+fname)
-				if !profile.FileExists(src) {
-					continue
-				}
-				dst := filepath.Join(home, fname)
-				if err := profile.CopyFile(src, dst); err != nil {
-					return fmt.Errorf("cannot restore %s: %w", fname, err)
-				}
-				restored++
-				if verbose {
-					ui.Info("Restored %s", fname)
-				}
-			}
+		// Credentials, ~/.claude files and identity keys are restored as one
+		// unit; on failure the live session is put back as it was.
+		restored, err := profile.RestoreBackup(backupDir, cfg.Settings.AccountKeys())
+		if err != nil {
+			return err
 		}
 
 		if restored == 0 {
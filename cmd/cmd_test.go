@@ -1,9 +1,22 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/server"
 )
 
 // captureOutput runs a function and captures stdout.
@@ -273,3 +286,728 @@ func TestLimitsCommand(t *testing.T) {
 		t.Error("limits command produced no output")
 	}
 }
+
+func TestAddEnvCommand(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+
+	rootCmd.SetArgs([]string{"add-env", "api", "ANTHROPIC_API_KEY=sk-ant-very-secret-1234"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+
+	output := captureOutput(func() {
+		rootCmd.SetArgs([]string{"info", "api"})
+		_ = rootCmd.Execute()
+	})
+	if !strings.Contains(output, "api-key") {
+		t.Errorf("info should show the provider, got:\n%s", output)
+	}
+	if strings.Contains(output, "sk-ant-very-secret") || !strings.Contains(output, "****1234") {
+		t.Errorf("info should mask the API key, got:\n%s", output)
+	}
+
+	output = captureOutput(func() {
+		rootCmd.SetArgs([]string{"alias", "--shell", "bash"})
+		_ = rootCmd.Execute()
+	})
+	if !strings.Contains(output, "alias claude-api='claude-switch exec api --'") {
+		t.Errorf("alias should run env profiles through exec, got:\n%s", output)
+	}
+}
+
+func TestListJSONOutput(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { outputFormat = "text" }()
+
+	rootCmd.SetArgs([]string{"add-env", "api", "ANTHROPIC_API_KEY=sk-ant-very-secret-1234"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"list", "--output", "json"},
+		{"status", "--output", "json"},
+		{"info", "api", "--output", "json"},
+	} {
+		out := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			if err := rootCmd.Execute(); err != nil {
+				t.Errorf("%v failed: %v", args, err)
+			}
+		})
+
+		var doc struct {
+			APIVersion string          `json:"apiVersion"`
+			Kind       string          `json:"kind"`
+			Data       json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(out), &doc); err != nil {
+			t.Fatalf("%v did not print JSON: %v\n%s", args, err, out)
+		}
+		if doc.APIVersion != "cs/v1" || doc.Kind == "" {
+			t.Errorf("%v: unexpected envelope %q %q", args, doc.APIVersion, doc.Kind)
+		}
+		if strings.Contains(out, "sk-ant-very-secret") {
+			t.Errorf("%v leaked a secret:\n%s", args, out)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { outputFormat = "text" }()
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"list", "--output", "xml"}, exitUsage},
+		{[]string{"info", "missing", "--output", "text"}, exitNotFound},
+		{[]string{"current"}, exitNotFound},
+		{[]string{"list"}, exitOK},
+	}
+	for _, tt := range tests {
+		rootCmd.SetArgs(tt.args)
+		var err error
+		captureOutput(func() { err = rootCmd.Execute() })
+		if got := exitCodeFor(err); got != tt.want {
+			t.Errorf("%v: exit code %d, want %d (err: %v)", tt.args, got, tt.want, err)
+		}
+	}
+}
+
+func TestHookEnv(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+
+	rootCmd.SetArgs([]string{"import", "work"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	project := t.TempDir()
+	if err := os.WriteFile(project+"/.claude-profile", []byte("work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(hookStateVar, "")
+	t.Setenv(hookProfileVar, "")
+
+	var stmts []string
+	captureOutput(func() {
+		var err error
+		stmts, err = hookEnvStatements("bash", project)
+		if err != nil {
+			t.Fatalf("hookEnvStatements failed: %v", err)
+		}
+	})
+	script := strings.Join(stmts, "\n")
+	for _, want := range []string{"export CS_PROFILE='work';", "export CLAUDE_CONFIG_DIR=", "export _CS_HOOK_STATE="} {
+		if !strings.Contains(script, want) {
+			t.Errorf("hook statements missing %q:\n%s", want, script)
+		}
+	}
+
+	// With the state already recorded the hook does nothing.
+	t.Setenv(hookStateVar, hookState(project+"/.claude-profile"))
+	t.Setenv(hookProfileVar, "work")
+	if stmts, _ := hookEnvStatements("bash", project); len(stmts) != 0 {
+		t.Errorf("unchanged directory should produce no statements, got %v", stmts)
+	}
+
+	// Leaving the project unsets what the hook exported.
+	stmts, _ = hookEnvStatements("bash", t.TempDir())
+	script = strings.Join(stmts, "\n")
+	if !strings.Contains(script, "unset CS_PROFILE;") || !strings.Contains(script, "unset CLAUDE_CONFIG_DIR;") {
+		t.Errorf("leaving the project should unset the profile:\n%s", script)
+	}
+}
+
+func TestEnvCommand(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { envShell, envUnset = "", false }()
+
+	rootCmd.SetArgs([]string{"add-env", "api", "ANTHROPIC_API_KEY=sk-it's", "ANTHROPIC_MODEL=opus"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+
+	tests := map[string][]string{
+		"bash":       {`export ANTHROPIC_API_KEY='sk-it'\''s';`, "export CS_PROFILE='api';", "export CLAUDE_CONFIG_DIR="},
+		"fish":       {`set -gx ANTHROPIC_API_KEY 'sk-it\'s';`, "set -gx CS_PROFILE 'api';"},
+		"powershell": {`$env:ANTHROPIC_API_KEY = 'sk-it''s'`, "$env:CS_PROFILE = 'api'"},
+	}
+	for shell, wants := range tests {
+		out := captureOutput(func() {
+			rootCmd.SetArgs([]string{"env", "api", "--shell", shell})
+			if err := rootCmd.Execute(); err != nil {
+				t.Errorf("env --shell %s failed: %v", shell, err)
+			}
+		})
+		for _, want := range wants {
+			if !strings.Contains(out, want) {
+				t.Errorf("env --shell %s missing %q:\n%s", shell, want, out)
+			}
+		}
+	}
+
+	out := captureOutput(func() {
+		rootCmd.SetArgs([]string{"env", "api", "--shell", "bash", "--unset"})
+		_ = rootCmd.Execute()
+	})
+	if !strings.Contains(out, "unset ANTHROPIC_API_KEY;") || !strings.Contains(out, "unset CLAUDE_CONFIG_DIR;") {
+		t.Errorf("env --unset output:\n%s", out)
+	}
+}
+
+func TestExecProgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	program, args := execProgram([]string{"sh", "-c", "true"}, false)
+	if program == "" || len(args) != 2 {
+		t.Errorf("executable should run directly, got %q %v", program, args)
+	}
+
+	for _, tt := range [][]string{
+		{"-p", "hello"},
+		{"definitely-not-a-command-xyz", "hi"},
+	} {
+		if program, args := execProgram(tt, false); program != "" || len(args) != len(tt) {
+			t.Errorf("%v should go to claude, got %q %v", tt, program, args)
+		}
+	}
+
+	if program, _ := execProgram([]string{"sh"}, true); program != "" {
+		t.Errorf("--claude should force claude, got %q", program)
+	}
+}
+
+func TestExecAll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() {
+		execAllProfiles, execAllTag, execAllLogDir, execAllGroup = nil, "", "", false
+	}()
+
+	for _, name := range []string{"one", "two"} {
+		rootCmd.SetArgs([]string{"add-env", name, "ANTHROPIC_API_KEY=sk-" + name})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("add-env %s failed: %v", name, err)
+		}
+	}
+	rootCmd.SetArgs([]string{"tag", "two", "batch"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+
+	logDir := t.TempDir()
+	var err error
+	captureOutput(func() {
+		rootCmd.SetArgs([]string{"exec-all", "--log-dir", logDir, "--", "sh", "-c", `echo "$ANTHROPIC_API_KEY"; [ "$ANTHROPIC_API_KEY" = sk-one ]`})
+		err = rootCmd.Execute()
+	})
+	if code := exitCodeFor(err); code != exitFailure {
+		t.Errorf("exit code = %d, want %d (err %v)", code, exitFailure, err)
+	}
+	for _, name := range []string{"one", "two"} {
+		data, readErr := os.ReadFile(logDir + "/" + name + ".log")
+		if readErr != nil || strings.TrimSpace(string(data)) != "sk-"+name {
+			t.Errorf("log of %s = %q (%v)", name, data, readErr)
+		}
+	}
+
+	execAllLogDir = ""
+	out := captureOutput(func() {
+		rootCmd.SetArgs([]string{"exec-all", "--tag", "batch", "--", "sh", "-c", "printf done"})
+		err = rootCmd.Execute()
+	})
+	if err != nil {
+		t.Errorf("exec-all --tag failed: %v", err)
+	}
+	if !strings.Contains(out, "[two] ") || !strings.Contains(out, "done\n") || strings.Contains(out, "[one]") {
+		t.Errorf("exec-all --tag output:\n%s", out)
+	}
+}
+
+func TestConfigEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	t.Setenv("VISUAL", "")
+
+	editor := func(script string) {
+		t.Helper()
+		path := t.TempDir() + "/editor"
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("EDITOR", path)
+	}
+	autoBackup := func() bool {
+		t.Helper()
+		cfg, err := config.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg.Settings.AutoBackup
+	}
+
+	editor(`printf '{"settings":{"auto_backup":false}}' > "$1"`)
+	rootCmd.SetArgs([]string{"config", "edit"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config edit failed: %v", err)
+	}
+	if autoBackup() {
+		t.Error("edited setting was not saved")
+	}
+
+	editor(`printf '{"settings":' > "$1"`)
+	rootCmd.SetArgs([]string{"config", "edit"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid config JSON") {
+		t.Errorf("invalid edit: err = %v", err)
+	}
+
+	// Another command saves the config while the editor is open.
+	cfgPath, _ := config.ConfigPath()
+	editor(`printf '{"settings":{"auto_backup":true}}' > "$1"; printf '{"active_profile":"other"}' > "` + cfgPath + `"`)
+	rootCmd.SetArgs([]string{"config", "edit"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "changed by another cs command") {
+		t.Errorf("concurrent edit: err = %v", err)
+	}
+	if cfg, _ := config.Load(); cfg.ActiveProfile != "other" {
+		t.Errorf("concurrent change was lost: active profile %q", cfg.ActiveProfile)
+	}
+}
+
+func TestExecAllStopsOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh and SIGTERM")
+	}
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { execAllParallel = 4 }()
+
+	for _, name := range []string{"one", "two", "three"} {
+		rootCmd.SetArgs([]string{"add-env", name, "ANTHROPIC_API_KEY=sk-" + name})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("add-env %s failed: %v", name, err)
+		}
+	}
+
+	// The first command to run terminates cs; the others must not start.
+	script := `trap '' TERM; kill -TERM $PPID; sleep 0.5`
+	var err error
+	out := captureOutput(func() {
+		rootCmd.SetArgs([]string{"exec-all", "--parallel", "1", "--", "sh", "-c", script})
+		err = rootCmd.Execute()
+	})
+	if code := exitCodeFor(err); code != 143 {
+		t.Errorf("exit code = %d, want 143 (err %v)", code, err)
+	}
+	if n := strings.Count(out, "interrupted"); n != 2 {
+		t.Errorf("%d profiles marked interrupted, want 2:\n%s", n, out)
+	}
+}
+
+func TestExecPoolFailover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { execPool = "" }()
+
+	for _, name := range []string{"first", "second"} {
+		rootCmd.SetArgs([]string{"add-env", name, "ANTHROPIC_API_KEY=sk-" + name})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("add-env %s failed: %v", name, err)
+		}
+	}
+	rootCmd.SetArgs([]string{"pool", "add", "main", "first", "second"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("pool add failed: %v", err)
+	}
+
+	script := `echo "ran $ANTHROPIC_API_KEY"; [ "$ANTHROPIC_API_KEY" = sk-second ] && exit 0; echo "5-hour limit reached - resets 3pm" >&2; exit 1`
+	var err error
+	out := captureOutput(func() {
+		rootCmd.SetArgs([]string{"exec", "--pool", "main", "--", "sh", "-c", script})
+		err = rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("exec --pool failed: %v", err)
+	}
+	if !strings.Contains(out, "ran sk-second") || strings.Contains(out, "ran sk-first") {
+		t.Errorf("only the successful run's output should be printed:\n%s", out)
+	}
+
+	limits, loadErr := claude.LoadLimits()
+	if loadErr != nil {
+		t.Fatalf("LoadLimits failed: %v", loadErr)
+	}
+	if _, ok := limits.Active("first", time.Now()); !ok {
+		t.Error("the limit of profile first was not recorded")
+	}
+	if _, ok := limits.Active("second", time.Now()); ok {
+		t.Error("profile second should not be limited")
+	}
+}
+
+func TestLimitsFromTranscripts(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { outputFormat = "text" }()
+
+	rootCmd.SetArgs([]string{"import", "work"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	rootCmd.SetArgs([]string{"add-env", "spare", "ANTHROPIC_API_KEY=sk-spare"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+
+	// A usage limit Claude Code wrote into a transcript while "work" was active.
+	now := time.Now()
+	reset := now.Add(2 * time.Hour).Truncate(time.Second)
+	line := `{"type":"assistant","timestamp":"` + now.UTC().Format(time.RFC3339Nano) + `","isApiErrorMessage":true,` +
+		`"message":{"model":"<synthetic>","content":[{"type":"text","text":"Claude AI usage limit reached|` +
+		strconv.FormatInt(reset.Unix(), 10) + `"}]}}` + "\n"
+	projectDir := os.Getenv("HOME") + "/.claude/projects/p"
+	if err := os.MkdirAll(projectDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(projectDir+"/s.jsonl", []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureOutput(func() {
+		rootCmd.SetArgs([]string{"limits", "--output", "json"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Errorf("limits failed: %v", err)
+		}
+	})
+	var doc struct {
+		Data struct {
+			RateLimited bool `json:"rate_limited"`
+			Profiles    []struct {
+				Profile string     `json:"profile"`
+				Limited bool       `json:"limited"`
+				Kind    string     `json:"kind"`
+				ResetAt *time.Time `json:"reset_at"`
+			} `json:"profiles"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("limits did not print JSON: %v\n%s", err, out)
+	}
+	if !doc.Data.RateLimited {
+		t.Error("the active profile should be rate limited")
+	}
+	limited := map[string]bool{}
+	for _, p := range doc.Data.Profiles {
+		limited[p.Profile] = p.Limited
+		if p.Profile == "work" && (p.Kind != "usage" || p.ResetAt == nil || !p.ResetAt.Equal(reset)) {
+			t.Errorf("unexpected limit of work: %+v", p)
+		}
+	}
+	if !limited["work"] || limited["spare"] || len(limited) != 2 {
+		t.Errorf("unexpected limit states: %v", limited)
+	}
+}
+
+func TestLimitsAllAndProfile(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { outputFormat = "text"; limitsAll = false; limitsProfile = "" }()
+
+	rootCmd.SetArgs([]string{"import", "work"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	rootCmd.SetArgs([]string{"add-env", "spare", "ANTHROPIC_API_KEY=sk-spare"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+	now := time.Now()
+	store := claude.LimitStore{"spare": {Kind: claude.LimitRate, HitAt: now, ResetAt: now.Add(time.Minute)}}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureOutput(func() {
+		rootCmd.SetArgs([]string{"limits", "--all", "--output", "json"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Errorf("limits --all failed: %v", err)
+		}
+	})
+	var doc struct {
+		Kind string `json:"kind"`
+		Data struct {
+			Profiles []struct {
+				Profile     string `json:"profile"`
+				Email       string `json:"email"`
+				RateLimited bool   `json:"rate_limited"`
+			} `json:"profiles"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("limits --all did not print JSON: %v\n%s", err, out)
+	}
+	if doc.Kind != "UsageInfoList" || len(doc.Data.Profiles) != 2 {
+		t.Fatalf("unexpected document: %s", out)
+	}
+	for _, p := range doc.Data.Profiles {
+		if p.RateLimited != (p.Profile == "spare") {
+			t.Errorf("%s: rate_limited = %v", p.Profile, p.RateLimited)
+		}
+		if p.Profile == "work" && p.Email != "test@example.com" {
+			t.Errorf("work: email = %q", p.Email)
+		}
+	}
+
+	outputFormat = "text"
+	limitsAll = false
+	out = captureOutput(func() {
+		rootCmd.SetArgs([]string{"limits", "--profile", "spare"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Errorf("limits --profile failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Claude Usage - spare") || !strings.Contains(out, "Resets in") {
+		t.Errorf("limits --profile spare:\n%s", out)
+	}
+
+	var err error
+	captureOutput(func() {
+		rootCmd.SetArgs([]string{"limits", "--all", "--profile", "spare"})
+		err = rootCmd.Execute()
+	})
+	if code := exitCodeFor(err); code != exitUsage {
+		t.Errorf("--all with --profile: exit code %d, want %d", code, exitUsage)
+	}
+}
+
+func TestWaitReset(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	cleanup := setupTestHome(t)
+	defer cleanup()
+	defer func() { waitResetProfile = ""; waitResetAny = false; waitResetThenUse = false; waitResetThenExec = false }()
+
+	for _, name := range []string{"work", "other"} {
+		rootCmd.SetArgs([]string{"import", name})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("import %s failed: %v", name, err)
+		}
+	}
+	rootCmd.SetArgs([]string{"add-env", "spare", "ANTHROPIC_API_KEY=sk-spare"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+	now := time.Now()
+	store := claude.LimitStore{
+		"work":  {Kind: claude.LimitUsage, HitAt: now, ResetAt: now.Add(time.Hour)},
+		"other": {Kind: claude.LimitRate, HitAt: now, ResetAt: now.Add(1500 * time.Millisecond)},
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	start := time.Now()
+	out := captureOutput(func() {
+		rootCmd.SetArgs([]string{"wait-reset", "--any", "--then-use"})
+		err = rootCmd.Execute()
+	})
+	if err != nil {
+		t.Fatalf("wait-reset failed: %v", err)
+	}
+	if strings.TrimSpace(out) != "other" {
+		t.Errorf("wait-reset printed %q, want the profile name", out)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("returned after %s, before the reset", waited)
+	}
+	out = captureOutput(func() {
+		rootCmd.SetArgs([]string{"current"})
+		_ = rootCmd.Execute()
+	})
+	if !strings.Contains(out, "other") {
+		t.Errorf("--then-use did not switch to other: %q", out)
+	}
+
+	waitResetAny, waitResetThenUse = false, false
+	out = captureOutput(func() {
+		rootCmd.SetArgs([]string{"wait-reset", "--profile", "spare", "--then-exec", "--", "sh", "-c", `echo "$ANTHROPIC_API_KEY"; exit 3`})
+		err = rootCmd.Execute()
+	})
+	if code := exitCodeFor(err); code != 3 {
+		t.Errorf("exit code = %d, want the command's 3 (err %v)", code, err)
+	}
+	if strings.TrimSpace(out) != "sk-spare" {
+		t.Errorf("--then-exec output = %q", out)
+	}
+}
+
+func TestProxyAuthAndListen(t *testing.T) {
+	cleanup := setupTestHome(t)
+	defer cleanup()
+
+	rootCmd.SetArgs([]string{"add-env", "api", "ANTHROPIC_API_KEY=sk-api"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+	rootCmd.SetArgs([]string{"add-env", "gw", "ANTHROPIC_AUTH_TOKEN=tok-gw", "ANTHROPIC_BASE_URL=https://gw.example"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+
+	if auth, err := proxyAuth("api"); err != nil || auth.APIKey != "sk-api" {
+		t.Errorf("proxyAuth(api) = %+v, %v", auth, err)
+	}
+	if auth, err := proxyAuth("gw"); err != nil || auth.BearerToken != "tok-gw" || auth.OAuth {
+		t.Errorf("proxyAuth(gw) = %+v, %v", auth, err)
+	}
+	if _, err := proxyAuth("nobody"); err == nil {
+		t.Error("proxyAuth of an unknown profile should fail")
+	}
+
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:8787": true,
+		"[::1]:8787":     true,
+		"localhost:8787": true,
+		"0.0.0.0:8787":   false,
+		":8787":          false,
+		"10.0.0.5:8787":  false,
+	} {
+		if err := checkLoopback(addr); (err == nil) != ok {
+			t.Errorf("checkLoopback(%q) = %v", addr, err)
+		}
+	}
+}
+
+func TestServeAPI(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a Unix socket")
+	}
+	cleanup := setupTestHome(t)
+	defer cleanup()
+
+	for _, name := range []string{"work", "other"} {
+		rootCmd.SetArgs([]string{"import", name})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("import %s failed: %v", name, err)
+		}
+	}
+	rootCmd.SetArgs([]string{"add-env", "spare", "ANTHROPIC_API_KEY=sk-spare"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("add-env failed: %v", err)
+	}
+
+	api := newServeAPI()
+	if err := api.watcher.Poll(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	socketPath, err := server.SocketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := server.Listen(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: api.routes()}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	call := func(method, path, body string, wantStatus int) map[string]interface{} {
+		t.Helper()
+		req, _ := http.NewRequest(method, "http://cs"+path, strings.NewReader(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var doc struct {
+			Kind string                 `json:"kind"`
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+			t.Fatalf("%s %s: invalid JSON: %v", method, path, err)
+		}
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("%s %s = %d %v, want %d", method, path, resp.StatusCode, doc.Data, wantStatus)
+		}
+		return doc.Data
+	}
+
+	resp, err := client.Get("http://cs/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	if line, _ := events.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("event stream began with %q", line)
+	}
+
+	if data := call("GET", "/v1/profiles", "", http.StatusOK); len(data["profiles"].([]interface{})) != 3 {
+		t.Errorf("profiles = %v", data["profiles"])
+	}
+	data := call("POST", "/v1/use", `{"profile":"other"}`, http.StatusOK)
+	if name := data["profile"].(map[string]interface{})["name"]; name != "other" {
+		t.Errorf("use returned profile %v", name)
+	}
+	for line := ""; !strings.HasPrefix(line, "data:"); {
+		if line, err = events.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data:") && !strings.Contains(line, `"to":"other"`) {
+			t.Errorf("switch event = %q", line)
+		}
+	}
+	call("POST", "/v1/use", `{"profile":"nobody"}`, http.StatusNotFound)
+	call("POST", "/v1/use", `{"profile":"spare"}`, http.StatusBadRequest)
+	if data := call("GET", "/v1/status/other", "", http.StatusOK); data["active"] != true {
+		t.Errorf("token status = %v", data)
+	}
+	if data := call("GET", "/v1/limits?profile=work", "", http.StatusOK); data["profile"] != "work" {
+		t.Errorf("limits = %v", data)
+	}
+
+	session := call("POST", "/v1/sessions", `{"profile":"spare"}`, http.StatusOK)
+	env := session["env"].(map[string]interface{})
+	dir, _ := session["config_dir"].(string)
+	if env["ANTHROPIC_API_KEY"] != "sk-spare" || env["CLAUDE_CONFIG_DIR"] != dir {
+		t.Errorf("session env = %v", env)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("session directory missing: %v", err)
+	}
+	if data := call("GET", "/v1/sessions", "", http.StatusOK); len(data["sessions"].([]interface{})) != 1 {
+		t.Errorf("sessions = %v", data["sessions"])
+	}
+	id, _ := session["id"].(string)
+	call("DELETE", "/v1/sessions/"+id, "", http.StatusOK)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("session directory left behind: %v", err)
+	}
+	call("DELETE", "/v1/sessions/"+id, "", http.StatusNotFound)
+}
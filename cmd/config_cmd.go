@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/caeser1996/claude-switch/internal/config"
@@ -38,23 +40,17 @@ var configShowCmd = &cobra.Command{
 var configEditCmd = &cobra.Command{
 	Use:   "edit",
 	Short: "Open config file in $EDITOR",
+	Long: `Edit opens a copy of the config file in $EDITOR and saves it back once
+the editor exits and the result is valid. If another cs command changed the
+config in the meantime, nothing is saved and the edited copy is kept.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Ensure config file exists
-		if err := config.EnsureDirs(); err != nil {
-			return err
-		}
-
 		cfgPath, err := config.ConfigPath()
 		if err != nil {
 			return err
 		}
-
-		// Create config if it doesn't exist
-		if _, statErr := os.Stat(cfgPath); os.IsNotExist(statErr) {
-			cfg := config.NewConfig()
-			if err := cfg.Save(); err != nil {
-				return err
-			}
+		original, err := readConfigForEdit()
+		if err != nil {
+			return err
 		}
 
 		editor := os.Getenv("EDITOR")
@@ -74,19 +70,112 @@ var configEditCmd = &cobra.Command{
 			return fmt.Errorf("no editor found — set $EDITOR environment variable")
 		}
 
+		// Edit a copy: the state lock can't be held while the editor is open,
+		// and a half-written file must never be the live config.
+		tmp, err := os.CreateTemp(filepath.Dir(cfgPath), "config-edit-*.json")
+		if err != nil {
+			return fmt.Errorf("cannot create a copy to edit: %w", err)
+		}
+		editPath := tmp.Name()
+		_, err = tmp.Write(original)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(editPath)
+			return fmt.Errorf("cannot create a copy to edit: %w", err)
+		}
+
 		if verbose {
 			ui.Info("Opening %s with %s", cfgPath, editor)
 		}
 
-		c := exec.Command(editor, cfgPath)
+		c := exec.Command(editor, editPath)
 		c.Stdin = os.Stdin
 		c.Stdout = os.Stdout
 		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			os.Remove(editPath)
+			return err
+		}
 
-		return c.Run()
+		saved, err := saveEditedConfig(editPath, original)
+		if err != nil {
+			return err
+		}
+		os.Remove(editPath)
+		if saved {
+			ui.Success("Config saved")
+		}
+		return nil
 	},
 }
 
+// readConfigForEdit returns the contents of the config file, creating it
+// first if it doesn't exist.
+func readConfigForEdit() ([]byte, error) {
+	unlock, err := lockState()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, statErr := os.Stat(cfgPath); os.IsNotExist(statErr) {
+		if err := config.NewConfig().Save(); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config: %w", err)
+	}
+	return data, nil
+}
+
+// saveEditedConfig validates the edited copy at editPath and saves it as
+// the config, unless the config no longer holds original. It reports
+// whether anything was saved; on error the copy is left in place.
+func saveEditedConfig(editPath string, original []byte) (bool, error) {
+	edited, err := os.ReadFile(editPath)
+	if err != nil {
+		return false, fmt.Errorf("cannot read edited config: %w", err)
+	}
+	if bytes.Equal(edited, original) {
+		return false, nil
+	}
+
+	cfg := config.NewConfig()
+	if err := json.Unmarshal(edited, cfg); err != nil {
+		return false, fmt.Errorf("invalid config JSON: %w (your edits are in %s)", err, editPath)
+	}
+
+	unlock, err := lockState()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	cfgPath, err := config.ConfigPath()
+	if err != nil {
+		return false, err
+	}
+	current, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return false, fmt.Errorf("cannot read config: %w", err)
+	}
+	if !bytes.Equal(current, original) {
+		return false, fmt.Errorf("the config was changed by another cs command while you were editing; nothing was saved (your edits are in %s)", editPath)
+	}
+	if err := cfg.Save(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 var configPathCmd = &cobra.Command{
 	Use:   "path",
 	Short: "Print the config file path",
@@ -21,7 +21,14 @@ var currentCmd = &cobra.Command{
 		mgr := profile.NewManager(cfg)
 		p, err := mgr.Current()
 		if err != nil {
-			return err
+			return withExitCode(exitNotFound, err)
+		}
+
+		if machineOutput() {
+			return writeOutput("CurrentProfile", currentDoc{
+				Profile: newProfileDoc(*p),
+				Drift:   newDriftDoc(mgr.DetectDrift()),
+			})
 		}
 
 		fmt.Println(ui.Colorize(ui.Green, p.Name))
@@ -36,6 +43,8 @@ var currentCmd = &cobra.Command{
 			fmt.Printf("  Created:     %s\n", p.CreatedAt.Format("2006-01-02 15:04:05"))
 		}
 
+		warnDrift(mgr)
+
 		return nil
 	},
 }
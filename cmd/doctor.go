@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/caeser1996/claude-switch/internal/config"
 	"github.com/caeser1996/claude-switch/internal/doctor"
@@ -16,8 +18,19 @@ var doctorCmd = &cobra.Command{
 		}
 
 		results := doctor.RunAll(cfg)
-		doctor.PrintResults(results)
+		doc := newDoctorDoc(results)
+
+		if machineOutput() {
+			if err := writeOutput("DoctorReport", doc); err != nil {
+				return err
+			}
+		} else {
+			doctor.PrintResults(results)
+		}
 
+		if !doc.Healthy {
+			return withExitCode(exitUnhealthy, fmt.Errorf("%d check(s) failed", doc.Summary.Fail))
+		}
 		return nil
 	},
 }
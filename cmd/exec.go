@@ -1,6 +1,14 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+
 	"github.com/spf13/cobra"
 	"github.com/caeser1996/claude-switch/internal/claude"
 	"github.com/caeser1996/claude-switch/internal/config"
@@ -8,54 +16,203 @@ import (
 	"github.com/caeser1996/claude-switch/internal/ui"
 )
 
+var (
+	execNoWriteback bool
+	execClaude      bool
+	execCwd         string
+	execEnv         []string
+	execPool        string
+)
+
 var execCmd = &cobra.Command{
-	Use:   "exec <profile> [--] <command> [args...]",
+	Use:   "exec <profile|--pool name> [--] <command> [args...]",
 	Short: "Run a command with a specific profile's credentials",
-	Long: `Exec runs the claude binary using the specified profile's credentials
-in an isolated environment, without switching your active profile.
+	Long: `Exec runs a command using the specified profile's credentials in an
+isolated environment, without switching your active profile.
+
+If the first argument is an executable on PATH it is run directly, so
+'cs exec work -- npm test' runs npm with CLAUDE_CONFIG_DIR pointing at the
+profile. Otherwise the arguments are passed to claude, as they are with
+--claude: 'cs exec work -- -p "hello"' runs 'claude -p "hello"'.
 
 The current profile remains unchanged. Shared resources like commands,
 skills, and settings are symlinked into the isolated environment.
 
+Credentials refreshed during the run are saved back into the profile when
+the command exits, unless --no-writeback is given. Termination signals are
+passed on to the command, cs exits with the command's exit status, and the
+isolated environment is removed even when the run is interrupted.
+
+With --pool, no profile is named: the command runs under the first
+profile of the pool that isn't known to be limited. If the run fails with
+a usage-limit or 429 message, the reset time is recorded on that profile
+and the command is run again under the next one. Its output is held back
+until a run succeeds, and piped input is replayed to each attempt, so
+this suits headless use ('claude -p').
+
 Use -- to separate the profile name from the command arguments.`,
-	Args:               cobra.MinimumNArgs(2),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if execPool != "" {
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+		return cobra.MinimumNArgs(2)(cmd, args)
+	},
 	DisableFlagParsing: false,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if execPool != "" {
+			return execWithPool(execPool, args)
+		}
+
+		// The command's stdout is its own; notices go to stderr.
+		defer ui.SetOutput(os.Stderr)()
+
 		profileName := args[0]
-		cmdArgs := args[1:]
+		program, cmdArgs := execProgram(args[1:], execClaude)
 
-		cfg, err := config.Load()
+		extraEnv, err := profile.ParseEnvAssignments(execEnv)
 		if err != nil {
-			return err
+			return withExitCode(exitUsage, err)
 		}
 
-		if verbose {
-			ui.Info("Setting up isolated environment for profile %q...", profileName)
-		}
+		// From here until the environment is removed, termination signals
+		// must not kill cs; claude.Run forwards them to the command.
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, claude.ForwardedSignals...)
+		defer signal.Stop(interrupted)
 
-		env, err := profile.SetupIsolatedEnv(profileName, cfg)
+		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
-		defer func() {
-			if verbose {
-				ui.Info("Cleaning up isolated environment...")
-			}
-			_ = env.Cleanup()
-		}()
 
+		runErr := runInProfile(cfg, profileName, extraEnv, interrupted, claude.RunOptions{
+			Program:          program,
+			Args:             cmdArgs,
+			Dir:              execCwd,
+			ForwardInterrupt: !ui.IsTerminal(),
+		})
+		return execResult(program, runErr)
+	},
+}
+
+// runInProfile runs a command in a fresh isolated environment of the
+// profile, saves refreshed credentials back unless --no-writeback, and
+// removes the environment. opts.Env is filled in from the environment and
+// extraEnv. The result is the error from claude.Run, a setup error, or an
+// exit status if a signal arrived before the command started.
+func runInProfile(cfg *config.Config, profileName string, extraEnv map[string]string, interrupted <-chan os.Signal, opts claude.RunOptions) error {
+	if verbose {
+		ui.Info("Setting up isolated environment for profile %q...", profileName)
+	}
+
+	env, err := profile.SetupIsolatedEnv(profileName, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
 		if verbose {
-			ui.Info("Running: claude %v", cmdArgs)
-			ui.Info("CLAUDE_CONFIG_DIR=%s", env.TempDir)
+			ui.Info("Cleaning up isolated environment...")
 		}
+		_ = env.Cleanup()
+	}()
 
-		return claude.Run(claude.RunOptions{
-			Args: cmdArgs,
-			Env:  env.Env(),
-		})
-	},
+	select {
+	case sig := <-interrupted:
+		return exitStatus(claude.SignalExitCode(sig))
+	default:
+	}
+
+	if verbose {
+		name := opts.Program
+		if name == "" {
+			name = "claude"
+		}
+		ui.Info("Running: %s %v", name, opts.Args)
+		ui.Info("CLAUDE_CONFIG_DIR=%s", env.TempDir)
+	}
+
+	opts.Env = env.Env()
+	for _, key := range sortedEnvKeys(extraEnv) {
+		opts.Env = append(opts.Env, key+"="+extraEnv[key])
+	}
+
+	runErr := claude.Run(opts)
+
+	if !execNoWriteback {
+		writeBackCredentials(env)
+	}
+	return runErr
+}
+
+// execResult turns the result of runInProfile into exec's own: the
+// command's exit status, or an error if it could not be run.
+func execResult(program string, runErr error) error {
+	if code, ok := claude.ExitCode(runErr); ok {
+		return exitStatus(code)
+	}
+	var ee *exitError
+	if runErr != nil && program != "" && !errors.As(runErr, &ee) {
+		return fmt.Errorf("cannot run %s: %w", program, runErr)
+	}
+	return runErr
+}
+
+// execProgram splits exec's arguments into the program to run and its
+// arguments. The program is "" (claude) when forced, or when the first
+// argument is not an executable on PATH.
+func execProgram(args []string, forceClaude bool) (string, []string) {
+	if forceClaude || len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", args
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return "", args
+	}
+	return path, args[1:]
+}
+
+// sortedEnvKeys returns the keys of vars in sorted order.
+func sortedEnvKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeBackCredentials saves credentials refreshed during an exec run into
+// the profile. Failures are reported on stderr but don't change the result
+// of the run.
+func writeBackCredentials(env *profile.IsolatedEnv) {
+	unlock, err := lockState()
+	if err != nil {
+		ui.Warn("Credentials refreshed during the run were not saved: %s", err)
+		return
+	}
+	defer unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		ui.Warn("Credentials refreshed during the run were not saved: %s", err)
+		return
+	}
+
+	written, err := env.WriteBack(cfg)
+	if err != nil {
+		ui.Warn("%s", err)
+		return
+	}
+	if len(written) > 0 && verbose {
+		ui.Info("Saved %s back to profile %q", strings.Join(written, ", "), env.ProfileName)
+	}
 }
 
 func init() {
+	execCmd.Flags().BoolVar(&execNoWriteback, "no-writeback", false, "Don't save credentials refreshed during the run back into the profile")
+	execCmd.Flags().BoolVar(&execClaude, "claude", false, "Pass all arguments to claude, even if the first one is an executable")
+	execCmd.Flags().StringVar(&execCwd, "cwd", "", "Run the command in this directory")
+	execCmd.Flags().StringArrayVar(&execEnv, "env", nil, "Set an extra environment variable (KEY=VALUE, repeatable)")
+	execCmd.Flags().StringVar(&execPool, "pool", "", "Run under the first profile of this pool that isn't rate-limited, failing over to the next")
 	rootCmd.AddCommand(execCmd)
 }
@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var (
+	execAllProfiles    []string
+	execAllTag         string
+	execAllParallel    int
+	execAllGroup       bool
+	execAllLogDir      string
+	execAllClaude      bool
+	execAllNoWriteback bool
+	execAllCwd         string
+	execAllEnv         []string
+)
+
+var execAllCmd = &cobra.Command{
+	Use:   "exec-all [--profiles a,b | --tag x] [--] <command> [args...]",
+	Short: "Run a command under several profiles concurrently",
+	Long: `Exec-all runs the same command once per profile, each in its own
+isolated environment as 'cs exec' would, up to --parallel at a time. It
+runs under every profile unless --profiles or --tag narrows the set.
+
+Each line of output is prefixed with the profile name. With --group the
+output of each profile is printed in one block when it finishes, and with
+--log-dir it is written to <dir>/<profile>.log instead. The commands get
+no input.
+
+A summary of exit codes and durations is printed at the end; cs exits
+with status 1 if any command failed. With --output json, yaml or tsv the
+summary is the only thing written to stdout and command output goes to
+stderr, unless --log-dir is given.
+
+On SIGINT or SIGTERM the running commands get the signal and those not
+started yet are skipped.
+
+Examples:
+  cs exec-all -- -p "Summarise README.md"
+  cs exec-all --tag batch --parallel 2 --log-dir logs -- ./run-job.sh`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(execAllProfiles) > 0 && execAllTag != "" {
+			return withExitCode(exitUsage, fmt.Errorf("use either --profiles or --tag, not both"))
+		}
+		if execAllParallel < 1 {
+			return withExitCode(exitUsage, fmt.Errorf("--parallel must be at least 1"))
+		}
+		program, cmdArgs := execProgram(args, execAllClaude)
+
+		extraEnv, err := profile.ParseEnvAssignments(execAllEnv)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+
+		// As in exec, termination signals are forwarded to the commands and
+		// must not keep the environments from being removed.
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, claude.ForwardedSignals...)
+		defer signal.Stop(interrupted)
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		names, err := execAllTargets(cfg)
+		if err != nil {
+			return err
+		}
+
+		if execAllLogDir != "" {
+			if err := os.MkdirAll(execAllLogDir, 0700); err != nil {
+				return fmt.Errorf("cannot create log directory: %w", err)
+			}
+		}
+
+		results := make([]execResultDoc, len(names))
+		envs := make([]*profile.IsolatedEnv, len(names))
+		defer func() {
+			for _, env := range envs {
+				if env != nil {
+					_ = env.Cleanup()
+				}
+			}
+		}()
+		for i, name := range names {
+			results[i] = execResultDoc{Profile: name, ExitCode: -1}
+			env, err := profile.SetupIsolatedEnv(name, cfg)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			envs[i] = env
+		}
+
+		select {
+		case sig := <-interrupted:
+			return exitStatus(claude.SignalExitCode(sig))
+		default:
+		}
+
+		// Command output and notices must not mix with a machine-readable
+		// summary.
+		stdout := io.Writer(os.Stdout)
+		if machineOutput() {
+			stdout = os.Stderr
+			defer ui.SetOutput(os.Stderr)()
+		}
+		width := 0
+		for _, name := range names {
+			width = max(width, len(name))
+		}
+
+		// A signal reaches the running commands through claude.Run; the ones
+		// still waiting for their turn are not started. stopSig is set before
+		// stop is closed.
+		var stopSig os.Signal
+		stop := make(chan struct{})
+		finished := make(chan struct{})
+		defer close(finished)
+		go func() {
+			select {
+			case stopSig = <-interrupted:
+				close(stop)
+			case <-finished:
+			}
+		}()
+
+		var (
+			mu  sync.Mutex // serialises writes to stdout and stderr
+			wg  sync.WaitGroup
+			sem = make(chan struct{}, execAllParallel)
+		)
+		for i := range names {
+			if envs[i] == nil {
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				select {
+				case sem <- struct{}{}:
+				case <-stop:
+					results[i].Error = "interrupted"
+					return
+				}
+				defer func() { <-sem }()
+				select {
+				case <-stop:
+					results[i].Error = "interrupted"
+					return
+				default:
+				}
+
+				opts := claude.RunOptions{
+					Program:          program,
+					Args:             cmdArgs,
+					Env:              execAllEnviron(envs[i], extraEnv),
+					Dir:              execAllCwd,
+					Stdin:            strings.NewReader(""),
+					ForwardInterrupt: !ui.IsTerminal(),
+				}
+				results[i] = runProfileCommand(opts, results[i], i, width, stdout, &mu)
+			}(i)
+		}
+		wg.Wait()
+
+		if !execAllNoWriteback {
+			for _, env := range envs {
+				if env != nil {
+					writeBackCredentials(env)
+				}
+			}
+		}
+
+		doc := execAllDoc{Command: args, Results: results}
+		if machineOutput() {
+			if err := writeOutput("ExecSummary", doc); err != nil {
+				return err
+			}
+		} else {
+			printExecSummary(doc)
+		}
+
+		select {
+		case <-stop:
+			return exitStatus(claude.SignalExitCode(stopSig))
+		default:
+		}
+
+		for _, r := range results {
+			if r.ExitCode != 0 {
+				return exitStatus(exitFailure)
+			}
+		}
+		return nil
+	},
+}
+
+// execAllTargets returns the names of the profiles exec-all runs under.
+func execAllTargets(cfg *config.Config) ([]string, error) {
+	if len(execAllProfiles) > 0 {
+		seen := make(map[string]bool)
+		var names []string
+		for _, name := range execAllProfiles {
+			if _, ok := cfg.Profiles[name]; !ok {
+				return nil, withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	mgr := profile.NewManager(cfg)
+	profiles := mgr.List()
+	if execAllTag != "" {
+		profiles = mgr.WithTag(execAllTag)
+		if len(profiles) == 0 {
+			return nil, withExitCode(exitNotFound, fmt.Errorf("no profiles tagged %q — add one with: cs tag <profile> %s", execAllTag, execAllTag))
+		}
+	}
+	if len(profiles) == 0 {
+		return nil, withExitCode(exitNotFound, fmt.Errorf("no profiles saved yet — run: cs import <name>"))
+	}
+
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+// execAllEnviron returns the environment of a command run in env.
+func execAllEnviron(env *profile.IsolatedEnv, extra map[string]string) []string {
+	vars := env.Env()
+	for _, key := range sortedEnvKeys(extra) {
+		vars = append(vars, key+"="+extra[key])
+	}
+	return vars
+}
+
+// prefixColors tell the profiles apart in interleaved output.
+var prefixColors = []string{ui.Cyan, ui.Green, ui.Yellow, ui.Blue}
+
+// runProfileCommand runs one profile's command, sending its output where the
+// flags say, and returns res completed with the outcome.
+func runProfileCommand(opts claude.RunOptions, res execResultDoc, index, width int, stdout io.Writer, mu *sync.Mutex) execResultDoc {
+	var (
+		group   bytes.Buffer
+		flushes []func()
+	)
+	switch {
+	case execAllLogDir != "":
+		res.Log = filepath.Join(execAllLogDir, res.Profile+".log")
+		f, err := os.OpenFile(res.Log, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			res.Error = fmt.Sprintf("cannot create log: %s", err)
+			return res
+		}
+		defer f.Close()
+		opts.Stdout, opts.Stderr = f, f
+	case execAllGroup:
+		opts.Stdout, opts.Stderr = &group, &group
+	default:
+		prefix := ui.Colorize(prefixColors[index%len(prefixColors)], fmt.Sprintf("[%-*s] ", width, res.Profile))
+		out := &prefixWriter{mu: mu, w: stdout, prefix: prefix}
+		errOut := &prefixWriter{mu: mu, w: os.Stderr, prefix: prefix}
+		opts.Stdout, opts.Stderr = out, errOut
+		flushes = append(flushes, out.Flush, errOut.Flush)
+	}
+
+	start := time.Now()
+	runErr := claude.Run(opts)
+	res.DurationMS = time.Since(start).Milliseconds()
+	for _, flush := range flushes {
+		flush()
+	}
+
+	if code, ok := claude.ExitCode(runErr); ok {
+		res.ExitCode = code
+	} else if runErr != nil {
+		res.Error = runErr.Error()
+	} else {
+		res.ExitCode = 0
+	}
+
+	if execAllGroup && execAllLogDir == "" {
+		mu.Lock()
+		fmt.Fprintln(stdout, ui.Colorize(ui.Bold, fmt.Sprintf("==> %s (exit %d)", res.Profile, res.ExitCode)))
+		_, _ = stdout.Write(group.Bytes())
+		if group.Len() > 0 && !bytes.HasSuffix(group.Bytes(), []byte("\n")) {
+			fmt.Fprintln(stdout)
+		}
+		mu.Unlock()
+	}
+	return res
+}
+
+// printExecSummary prints the outcome of each profile's command.
+func printExecSummary(doc execAllDoc) {
+	fmt.Println()
+	ui.Header("Summary:")
+	headers := []string{"PROFILE", "EXIT", "DURATION", "NOTE"}
+	table := ui.NewTable(headers...)
+	for _, r := range doc.Results {
+		exit := fmt.Sprint(r.ExitCode)
+		if r.ExitCode < 0 {
+			exit = "-"
+		}
+		note := r.Error
+		if note == "" {
+			note = r.Log
+		}
+		duration := (time.Duration(r.DurationMS) * time.Millisecond).Round(time.Millisecond).String()
+		table.AddRow(r.Profile, exit, duration, note)
+	}
+	table.Render()
+}
+
+// prefixWriter writes each line it receives to w behind a prefix, so the
+// output of concurrent commands can be told apart. Writers sharing w share
+// mu. A trailing partial line is held back until Flush.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.emit(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes out a final line that lacks a newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.emit(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) emit(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+}
+
+func init() {
+	execAllCmd.Flags().StringSliceVar(&execAllProfiles, "profiles", nil, "Profiles to run under (comma-separated)")
+	execAllCmd.Flags().StringVar(&execAllTag, "tag", "", "Run under the profiles with this tag")
+	execAllCmd.Flags().IntVarP(&execAllParallel, "parallel", "j", 4, "How many commands to run at once")
+	execAllCmd.Flags().BoolVar(&execAllGroup, "group", false, "Print each profile's output in one block when it finishes")
+	execAllCmd.Flags().StringVar(&execAllLogDir, "log-dir", "", "Write each profile's output to <dir>/<profile>.log")
+	execAllCmd.Flags().BoolVar(&execAllClaude, "claude", false, "Pass all arguments to claude, even if the first one is an executable")
+	execAllCmd.Flags().BoolVar(&execAllNoWriteback, "no-writeback", false, "Don't save credentials refreshed during the runs back into the profiles")
+	execAllCmd.Flags().StringVar(&execAllCwd, "cwd", "", "Run the commands in this directory")
+	execAllCmd.Flags().StringArrayVar(&execAllEnv, "env", nil, "Set an extra environment variable (KEY=VALUE, repeatable)")
+	rootCmd.AddCommand(execAllCmd)
+}
@@ -88,6 +88,12 @@ Use the passphrase that was set during export.`,
 			return err
 		}
 
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
 		if err := config.EnsureDirs(); err != nil {
 			return err
 		}
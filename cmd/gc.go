@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+// gcStampFile records when the startup sweep last ran.
+const gcStampFile = "gc.stamp"
+
+// gcSweepInterval is how often the startup sweep looks for leftovers.
+const gcSweepInterval = time.Hour
+
+var gcDryRun bool
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove temporary credential copies left behind by cs",
+	Long: `GC removes the temporary directories created by 'cs exec' and 'cs login'
+whose owning cs process is no longer running, e.g. because it was killed.
+These directories contain copies of your credentials.
+
+cs also does this automatically, at most once an hour, when it starts.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dirs, err := profile.ListTempDirs()
+		if err != nil {
+			return err
+		}
+
+		orphaned := 0
+		table := ui.NewTable("DIRECTORY", "PROFILE", "PID", "AGE", "STATE")
+		for _, d := range dirs {
+			name, pid, state := "-", "-", ui.Colorize(ui.Green, "in use")
+			if d.Owner != nil {
+				if d.Owner.Profile != "" {
+					name = d.Owner.Profile
+				}
+				pid = strconv.Itoa(d.Owner.PID)
+			}
+			if d.Orphaned {
+				orphaned++
+				state = ui.Colorize(ui.Yellow, "orphaned")
+			}
+			table.AddRow(filepath.Base(d.Path), name, pid, formatAge(time.Since(d.ModTime)), state)
+		}
+
+		if len(dirs) == 0 {
+			ui.Success("No temporary directories found")
+			return nil
+		}
+		table.Render()
+		fmt.Println()
+
+		if orphaned == 0 {
+			ui.Success("Nothing to remove")
+			return nil
+		}
+		if gcDryRun {
+			ui.Info("%d orphaned director(ies) would be removed", orphaned)
+			return nil
+		}
+
+		removed, err := profile.RemoveOrphanedTempDirs()
+		if err != nil {
+			return err
+		}
+		ui.Success("Removed %d orphaned director(ies)", len(removed))
+		return nil
+	},
+}
+
+// sweepTempDirs removes orphaned temporary directories, at most once per
+// gcSweepInterval. It runs on every start, so it stays quiet and cheap.
+func sweepTempDirs() {
+	base, err := config.AppDataDir()
+	if err != nil || !profile.DirExists(base) {
+		return
+	}
+	stamp := filepath.Join(base, gcStampFile)
+	if info, err := os.Stat(stamp); err == nil && time.Since(info.ModTime()) < gcSweepInterval {
+		return
+	}
+	if err := os.WriteFile(stamp, nil, 0600); err != nil {
+		return
+	}
+
+	removed, _ := profile.RemoveOrphanedTempDirs()
+	if verbose && len(removed) > 0 {
+		ui.Info("Removed %d leftover temporary director(ies)", len(removed))
+	}
+}
+
+// formatAge renders a duration as a short age such as "5m" or "3d".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+func init() {
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Only list what would be removed")
+	rootCmd.AddCommand(gcCmd)
+}
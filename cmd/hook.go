@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+// Variables the hook keeps in the shell. The underscore-prefixed ones are
+// internal bookkeeping; CS_PROFILE names the profile the shell is using.
+const (
+	profileEnvVar      = "CS_PROFILE"
+	hookStateVar       = "_CS_HOOK_STATE"
+	hookProfileVar     = "_CS_HOOK_PROFILE"
+	hookVarsVar        = "_CS_HOOK_VARS"
+	hookSavedConfigVar = "_CS_HOOK_SAVED_CONFIG_DIR"
+	claudeConfigDirVar = "CLAUDE_CONFIG_DIR"
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook <bash|zsh|fish>",
+	Short: "Print a shell hook that follows .claude-profile files",
+	Long: `Hook prints shell code that, whenever the working directory changes,
+looks up the nearest .claude-profile and applies it.
+
+By default only the current shell is pointed at the profile: CS_PROFILE and
+CLAUDE_CONFIG_DIR are exported (the latter to a persistent per-profile
+directory under ~/.claude-switch/sessions), and restored when you leave the
+project. Set "hook_mode": "global" in the config to run 'cs use' instead.
+
+Add it to your shell startup file:
+  bash:  eval "$(cs hook bash)"     # ~/.bashrc
+  zsh:   eval "$(cs hook zsh)"      # ~/.zshrc
+  fish:  cs hook fish | source      # ~/.config/fish/config.fish`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			exe = "cs"
+		}
+
+		switch args[0] {
+		case "bash":
+			fmt.Printf(bashHook, posixQuote(exe))
+		case "zsh":
+			fmt.Printf(zshHook, posixQuote(exe))
+		case "fish":
+			fmt.Printf(fishHook, fishQuote(exe))
+		default:
+			return withExitCode(exitUsage, fmt.Errorf("unsupported shell: %s (use bash, zsh, or fish)", args[0]))
+		}
+		return nil
+	},
+}
+
+// The hooks only call cs when $PWD has changed, and cs itself prints nothing
+// unless the resolved .claude-profile differs from _CS_HOOK_STATE.
+
+const bashHook = `_cs_hook() {
+  local status=$?
+  if [[ "$PWD" != "${_CS_HOOK_PWD-}" ]]; then
+    _CS_HOOK_PWD="$PWD"
+    eval "$(%s hook-env bash)"
+  fi
+  return $status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_cs_hook;"* ]]; then
+  PROMPT_COMMAND="_cs_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`
+
+const zshHook = `_cs_hook() {
+  eval "$(%s hook-env zsh)"
+}
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_cs_hook]} )); then
+  chpwd_functions=(_cs_hook $chpwd_functions)
+fi
+_cs_hook
+`
+
+const fishHook = `function __cs_hook --on-variable PWD
+    %s hook-env fish | source
+end
+__cs_hook
+`
+
+var hookEnvCmd = &cobra.Command{
+	Use:    "hook-env <shell>",
+	Short:  "Print the statements the shell hook evaluates",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := args[0]
+		if shell != "bash" && shell != "zsh" && shell != "fish" {
+			return withExitCode(exitUsage, fmt.Errorf("unsupported shell: %s (use bash, zsh, or fish)", shell))
+		}
+
+		// Everything on stdout is evaluated by the shell, so send notices
+		// and prompts to stderr.
+		defer ui.SetOutput(os.Stderr)()
+
+		dir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		stmts, err := hookEnvStatements(shell, dir)
+		if err != nil {
+			return err
+		}
+		if len(stmts) > 0 {
+			fmt.Println(strings.Join(stmts, "\n"))
+		}
+		return nil
+	},
+}
+
+// hookState identifies a .claude-profile file and its version, so the hook
+// can skip all work while the resolved file is unchanged.
+func hookState(path string) string {
+	if path == "" {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", path, info.ModTime().UnixNano())
+}
+
+// hookEnvStatements returns the statements that bring the shell in line with
+// the .claude-profile that applies to dir. On error nothing is applied, so
+// the next directory change tries again.
+func hookEnvStatements(shell, dir string) ([]string, error) {
+	name, path := profile.FindProjectProfile(dir)
+	state := hookState(path)
+	if state == os.Getenv(hookStateVar) {
+		return nil, nil
+	}
+
+	var stmts []string
+	if state == "" {
+		stmts = append(stmts, shellUnset(shell, hookStateVar))
+	} else {
+		stmts = append(stmts, shellExport(shell, hookStateVar, state))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		if _, ok := cfg.Profiles[name]; !ok {
+			ui.Warn("%s names unknown profile %q", path, name)
+			name = ""
+		}
+	}
+
+	if cfg.Settings.HookMode == config.HookModeGlobal {
+		if err := hookSwitchGlobal(name, path); err != nil {
+			return nil, err
+		}
+		return stmts, nil
+	}
+
+	prev := os.Getenv(hookProfileVar)
+	if name == prev {
+		return stmts, nil
+	}
+	if name == "" {
+		ui.Info("Left profile %q", prev)
+		return append(stmts, hookDeactivate(shell)...), nil
+	}
+
+	env, err := profile.SetupSessionEnv(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev == "" {
+		if saved, ok := os.LookupEnv(claudeConfigDirVar); ok {
+			stmts = append(stmts, shellExport(shell, hookSavedConfigVar, saved))
+		}
+	}
+	for _, key := range strings.Fields(os.Getenv(hookVarsVar)) {
+		if _, keep := env.Vars[key]; !keep {
+			stmts = append(stmts, shellUnset(shell, key))
+		}
+	}
+
+	keys := make([]string, 0, len(env.Vars))
+	for _, kv := range sessionExports(env) {
+		stmts = append(stmts, shellExport(shell, kv[0], kv[1]))
+		if _, ok := env.Vars[kv[0]]; ok {
+			keys = append(keys, kv[0])
+		}
+	}
+	if len(keys) > 0 {
+		stmts = append(stmts, shellExport(shell, hookVarsVar, strings.Join(keys, " ")))
+	} else {
+		stmts = append(stmts, shellUnset(shell, hookVarsVar))
+	}
+	stmts = append(stmts, shellExport(shell, hookProfileVar, name))
+
+	ui.Info("Using profile %q in this shell (%s)", name, path)
+	return stmts, nil
+}
+
+// hookDeactivate undoes what the hook exported for the previous profile and
+// restores a CLAUDE_CONFIG_DIR the user had set before.
+func hookDeactivate(shell string) []string {
+	var stmts []string
+	for _, key := range strings.Fields(os.Getenv(hookVarsVar)) {
+		stmts = append(stmts, shellUnset(shell, key))
+	}
+	if saved, ok := os.LookupEnv(hookSavedConfigVar); ok {
+		stmts = append(stmts, shellExport(shell, claudeConfigDirVar, saved), shellUnset(shell, hookSavedConfigVar))
+	} else {
+		stmts = append(stmts, shellUnset(shell, claudeConfigDirVar))
+	}
+	return append(stmts,
+		shellUnset(shell, hookVarsVar),
+		shellUnset(shell, profileEnvVar),
+		shellUnset(shell, hookProfileVar),
+	)
+}
+
+// hookSwitchGlobal switches the active profile when the hook runs in global
+// mode. Leaving a project keeps whatever profile is active.
+func hookSwitchGlobal(name, path string) error {
+	if name == "" {
+		return nil
+	}
+
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if cfg.ActiveProfile == name {
+		return nil
+	}
+	if cfg.Profiles[name].IsEnv() {
+		ui.Warn("%s names env profile %q, which cannot be switched to globally", path, name)
+		return nil
+	}
+
+	if err := profile.NewManager(cfg).Use(name); err != nil {
+		return err
+	}
+	ui.Success("Switched to profile %q (%s)", name, path)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	rootCmd.AddCommand(hookEnvCmd)
+}
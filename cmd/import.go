@@ -23,6 +23,12 @@ Before importing, make sure you're logged into the Claude account you want to sa
 			return err
 		}
 
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
@@ -1,10 +1,10 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/caeser1996/claude-switch/internal/config"
@@ -26,7 +26,7 @@ var infoCmd = &cobra.Command{
 
 		p, ok := cfg.Profiles[name]
 		if !ok {
-			return fmt.Errorf("profile %q not found", name)
+			return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
 		}
 
 		profilesDir, err := config.ProfilesDir()
@@ -35,6 +35,14 @@ var infoCmd = &cobra.Command{
 		}
 		profileDir := filepath.Join(profilesDir, name)
 
+		if machineOutput() {
+			doc, err := newInfoDoc(cfg, p, profileDir)
+			if err != nil {
+				return err
+			}
+			return writeOutput("ProfileInfo", doc)
+		}
+
 		ui.Header(fmt.Sprintf("Profile: %s", name))
 		fmt.Println()
 
@@ -48,10 +56,39 @@ var infoCmd = &cobra.Command{
 		if p.Description != "" {
 			fmt.Printf("  %-15s %s\n", "Description:", p.Description)
 		}
+		if len(p.Tags) > 0 {
+			fmt.Printf("  %-15s %s\n", "Tags:", strings.Join(p.Tags, ", "))
+		}
 		if !p.CreatedAt.IsZero() {
 			fmt.Printf("  %-15s %s\n", "Created:", p.CreatedAt.Format("2006-01-02 15:04:05 UTC"))
 		}
 
+		if p.IsEnv() {
+			fmt.Printf("  %-15s %s\n", "Type:", "environment")
+			fmt.Printf("  %-15s %s\n", "Provider:", p.Provider)
+			fmt.Println()
+
+			vars, err := profile.LoadEnvVars(profileDir)
+			if err != nil {
+				return err
+			}
+			ui.Header("Environment:")
+			for _, key := range p.EnvVars {
+				value, ok := vars[key]
+				if !ok {
+					continue
+				}
+				if profile.IsSecretVar(key, value) {
+					value = profile.MaskValue(value)
+				}
+				fmt.Printf("  %s=%s\n", key, value)
+			}
+			fmt.Println()
+			printResources(cfg.EffectiveResources(name))
+			return nil
+		}
+
+		fmt.Printf("  %-15s %s\n", "Type:", "credentials")
 		fmt.Println()
 
 		// Credential files
@@ -64,6 +101,9 @@ var infoCmd = &cobra.Command{
 				if info != nil {
 					size = formatBytes(info.Size())
 				}
+				if profile.IsSealedFile(fpath) {
+					size += ", encrypted"
+				}
 				fmt.Printf("  %s  %s (%s)\n", ui.Colorize(ui.Green, "✓"), fname, size)
 			} else {
 				fmt.Printf("  %s  %s\n", ui.Colorize(ui.Gray, "-"), fname)
@@ -79,6 +119,9 @@ var infoCmd = &cobra.Command{
 				if info != nil {
 					size = formatBytes(info.Size())
 				}
+				if profile.IsSealedFile(fpath) {
+					size += ", encrypted"
+				}
 				fmt.Printf("  %s  %s (%s)\n", ui.Colorize(ui.Green, "✓"), stored, size)
 			} else {
 				fmt.Printf("  %s  %s\n", ui.Colorize(ui.Gray, "-"), stored)
@@ -87,27 +130,119 @@ var infoCmd = &cobra.Command{
 
 		fmt.Println()
 
-		// Try to extract extra info from credentials
-		credPath := filepath.Join(profileDir, ".credentials.json")
-		if profile.FileExists(credPath) {
-			data, err := os.ReadFile(credPath)
-			if err == nil {
-				var creds map[string]interface{}
-				if json.Unmarshal(data, &creds) == nil {
-					ui.Header("Account Details:")
-					for _, key := range []string{"plan", "org_name", "orgName", "model"} {
-						if v, ok := creds[key]; ok {
-							fmt.Printf("  %-15s %v\n", key+":", v)
-						}
-					}
+		// Account details from the typed credential model
+		if creds, err := profile.LoadProfileCredentials(profileDir); err == nil {
+			ui.Header("Account Details:")
+			rows := []struct{ label, value string }{
+				{"Login:", creds.Kind},
+				{"Plan:", creds.PlanName()},
+				{"Subscription:", creds.SubscriptionType},
+				{"Rate tier:", creds.RateLimitTier},
+				{"Organization:", creds.OrgName},
+				{"Org UUID:", creds.OrgUUID},
+				{"Account UUID:", creds.AccountUUID},
+				{"Scopes:", strings.Join(creds.Scopes, " ")},
+			}
+			for _, r := range rows {
+				if r.value != "" {
+					fmt.Printf("  %-15s %s\n", r.label, r.value)
 				}
 			}
+			if creds.ExpiresAt != nil {
+				expires := creds.ExpiresAt.Local().Format("2006-01-02 15:04:05")
+				if creds.IsExpired() {
+					expires += " " + ui.Colorize(ui.Red, "(expired)")
+				}
+				fmt.Printf("  %-15s %s\n", "Token expires:", expires)
+			}
+			fmt.Println()
 		}
 
+		printResources(cfg.EffectiveResources(name))
 		return nil
 	},
 }
 
+// newInfoDoc collects the machine-readable details of a profile. Secret
+// environment values are never included.
+func newInfoDoc(cfg *config.Config, p config.ProfileEntry, profileDir string) (infoDoc, error) {
+	doc := infoDoc{profileDoc: newProfileDoc(p), Resources: cfg.EffectiveResources(p.Name)}
+
+	if p.IsEnv() {
+		vars, err := profile.LoadEnvVars(profileDir)
+		if err != nil {
+			return doc, err
+		}
+		for _, key := range p.EnvVars {
+			value, ok := vars[key]
+			if !ok {
+				continue
+			}
+			v := envVarDoc{Name: key, Secret: profile.IsSecretVar(key, value)}
+			if !v.Secret {
+				v.Value = value
+			}
+			doc.Env = append(doc.Env, v)
+		}
+		return doc, nil
+	}
+
+	stored := append([]string{}, profile.CredentialFiles...)
+	for _, fname := range profile.HomeCredentialFiles {
+		stored = append(stored, "home_"+fname)
+	}
+	for _, fname := range stored {
+		f := fileDoc{Name: fname}
+		fpath := filepath.Join(profileDir, fname)
+		if info, err := os.Stat(fpath); err == nil && !info.IsDir() {
+			f.Present = true
+			f.Size = info.Size()
+			f.Encrypted = profile.IsSealedFile(fpath)
+		}
+		doc.Files = append(doc.Files, f)
+	}
+
+	if creds, err := profile.LoadProfileCredentials(profileDir); err == nil {
+		doc.Account = &accountDoc{
+			Login:        creds.Kind,
+			Plan:         creds.PlanName(),
+			Subscription: creds.SubscriptionType,
+			RateTier:     creds.RateLimitTier,
+			OrgName:      creds.OrgName,
+			OrgUUID:      creds.OrgUUID,
+			AccountUUID:  creds.AccountUUID,
+			Scopes:       creds.Scopes,
+			Expired:      creds.IsExpired(),
+		}
+		if creds.ExpiresAt != nil {
+			expires := creds.ExpiresAt.UTC()
+			doc.Account.ExpiresAt = &expires
+		}
+	}
+
+	return doc, nil
+}
+
+// printResources shows which parts of ~/.claude an isolated environment of
+// the profile shares, copies or keeps per profile.
+func printResources(policy config.ResourcePolicy) {
+	ui.Header("Resources:")
+	for _, r := range []struct {
+		label string
+		paths []string
+	}{
+		{"Shared:", policy.Shared},
+		{"Copied:", policy.Copied},
+		{"Persistent:", policy.Persistent},
+	} {
+		paths := strings.Join(r.paths, ", ")
+		if paths == "" {
+			paths = ui.Colorize(ui.Gray, "-")
+		}
+		fmt.Printf("  %-15s %s\n", r.label, paths)
+	}
+}
+
 func boolIcon(b bool) string {
 	if b {
 		return ui.Colorize(ui.Green, "yes")
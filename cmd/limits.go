@@ -2,39 +2,255 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/caeser1996/claude-switch/internal/claude"
 	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var (
+	limitsProfile string
+	limitsAll     bool
 )
 
 var limitsCmd = &cobra.Command{
 	Use:   "limits",
 	Short: "Show usage limits for the active profile",
 	Long: `Limits displays usage and rate limit information for the currently
-active Claude Code profile, extracted from local credential and config files.`,
+active Claude Code profile, extracted from local credential and config files,
+followed by the limit state of every profile.
+
+With --profile, it shows another profile's details, read from its stored
+credentials without switching to it. With --all, it compares plan,
+organization, subscription, token expiry and limit state across every
+profile in one table.
+
+Usage-limit, 429 and overloaded errors are picked up from Claude Code's
+transcripts and debug logs (in ~/.claude and in each profile's session
+directory) and from 'cs exec --pool' runs. The latest one of each profile
+is kept in ~/.claude-switch/limits.json, together with the time its limit
+window resets.
+
+Examples:
+  cs limits
+  cs limits --profile work
+  cs limits --all -o json`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if limitsAll && limitsProfile != "" {
+			return withExitCode(exitUsage, fmt.Errorf("--all and --profile cannot be used together"))
+		}
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
 		}
 
 		profileName := cfg.ActiveProfile
-		if profileName == "" {
-			return fmt.Errorf("no active profile — import a profile first with 'cs import <name>'")
+		switch {
+		case limitsAll:
+		case limitsProfile != "":
+			if _, ok := cfg.Profiles[limitsProfile]; !ok {
+				return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", limitsProfile))
+			}
+			profileName = limitsProfile
+		case profileName == "":
+			return withExitCode(exitNotFound, fmt.Errorf("no active profile — import a profile first with 'cs import <name>'"))
 		}
 
-		info, err := claude.GetUsageInfo()
+		store, err := refreshLimits(cfg)
 		if err != nil {
 			return err
 		}
+		now := time.Now()
+
+		if limitsAll {
+			return printLimitsComparison(cfg, now)
+		}
+
+		info, err := profileUsageInfo(cfg, profileName)
+		if err != nil {
+			return err
+		}
+
+		doc := limitsDoc{Profile: profileName, UsageInfo: info}
+		if limitsProfile == "" {
+			doc.Profiles = []profileLimitDoc{}
+			for _, p := range profile.NewManager(cfg).List() {
+				doc.Profiles = append(doc.Profiles, newProfileLimitDoc(p.Name, store, now))
+			}
+		}
+
+		if machineOutput() {
+			return writeOutput("UsageInfo", doc)
+		}
 
 		fmt.Println(claude.FormatUsageInfo(info, profileName))
+		if limitsProfile != "" {
+			return nil
+		}
+		fmt.Println()
 
+		table := ui.NewTable("PROFILE", "STATE", "RESETS", "IN")
+		for _, p := range doc.Profiles {
+			state, resets, in := "available", "-", "-"
+			if p.Limited {
+				state = p.Kind + " limit"
+				resets = p.ResetAt.Local().Format("Jan 2 15:04")
+				in = claude.FormatCountdown(p.ResetAt.Sub(now))
+			}
+			name := p.Profile
+			if name == profileName {
+				name += " *"
+			}
+			table.AddRow(name, state, resets, in)
+		}
+		table.Render()
 		return nil
 	},
 }
 
+// printLimitsComparison prints the account details and limit state of
+// every profile side by side.
+func printLimitsComparison(cfg *config.Config, now time.Time) error {
+	doc, err := limitsComparison(cfg)
+	if err != nil {
+		return err
+	}
+
+	if machineOutput() {
+		return writeOutput("UsageInfoList", doc)
+	}
+
+	if len(doc.Profiles) == 0 {
+		ui.Info("No profiles yet. Import one with: cs import <name>")
+		return nil
+	}
+	table := ui.NewTable("PROFILE", "PLAN", "ORG", "SUBSCRIPTION", "TOKEN EXPIRES", "LIMIT")
+	for _, d := range doc.Profiles {
+		u := d.UsageInfo
+		name := d.Profile
+		if name == cfg.ActiveProfile {
+			name += " *"
+		}
+		expires := "-"
+		if u.TokenExpiresAt != nil {
+			expires = u.TokenExpiresAt.Local().Format("Jan 2 15:04")
+			if now.After(*u.TokenExpiresAt) {
+				expires = "expired"
+			}
+		}
+		limit := "available"
+		if u.RateLimited {
+			limit = u.LimitKind + " limit"
+			if reset, err := time.Parse(time.RFC3339, u.ResetTime); err == nil {
+				limit += ", resets in " + claude.FormatCountdown(reset.Sub(now))
+			}
+		}
+		table.AddRow(name, claude.ValueOrDash(u.Plan), claude.ValueOrDash(u.OrgName), claude.ValueOrDash(u.SubscriptionType), expires, limit)
+	}
+	table.Render()
+	return nil
+}
+
+// limitsComparison reads the account details and limit state of every
+// profile.
+func limitsComparison(cfg *config.Config) (limitsListDoc, error) {
+	doc := limitsListDoc{Profiles: []limitsDoc{}}
+	for _, p := range profile.NewManager(cfg).List() {
+		info, err := profileUsageInfo(cfg, p.Name)
+		if err != nil {
+			return doc, err
+		}
+		doc.Profiles = append(doc.Profiles, limitsDoc{Profile: p.Name, UsageInfo: info})
+	}
+	return doc, nil
+}
+
+// profileUsageInfo reads a profile's account details and limit state: from
+// the live Claude directory for the active profile, whose stored copy may
+// be behind, and from the profile store for the others.
+func profileUsageInfo(cfg *config.Config, name string) (*claude.UsageInfo, error) {
+	var (
+		src claude.UsageSource
+		err error
+	)
+	if name == cfg.ActiveProfile {
+		src, err = claude.LiveUsageSource(name)
+	} else {
+		src, err = profile.StoredUsageSource(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return claude.GetUsageInfo(src)
+}
+
+// refreshLimits records the limit events found in Claude Code's transcripts
+// and debug logs since the last run and returns the updated limit store.
+// Events in the live Claude directory are attributed to the profile that
+// was active when they happened.
+func refreshLimits(cfg *config.Config) (claude.LimitStore, error) {
+	ix, err := loadUsageIndex()
+	if err != nil {
+		return nil, err
+	}
+	history, err := profile.LoadHistory()
+	if err != nil {
+		return nil, err
+	}
+	events := ix.Limits(func(t time.Time) string {
+		return history.ProfileAt(t, cfg.ActiveProfile)
+	})
+
+	unlock, err := lockState()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	store, err := claude.LoadLimits()
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	for _, ev := range events {
+		if _, ok := cfg.Profiles[ev.Profile]; ok && store.Record(ev.Profile, ev.LimitEvent) {
+			changed = true
+		}
+	}
+	if changed {
+		if err := store.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func newProfileLimitDoc(name string, store claude.LimitStore, now time.Time) profileLimitDoc {
+	doc := profileLimitDoc{Profile: name}
+	ev, ok := store[name]
+	if !ok {
+		return doc
+	}
+	hit := ev.HitAt.UTC()
+	doc.LastEvent = ev.Kind
+	doc.HitAt = &hit
+	if ev.Active(now) {
+		reset := ev.ResetAt.UTC()
+		doc.Limited = true
+		doc.Kind = ev.Kind
+		doc.ResetAt = &reset
+		doc.ResetsIn = int64(ev.ResetAt.Sub(now).Seconds())
+	}
+	return doc
+}
+
 func init() {
+	limitsCmd.Flags().StringVar(&limitsProfile, "profile", "", "Show this profile instead of the active one")
+	limitsCmd.Flags().BoolVar(&limitsAll, "all", false, "Compare every profile in one table")
 	rootCmd.AddCommand(limitsCmd)
 }
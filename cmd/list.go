@@ -22,6 +22,14 @@ var listCmd = &cobra.Command{
 		mgr := profile.NewManager(cfg)
 		profiles := mgr.List()
 
+		if machineOutput() {
+			doc := profileListDoc{Active: cfg.ActiveProfile, Profiles: make([]profileDoc, 0, len(profiles))}
+			for _, p := range profiles {
+				doc.Profiles = append(doc.Profiles, newProfileDoc(p))
+			}
+			return writeOutput("ProfileList", doc)
+		}
+
 		if len(profiles) == 0 {
 			ui.Info("No profiles saved yet. Use 'cs import <name>' to save your current session.")
 			return nil
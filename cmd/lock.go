@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var lockTimeout time.Duration
+
+// lockState takes the cross-process lock that serialises commands which
+// change profiles, the config or the live credentials. Take it before
+// loading the config so the command works on the latest state. The
+// returned function releases the lock.
+func lockState() (func(), error) {
+	path, err := config.LockPath()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := fsutil.Acquire(path, lockTimeout, func(pid int) {
+		if pid > 0 {
+			ui.Info("Waiting for another cs process (pid %d) to finish...", pid)
+		} else {
+			ui.Info("Waiting for another cs process to finish...")
+		}
+	})
+	if err != nil {
+		var busy *fsutil.BusyError
+		if errors.As(err, &busy) {
+			return nil, fmt.Errorf("%w — retry, or wait longer with --lock-timeout", err)
+		}
+		return nil, err
+	}
+	return func() { _ = lock.Release() }, nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 10*time.Second,
+		"How long to wait for another cs process to finish changing profiles")
+}
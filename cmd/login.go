@@ -33,9 +33,9 @@ This is a convenience shortcut for:
 
 		// Create a temporary config dir so Claude starts fresh (no existing creds)
 		// and triggers its OAuth flow.
-		tmpDir, err := os.MkdirTemp("", "cs-login-*")
+		tmpDir, err := profile.CreateTempDir("cs-login-*", name)
 		if err != nil {
-			return fmt.Errorf("cannot create temp directory: %w", err)
+			return err
 		}
 		defer os.RemoveAll(tmpDir)
 
@@ -67,6 +67,12 @@ This is a convenience shortcut for:
 
 		fmt.Println()
 
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
 		if err := config.EnsureDirs(); err != nil {
 			return err
 		}
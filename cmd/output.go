@@ -0,0 +1,472 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/doctor"
+	"github.com/caeser1996/claude-switch/internal/output"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/usage"
+)
+
+// outputFormat is the value of the global --output flag.
+var outputFormat = output.Text
+
+// Exit codes. They are part of the scripting interface documented in the
+// README, so existing values must not change.
+const (
+	exitOK        = 0
+	exitFailure   = 1 // any other error
+	exitUsage     = 2 // unknown command or flag, bad arguments, bad --output
+	exitNotFound  = 3 // the named profile, backup or active profile does not exist
+	exitUnhealthy = 4 // cs doctor found failing checks
+)
+
+// exitError attaches an exit code to an error returned from a command.
+// A silent error sets the exit code without printing a message, e.g. to pass
+// on the status of a child process that already reported its own failure.
+type exitError struct {
+	code   int
+	err    error
+	silent bool
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitStatus returns a silent error that makes cs exit with code.
+func exitStatus(code int) error {
+	return &exitError{code: code, err: fmt.Errorf("exit status %d", code), silent: true}
+}
+
+// isSilent reports whether err should not be printed.
+func isSilent(err error) bool {
+	var ee *exitError
+	return errors.As(err, &ee) && ee.silent
+}
+
+// exitCodeFor maps an error returned by rootCmd.Execute to a process exit code.
+func exitCodeFor(err error) int {
+	var ee *exitError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ee):
+		return ee.code
+	case strings.HasPrefix(err.Error(), "unknown command"):
+		return exitUsage
+	default:
+		return exitFailure
+	}
+}
+
+// markUsageErrors makes argument-validation errors of c and its subcommands
+// exit with exitUsage.
+func markUsageErrors(c *cobra.Command) {
+	if validate := c.Args; validate != nil {
+		c.Args = func(cmd *cobra.Command, args []string) error {
+			return withExitCode(exitUsage, validate(cmd, args))
+		}
+	}
+	for _, sub := range c.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
+// machineOutput reports whether a structured --output format was requested.
+func machineOutput() bool {
+	return outputFormat != output.Text
+}
+
+// writeOutput prints data as a versioned document of the given kind.
+func writeOutput(kind string, data interface{}) error {
+	return output.Write(os.Stdout, outputFormat, kind, data)
+}
+
+// The types below define the machine-readable schema. Fields may be added,
+// but never renamed or removed without bumping output.APIVersion. None of
+// them may carry a token, key or other secret value.
+
+type profileDoc struct {
+	Name         string     `json:"name"`
+	Active       bool       `json:"active"`
+	Kind         string     `json:"kind"` // "credentials" or "env"
+	Provider     string     `json:"provider"`
+	Email        string     `json:"email"`
+	Subscription string     `json:"subscription"`
+	OrgName      string     `json:"org_name"`
+	Description  string     `json:"description"`
+	CreatedAt    *time.Time `json:"created_at"`
+	EnvVars      []string   `json:"env_vars,omitempty"`
+	Tags         []string   `json:"tags,omitempty"`
+}
+
+func newProfileDoc(p config.ProfileEntry) profileDoc {
+	doc := profileDoc{
+		Name:         p.Name,
+		Active:       p.IsActive,
+		Kind:         "credentials",
+		Provider:     p.Provider,
+		Email:        p.Email,
+		Subscription: p.Subscription,
+		OrgName:      p.OrgName,
+		Description:  p.Description,
+		EnvVars:      p.EnvVars,
+		Tags:         p.Tags,
+	}
+	if p.IsEnv() {
+		doc.Kind = config.ProfileKindEnv
+	}
+	if !p.CreatedAt.IsZero() {
+		created := p.CreatedAt.UTC()
+		doc.CreatedAt = &created
+	}
+	return doc
+}
+
+type profileListDoc struct {
+	Active   string       `json:"active"`
+	Profiles []profileDoc `json:"profiles"`
+}
+
+func (d profileListDoc) Rows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(d.Profiles))
+	for _, p := range d.Profiles {
+		rows = append(rows, []string{p.Name, fmt.Sprint(p.Active), p.Kind, p.Provider, p.Email, p.Description})
+	}
+	return []string{"NAME", "ACTIVE", "KIND", "PROVIDER", "EMAIL", "DESCRIPTION"}, rows
+}
+
+type driftDoc struct {
+	State  string `json:"state"` // in-sync, other-profile, unknown-account, no-session
+	Active string `json:"active"`
+	Match  string `json:"match"`
+	Email  string `json:"email"`
+}
+
+func newDriftDoc(r profile.DriftReport) driftDoc {
+	return driftDoc{State: string(r.Kind), Active: r.Active, Match: r.Match, Email: r.Email}
+}
+
+type currentDoc struct {
+	Profile profileDoc `json:"profile"`
+	Drift   driftDoc   `json:"drift"`
+}
+
+func (d currentDoc) Rows() ([]string, [][]string) {
+	p := d.Profile
+	return []string{"NAME", "KIND", "EMAIL", "DESCRIPTION", "DRIFT"},
+		[][]string{{p.Name, p.Kind, p.Email, p.Description, d.Drift.State}}
+}
+
+type statusEntryDoc struct {
+	Name      string     `json:"name"`
+	Active    bool       `json:"active"`
+	Kind      string     `json:"kind"`  // "credentials" or "env"
+	Login     string     `json:"login"` // credential kind, e.g. "oauth" or "api-key"
+	Provider  string     `json:"provider"`
+	Email     string     `json:"email"`
+	Plan      string     `json:"plan"`
+	Status    string     `json:"status"` // valid, expired, missing, unknown, configured
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type statusDoc struct {
+	Profiles []statusEntryDoc `json:"profiles"`
+	Drift    driftDoc         `json:"drift"`
+}
+
+func newStatusEntryDoc(p config.ProfileEntry, s profile.TokenStatus) statusEntryDoc {
+	doc := statusEntryDoc{
+		Name:     p.Name,
+		Active:   p.IsActive,
+		Kind:     "credentials",
+		Provider: s.Provider,
+		Email:    s.Email,
+		Plan:     s.SubscriptionType,
+	}
+	switch {
+	case s.Kind == config.ProfileKindEnv:
+		doc.Kind = config.ProfileKindEnv
+		doc.Status = "configured"
+	case !s.HasCreds:
+		doc.Status = "missing"
+	case s.IsExpired:
+		doc.Status = "expired"
+	case s.ExpiresAt != nil:
+		doc.Status = "valid"
+	default:
+		doc.Status = "unknown"
+	}
+	if doc.Kind != config.ProfileKindEnv {
+		doc.Login = s.Kind
+	}
+	if s.ExpiresAt != nil {
+		expires := s.ExpiresAt.UTC()
+		doc.ExpiresAt = &expires
+	}
+	return doc
+}
+
+func (d statusDoc) Rows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(d.Profiles))
+	for _, p := range d.Profiles {
+		rows = append(rows, []string{p.Name, fmt.Sprint(p.Active), p.Kind, p.Email, p.Plan, p.Status, formatTime(p.ExpiresAt)})
+	}
+	return []string{"PROFILE", "ACTIVE", "KIND", "EMAIL", "PLAN", "STATUS", "EXPIRES_AT"}, rows
+}
+
+type fileDoc struct {
+	Name      string `json:"name"`
+	Present   bool   `json:"present"`
+	Size      int64  `json:"size"`
+	Encrypted bool   `json:"encrypted"`
+}
+
+type accountDoc struct {
+	Login        string     `json:"login"`
+	Plan         string     `json:"plan"`
+	Subscription string     `json:"subscription"`
+	RateTier     string     `json:"rate_tier"`
+	OrgName      string     `json:"org_name"`
+	OrgUUID      string     `json:"org_uuid"`
+	AccountUUID  string     `json:"account_uuid"`
+	Scopes       []string   `json:"scopes"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Expired      bool       `json:"expired"`
+}
+
+// envVarDoc describes one variable of an env profile. Value is left empty
+// for secrets.
+type envVarDoc struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+type infoDoc struct {
+	profileDoc
+	Files   []fileDoc   `json:"files,omitempty"`
+	Account *accountDoc `json:"account,omitempty"`
+	Env     []envVarDoc `json:"env,omitempty"`
+
+	Resources config.ResourcePolicy `json:"resources"`
+}
+
+func (d infoDoc) Rows() ([]string, [][]string) {
+	rows := [][]string{
+		{"name", d.Name},
+		{"active", fmt.Sprint(d.Active)},
+		{"kind", d.Kind},
+		{"provider", d.Provider},
+		{"email", d.Email},
+		{"description", d.Description},
+		{"created_at", formatTime(d.CreatedAt)},
+	}
+	if a := d.Account; a != nil {
+		rows = append(rows,
+			[]string{"login", a.Login},
+			[]string{"plan", a.Plan},
+			[]string{"org_name", a.OrgName},
+			[]string{"expires_at", formatTime(a.ExpiresAt)},
+		)
+	}
+	for _, f := range d.Files {
+		if f.Present {
+			rows = append(rows, []string{"file", f.Name})
+		}
+	}
+	for _, v := range d.Env {
+		rows = append(rows, []string{"env", v.Name})
+	}
+	return []string{"FIELD", "VALUE"}, rows
+}
+
+type limitsDoc struct {
+	Profile string `json:"profile"`
+	*claude.UsageInfo
+	// Profiles is the limit state of every profile, shown alongside the
+	// active one.
+	Profiles []profileLimitDoc `json:"profiles,omitempty"`
+}
+
+// profileLimitDoc is the limit state of one profile. LastEvent and HitAt
+// describe the latest limit error seen, even once it has passed.
+type profileLimitDoc struct {
+	Profile   string     `json:"profile"`
+	Limited   bool       `json:"limited"`
+	Kind      string     `json:"kind,omitempty"`
+	ResetAt   *time.Time `json:"reset_at,omitempty"`
+	ResetsIn  int64      `json:"resets_in_seconds,omitempty"`
+	LastEvent string     `json:"last_event,omitempty"`
+	HitAt     *time.Time `json:"hit_at,omitempty"`
+}
+
+func (d limitsDoc) Rows() ([]string, [][]string) {
+	u := d.UsageInfo
+	return []string{"PROFILE", "PLAN", "SUBSCRIPTION", "MODEL", "EMAIL", "RATE_LIMITED", "RESET_TIME"},
+		[][]string{{d.Profile, u.Plan, u.SubscriptionType, u.Model, u.Email, fmt.Sprint(u.RateLimited), u.ResetTime}}
+}
+
+type limitsListDoc struct {
+	Profiles []limitsDoc `json:"profiles"`
+}
+
+func (d limitsListDoc) Rows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(d.Profiles))
+	for _, p := range d.Profiles {
+		u := p.UsageInfo
+		expires := ""
+		if u.TokenExpiresAt != nil {
+			expires = u.TokenExpiresAt.UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{p.Profile, u.Plan, u.OrgName, u.SubscriptionType, expires, fmt.Sprint(u.RateLimited), u.ResetTime})
+	}
+	return []string{"PROFILE", "PLAN", "ORG", "SUBSCRIPTION", "TOKEN_EXPIRES", "RATE_LIMITED", "RESET_TIME"}, rows
+}
+
+type doctorSummaryDoc struct {
+	OK   int `json:"ok"`
+	Warn int `json:"warn"`
+	Fail int `json:"fail"`
+}
+
+type doctorDoc struct {
+	Healthy bool                 `json:"healthy"`
+	Summary doctorSummaryDoc     `json:"summary"`
+	Checks  []doctor.CheckResult `json:"checks"`
+}
+
+func newDoctorDoc(results []doctor.CheckResult) doctorDoc {
+	doc := doctorDoc{Checks: results}
+	for _, r := range results {
+		switch r.Status {
+		case "ok":
+			doc.Summary.OK++
+		case "warn":
+			doc.Summary.Warn++
+		case "fail":
+			doc.Summary.Fail++
+		}
+	}
+	doc.Healthy = doc.Summary.Fail == 0
+	return doc
+}
+
+func (d doctorDoc) Rows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(d.Checks))
+	for _, c := range d.Checks {
+		rows = append(rows, []string{c.Name, c.Status, c.Message})
+	}
+	return []string{"CHECK", "STATUS", "MESSAGE"}, rows
+}
+
+type backupDoc struct {
+	Timestamp string   `json:"timestamp"`
+	Files     []string `json:"files"`
+}
+
+type backupListDoc struct {
+	Backups []backupDoc `json:"backups"`
+}
+
+func (d backupListDoc) Rows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(d.Backups))
+	for _, b := range d.Backups {
+		rows = append(rows, []string{b.Timestamp, strings.Join(b.Files, ",")})
+	}
+	return []string{"TIMESTAMP", "FILES"}, rows
+}
+
+// execResultDoc is the outcome of running a command under one profile.
+// ExitCode is -1 when the command could not be run; Error says why.
+type execResultDoc struct {
+	Profile    string `json:"profile"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	Log        string `json:"log,omitempty"`
+}
+
+type execAllDoc struct {
+	Command []string        `json:"command"`
+	Results []execResultDoc `json:"results"`
+}
+
+func (d execAllDoc) Rows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(d.Results))
+	for _, r := range d.Results {
+		rows = append(rows, []string{r.Profile, fmt.Sprint(r.ExitCode), fmt.Sprint(r.DurationMS), r.Error, r.Log})
+	}
+	return []string{"PROFILE", "EXIT_CODE", "DURATION_MS", "ERROR", "LOG"}, rows
+}
+
+type poolMemberDoc struct {
+	Profile  string     `json:"profile"`
+	Priority int        `json:"priority"`
+	Limited  bool       `json:"limited"`
+	ResetAt  *time.Time `json:"reset_at,omitempty"`
+}
+
+type poolDoc struct {
+	Name    string          `json:"name"`
+	Members []poolMemberDoc `json:"members"`
+}
+
+type poolListDoc struct {
+	Pools []poolDoc `json:"pools"`
+}
+
+func (d poolListDoc) Rows() ([]string, [][]string) {
+	var rows [][]string
+	for _, p := range d.Pools {
+		for i, m := range p.Members {
+			rows = append(rows, []string{p.Name, fmt.Sprint(i + 1), m.Profile, fmt.Sprint(m.Priority), fmt.Sprint(m.Limited), formatTime(m.ResetAt)})
+		}
+	}
+	return []string{"POOL", "ORDER", "PROFILE", "PRIORITY", "LIMITED", "RESET_AT"}, rows
+}
+
+type usageDoc struct {
+	By      string        `json:"by"`
+	Profile string        `json:"profile,omitempty"`
+	Since   *time.Time    `json:"since,omitempty"`
+	Groups  []usage.Group `json:"groups"`
+	Total   usage.Totals  `json:"total"`
+}
+
+func (d usageDoc) Rows() ([]string, [][]string) {
+	rows := make([][]string, 0, len(d.Groups))
+	for _, g := range d.Groups {
+		rows = append(rows, []string{g.Key, fmt.Sprint(g.Messages), fmt.Sprint(g.Input), fmt.Sprint(g.Output),
+			fmt.Sprint(g.CacheCreate), fmt.Sprint(g.CacheRead)})
+	}
+	return []string{strings.ToUpper(d.By), "MESSAGES", "INPUT_TOKENS", "OUTPUT_TOKENS", "CACHE_CREATION_TOKENS", "CACHE_READ_TOKENS"}, rows
+}
+
+// formatTime renders an optional timestamp for TSV output.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", output.Text,
+		"Output format: "+strings.Join(output.Formats(), ", "))
+}
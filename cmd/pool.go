@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var poolPriority int
+
+var poolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage profile pools for rate-limit failover",
+	Long: `A pool is an ordered group of profiles. 'cs exec --pool <name>' runs a
+command under the first profile of the pool that isn't rate-limited and
+moves on to the next when a run hits a usage limit.
+
+Profiles with a lower --priority are tried first; profiles with the same
+priority are tried in the order they were added.`,
+}
+
+var poolListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List pools, their profiles and limit state",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		limits, err := claude.LoadLimits()
+		if err != nil {
+			return err
+		}
+
+		doc := poolListDoc{Pools: []poolDoc{}}
+		for _, name := range sortedPoolNames(cfg) {
+			pool := poolDoc{Name: name, Members: []poolMemberDoc{}}
+			order, _ := cfg.PoolProfiles(name)
+			priority := make(map[string]int)
+			for _, m := range cfg.Pools[name] {
+				priority[m.Profile] = m.Priority
+			}
+			for _, p := range order {
+				member := poolMemberDoc{Profile: p, Priority: priority[p]}
+				if ev, ok := limits.Active(p, time.Now()); ok {
+					reset := ev.ResetAt.UTC()
+					member.Limited = true
+					member.ResetAt = &reset
+				}
+				pool.Members = append(pool.Members, member)
+			}
+			doc.Pools = append(doc.Pools, pool)
+		}
+
+		if machineOutput() {
+			return writeOutput("PoolList", doc)
+		}
+
+		if len(doc.Pools) == 0 {
+			ui.Info("No pools yet. Create one with: cs pool add <pool> <profile>...")
+			return nil
+		}
+		table := ui.NewTable("POOL", "#", "PROFILE", "PRIORITY", "STATE")
+		for _, pool := range doc.Pools {
+			for i, m := range pool.Members {
+				state := "available"
+				if m.Limited {
+					state = "limited until " + m.ResetAt.Local().Format("Jan 2 15:04")
+				}
+				table.AddRow(pool.Name, fmt.Sprint(i+1), m.Profile, fmt.Sprint(m.Priority), state)
+			}
+			if len(pool.Members) == 0 {
+				table.AddRow(pool.Name, "-", "-", "-", "empty")
+			}
+		}
+		table.Render()
+		return nil
+	},
+}
+
+var poolAddCmd = &cobra.Command{
+	Use:   "add <pool> <profile>...",
+	Short: "Add profiles to a pool, creating it if needed",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pool, names := args[0], args[1:]
+
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if !validPoolName(pool) {
+			return withExitCode(exitUsage, fmt.Errorf("invalid pool name %q: use only letters, numbers, hyphens, underscores", pool))
+		}
+
+		if cfg.Pools == nil {
+			cfg.Pools = make(map[string][]config.PoolMember)
+		}
+		members := cfg.Pools[pool]
+		for _, name := range names {
+			if _, ok := cfg.Profiles[name]; !ok {
+				return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+			}
+			replaced := false
+			for i := range members {
+				if members[i].Profile == name {
+					members[i].Priority = poolPriority
+					replaced = true
+				}
+			}
+			if !replaced {
+				members = append(members, config.PoolMember{Profile: name, Priority: poolPriority})
+			}
+		}
+		cfg.Pools[pool] = members
+
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		ui.Success("Pool %q: %d profile(s)", pool, len(members))
+		return nil
+	},
+}
+
+var poolRemoveCmd = &cobra.Command{
+	Use:   "remove <pool> [profile...]",
+	Short: "Remove profiles from a pool, or the whole pool",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pool, names := args[0], args[1:]
+
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		members, ok := cfg.Pools[pool]
+		if !ok {
+			return withExitCode(exitNotFound, fmt.Errorf("pool %q not found", pool))
+		}
+
+		if len(names) == 0 {
+			delete(cfg.Pools, pool)
+		} else {
+			drop := make(map[string]bool, len(names))
+			for _, name := range names {
+				drop[name] = true
+			}
+			kept := members[:0]
+			for _, m := range members {
+				if !drop[m.Profile] {
+					kept = append(kept, m)
+				}
+			}
+			cfg.Pools[pool] = kept
+		}
+
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			ui.Success("Pool %q removed", pool)
+		} else {
+			ui.Success("Pool %q: %d profile(s)", pool, len(cfg.Pools[pool]))
+		}
+		return nil
+	},
+}
+
+// execWithPool runs exec's command under the first profile of the pool
+// that isn't limited, failing over to the next profile when a run ends on
+// a usage or rate limit.
+func execWithPool(pool string, args []string) error {
+	program, cmdArgs := execProgram(args, execClaude)
+
+	extraEnv, err := profile.ParseEnvAssignments(execEnv)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, claude.ForwardedSignals...)
+	defer signal.Stop(interrupted)
+
+	// stdout carries only the output of the run that counts; notices go
+	// to stderr.
+	defer ui.SetOutput(os.Stderr)()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	names, err := cfg.PoolProfiles(pool)
+	if err != nil {
+		return withExitCode(exitNotFound, err)
+	}
+	limits, err := claude.LoadLimits()
+	if err != nil {
+		return err
+	}
+
+	// Every attempt gets the same piped input.
+	var input []byte
+	if !ui.IsTerminal() {
+		if input, err = io.ReadAll(os.Stdin); err != nil {
+			return fmt.Errorf("cannot read input: %w", err)
+		}
+	}
+
+	var (
+		lastOutput []byte
+		nextReset  time.Time
+	)
+	for _, name := range names {
+		if ev, ok := limits.Active(name, time.Now()); ok {
+			if verbose {
+				ui.Info("Skipping profile %q: limited until %s", name, ev.ResetAt.Local().Format("15:04"))
+			}
+			nextReset = earlier(nextReset, ev.ResetAt)
+			continue
+		}
+
+		var stdout bytes.Buffer
+		watch := &tailBuffer{max: 64 << 10}
+		opts := claude.RunOptions{
+			Program:          program,
+			Args:             cmdArgs,
+			Dir:              execCwd,
+			Stdout:           io.MultiWriter(&stdout, watch),
+			Stderr:           io.MultiWriter(os.Stderr, watch),
+			ForwardInterrupt: !ui.IsTerminal(),
+		}
+		if input != nil {
+			opts.Stdin = bytes.NewReader(input)
+		}
+
+		runErr := runInProfile(cfg, name, extraEnv, interrupted, opts)
+		if code, ok := claude.ExitCode(runErr); ok && code != 0 {
+			if ev, hit := claude.DetectLimit(watch.Bytes(), time.Now()); hit && ev.Kind != claude.LimitOverloaded {
+				recordLimit(name, ev)
+				ui.Warn("Profile %q hit its %s limit (resets %s)", name, ev.Kind, ev.ResetAt.Local().Format("Jan 2 15:04"))
+				lastOutput = stdout.Bytes()
+				nextReset = earlier(nextReset, ev.ResetAt)
+				continue
+			}
+		}
+
+		_, _ = os.Stdout.Write(stdout.Bytes())
+		return execResult(program, runErr)
+	}
+
+	_, _ = os.Stdout.Write(lastOutput)
+	return fmt.Errorf("every profile in pool %q is limited — the first resets at %s",
+		pool, nextReset.Local().Format("Jan 2 15:04"))
+}
+
+// recordLimit stores a limit hit by a profile. Failures are reported but
+// don't stop the failover.
+func recordLimit(name string, ev claude.LimitEvent) {
+	unlock, err := lockState()
+	if err != nil {
+		ui.Warn("Limit of profile %q not recorded: %s", name, err)
+		return
+	}
+	defer unlock()
+
+	limits, err := claude.LoadLimits()
+	if err == nil && limits.Record(name, ev) {
+		err = limits.Save()
+	}
+	if err != nil {
+		ui.Warn("Limit of profile %q not recorded: %s", name, err)
+	}
+}
+
+// earlier returns the earlier of two times, treating the zero time as unset.
+func earlier(a, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// validPoolName reports whether name uses only the characters allowed in
+// profile names.
+func validPoolName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedPoolNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Pools))
+	for name := range cfg.Pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tailBuffer keeps the last max bytes written to it. It is safe for use
+// by the stdout and stderr copiers at once.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns a copy of the kept output.
+func (t *tailBuffer) Bytes() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]byte(nil), t.buf...)
+}
+
+func init() {
+	poolAddCmd.Flags().IntVar(&poolPriority, "priority", 0, "Priority of the profiles; lower is tried first")
+	poolCmd.AddCommand(poolListCmd)
+	poolCmd.AddCommand(poolAddCmd)
+	poolCmd.AddCommand(poolRemoveCmd)
+	rootCmd.AddCommand(poolCmd)
+}
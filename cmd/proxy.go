@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/proxy"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+// proxyRefreshThreshold is how close to expiry an OAuth token is refreshed
+// before the proxy uses it.
+const proxyRefreshThreshold = 5 * time.Minute
+
+// proxyStateMu serializes the limit recording and token refreshes of
+// concurrent requests; lockState only excludes other processes.
+var proxyStateMu sync.Mutex
+
+var (
+	proxyListen   string
+	proxyProfile  string
+	proxyPool     string
+	proxyUpstream string
+)
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Serve the Claude API locally with a chosen profile's credentials",
+	Long: `Proxy runs a local HTTP endpoint to point ANTHROPIC_BASE_URL at. Each
+request is forwarded to the Anthropic API with the credentials of the
+proxy's current profile (its OAuth token or API key) in place of whatever
+the client sent, so long-running sessions keep working across switches
+without touching Claude Code's own credential files.
+
+The proxy starts as --profile, the first available profile of --pool, or
+the active profile. 'cs proxy use <name>' changes the profile of a running
+proxy through its control socket (~/.claude-switch/proxy.sock). With
+--pool, a request answered with 429 is recorded as a limit and retried as
+the next pool member, which then serves later requests.
+
+OAuth tokens about to expire are refreshed first. Requests go to
+https://api.anthropic.com unless --upstream or settings.proxy_upstream in
+config.json names another server. Only loopback addresses can be listened
+on, since anyone reaching the proxy uses your accounts, and requests from
+browsers or for other host names are refused.
+
+Examples:
+  cs proxy --listen 127.0.0.1:8787 --pool main
+  ANTHROPIC_BASE_URL=http://127.0.0.1:8787 claude
+  cs proxy use personal`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkLoopback(proxyListen); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		upstream := proxyUpstream
+		if upstream == "" {
+			upstream = cfg.Settings.Upstream()
+		}
+		upstreamURL, err := url.Parse(upstream)
+		if err != nil || upstreamURL.Scheme == "" || upstreamURL.Host == "" {
+			return withExitCode(exitUsage, fmt.Errorf("invalid upstream URL %q", upstream))
+		}
+
+		var pool []string
+		if proxyPool != "" {
+			if pool, err = cfg.PoolProfiles(proxyPool); err != nil {
+				return withExitCode(exitNotFound, err)
+			}
+		}
+		start, err := proxyStartProfile(cfg, pool)
+		if err != nil {
+			return err
+		}
+
+		p, err := proxy.New(proxy.Options{
+			Upstream:    upstreamURL,
+			Profile:     start,
+			Pool:        pool,
+			Credentials: proxyAuth,
+			Limited: func(name string, now time.Time) bool {
+				limits, err := claude.LoadLimits()
+				if err != nil {
+					return false
+				}
+				_, limited := limits.Active(name, now)
+				return limited
+			},
+			OnLimit: func(name string, ev claude.LimitEvent) {
+				proxyStateMu.Lock()
+				defer proxyStateMu.Unlock()
+				recordLimit(name, ev)
+			},
+			Logf: func(format string, args ...any) {
+				ui.Info(format, args...)
+			},
+			Listen: proxyListen,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot use profile %q: %w", start, err)
+		}
+
+		socketPath, err := proxy.SocketPath()
+		if err != nil {
+			return err
+		}
+		control, err := proxy.ListenControl(socketPath)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(socketPath)
+		defer control.Close()
+		go p.ServeControl(control)
+
+		ln, err := net.Listen("tcp", proxyListen)
+		if err != nil {
+			return fmt.Errorf("cannot listen on %s: %w", proxyListen, err)
+		}
+		srv := &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, claude.ForwardedSignals...)
+		defer signal.Stop(stop)
+		go func() {
+			<-stop
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(ctx)
+		}()
+
+		ui.Success("Proxy listening on http://%s as profile %q", ln.Addr(), start)
+		if len(pool) > 0 {
+			ui.Info("Failing over through pool %q on 429", proxyPool)
+		}
+		ui.Info("Forwarding to %s — point clients at it with ANTHROPIC_BASE_URL=http://%s", upstreamURL, ln.Addr())
+
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+var proxyUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch the profile of the running proxy",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, err := proxy.SocketPath()
+		if err != nil {
+			return err
+		}
+		name, err := proxy.Control(socketPath, "USE "+args[0])
+		if err != nil {
+			return err
+		}
+		ui.Success("Proxy now uses profile %q", name)
+		return nil
+	},
+}
+
+var proxyStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the profile the running proxy uses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, err := proxy.SocketPath()
+		if err != nil {
+			return err
+		}
+		name, err := proxy.Control(socketPath, "STATUS")
+		if err != nil {
+			return withExitCode(exitNotFound, err)
+		}
+		fmt.Println(name)
+		return nil
+	},
+}
+
+// proxyStartProfile picks the profile the proxy starts as.
+func proxyStartProfile(cfg *config.Config, pool []string) (string, error) {
+	if proxyProfile != "" {
+		if _, ok := cfg.Profiles[proxyProfile]; !ok {
+			return "", withExitCode(exitNotFound, fmt.Errorf("profile %q not found", proxyProfile))
+		}
+		return proxyProfile, nil
+	}
+	if len(pool) > 0 {
+		limits, err := claude.LoadLimits()
+		if err != nil {
+			return "", err
+		}
+		for _, name := range pool {
+			if _, limited := limits.Active(name, time.Now()); !limited {
+				return name, nil
+			}
+		}
+		return pool[0], nil
+	}
+	if cfg.ActiveProfile == "" {
+		return "", withExitCode(exitNotFound, fmt.Errorf("no active profile — name one with --profile or --pool"))
+	}
+	return cfg.ActiveProfile, nil
+}
+
+// proxyAuth returns the credential the proxy sends as a profile: the API
+// key or auth token of an environment profile, or the OAuth token or API
+// key of a login, refreshed first if it is about to expire.
+func proxyAuth(name string) (proxy.Auth, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return proxy.Auth{}, err
+	}
+	entry, ok := cfg.Profiles[name]
+	if !ok {
+		return proxy.Auth{}, fmt.Errorf("profile %q not found", name)
+	}
+
+	if entry.IsEnv() {
+		vars, err := profile.ProfileEnvVars(name, cfg)
+		if err != nil {
+			return proxy.Auth{}, err
+		}
+		switch {
+		case vars["ANTHROPIC_API_KEY"] != "":
+			return proxy.Auth{APIKey: vars["ANTHROPIC_API_KEY"]}, nil
+		case vars["ANTHROPIC_AUTH_TOKEN"] != "":
+			return proxy.Auth{BearerToken: vars["ANTHROPIC_AUTH_TOKEN"]}, nil
+		}
+		return proxy.Auth{}, fmt.Errorf("profile %q has no ANTHROPIC_API_KEY or ANTHROPIC_AUTH_TOKEN to send", name)
+	}
+
+	creds, err := profile.CurrentCredentials(cfg, name)
+	if err != nil {
+		return proxy.Auth{}, err
+	}
+	if creds.Kind == claude.KindOAuth && creds.ExpiresAt != nil && time.Until(*creds.ExpiresAt) < proxyRefreshThreshold {
+		if creds, err = refreshForProxy(name); err != nil {
+			return proxy.Auth{}, err
+		}
+	}
+	switch {
+	case creds.APIKey != "":
+		return proxy.Auth{APIKey: creds.APIKey}, nil
+	case creds.AccessToken != "":
+		return proxy.Auth{BearerToken: creds.AccessToken, OAuth: creds.Kind == claude.KindOAuth}, nil
+	}
+	return proxy.Auth{}, fmt.Errorf("profile %q has no token or API key — run: cs login %s", name, name)
+}
+
+// refreshForProxy renews a profile's OAuth token, unless another request
+// or process did so while waiting for the lock.
+func refreshForProxy(name string) (*claude.Credentials, error) {
+	proxyStateMu.Lock()
+	defer proxyStateMu.Unlock()
+
+	unlock, err := lockState()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	creds, err := profile.CurrentCredentials(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	if creds.ExpiresAt == nil || time.Until(*creds.ExpiresAt) >= proxyRefreshThreshold {
+		return creds, nil
+	}
+	if _, err := profile.NewManager(cfg).Refresh(name); err != nil {
+		return nil, fmt.Errorf("token of profile %q expires and cannot be refreshed: %w", name, err)
+	}
+	ui.Info("Refreshed the token of profile %q", name)
+	return profile.CurrentCredentials(cfg, name)
+}
+
+// checkLoopback rejects listen addresses reachable from other machines.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --listen address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("--listen %s is not a loopback address — the proxy hands out your credentials, so it only listens on 127.0.0.1 or ::1", addr)
+}
+
+func init() {
+	proxyCmd.Flags().StringVar(&proxyListen, "listen", "127.0.0.1:8787", "Address to listen on (loopback only)")
+	proxyCmd.Flags().StringVar(&proxyProfile, "profile", "", "Profile to start as (default: first available of --pool, or the active profile)")
+	proxyCmd.Flags().StringVar(&proxyPool, "pool", "", "Pool to fail over through when a profile answers 429")
+	proxyCmd.Flags().StringVar(&proxyUpstream, "upstream", "", "API to forward to (default: settings.proxy_upstream or "+config.DefaultProxyUpstream+")")
+	proxyCmd.AddCommand(proxyUseCmd)
+	proxyCmd.AddCommand(proxyStatusCmd)
+	rootCmd.AddCommand(proxyCmd)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var (
+	refreshAll       bool
+	refreshIfNeeded  bool
+	refreshThreshold time.Duration
+)
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh [name]",
+	Short: "Renew OAuth tokens using the stored refresh token",
+	Long: `Refresh exchanges a profile's stored OAuth refresh token for a new
+access token, without an interactive login.
+
+With no name the active profile is refreshed; --all refreshes every profile.
+With --if-needed, only profiles whose token is expired or expires within
+--threshold are refreshed.
+
+The token endpoint can be changed with settings.oauth_token_url in config.json.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		mgr := profile.NewManager(cfg)
+
+		var names []string
+		switch {
+		case refreshAll:
+			if len(args) > 0 {
+				return fmt.Errorf("cannot combine a profile name with --all")
+			}
+			for _, p := range mgr.List() {
+				if p.IsEnv() {
+					continue // no OAuth token to refresh
+				}
+				names = append(names, p.Name)
+			}
+		case len(args) > 0:
+			names = []string{args[0]}
+		default:
+			p, err := mgr.Current()
+			if err != nil {
+				return err
+			}
+			names = []string{p.Name}
+		}
+
+		if len(names) == 0 {
+			ui.Info("No profiles saved yet.")
+			return nil
+		}
+
+		table := ui.NewTable("PROFILE", "RESULT", "EXPIRES")
+		failed := 0
+
+		for _, name := range names {
+			if refreshIfNeeded {
+				status := profile.CheckTokenStatus(name)
+				if status.HasCreds && !profile.NeedsRefresh(status, refreshThreshold) {
+					table.AddRow(name, ui.Colorize(ui.Gray, "skipped (still valid)"), status.ExpiresIn)
+					continue
+				}
+			}
+
+			if verbose {
+				ui.Info("Refreshing %q...", name)
+			}
+
+			result, err := mgr.Refresh(name)
+			if err != nil {
+				failed++
+				table.AddRow(name, ui.Colorize(ui.Red, "failed: "+err.Error()), ui.Colorize(ui.Gray, "-"))
+				continue
+			}
+
+			expires := ui.Colorize(ui.Gray, "-")
+			if result.ExpiresAt != nil {
+				expires = result.ExpiresAt.Local().Format("2006-01-02 15:04")
+			}
+			msg := "refreshed"
+			if result.WroteLive {
+				msg = "refreshed (live)"
+			}
+			table.AddRow(name, ui.Colorize(ui.Green, msg), expires)
+		}
+
+		table.Render()
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d profile(s) failed to refresh", failed, len(names))
+		}
+		return nil
+	},
+}
+
+func init() {
+	refreshCmd.Flags().BoolVarP(&refreshAll, "all", "a", false, "Refresh every profile")
+	refreshCmd.Flags().BoolVar(&refreshIfNeeded, "if-needed", false, "Only refresh tokens that are expired or expiring within --threshold")
+	refreshCmd.Flags().DurationVar(&refreshThreshold, "threshold", time.Hour, "Expiry window used by --if-needed")
+	rootCmd.AddCommand(refreshCmd)
+}
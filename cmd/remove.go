@@ -19,6 +19,12 @@ var removeCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
 		cfg, err := config.Load()
 		if err != nil {
 			return err
@@ -4,7 +4,11 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/output"
+	"github.com/caeser1996/claude-switch/internal/profile"
 	"github.com/caeser1996/claude-switch/internal/ui"
+	"github.com/caeser1996/claude-switch/internal/vault"
 )
 
 var (
@@ -26,22 +30,43 @@ var rootCmd = &cobra.Command{
 Save, switch, and manage profiles for different Claude accounts with a single command.`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := output.Validate(outputFormat); err != nil {
+			return withExitCode(exitUsage, err)
+		}
 		if noColor {
 			ui.SetColorEnabled(false)
 		}
+		if cfg, err := config.Load(); err == nil {
+			profile.ConfigureCredentialStore(cfg.Settings)
+			profile.ConfigureVault(cfg.Settings.Vault)
+		}
+		vault.SetPrompt(func() (string, error) {
+			if !ui.IsTerminal() {
+				return "", vault.ErrLocked
+			}
+			return ui.ReadPassword("Vault passphrase: ")
+		})
+		sweepTempDirs()
+		return nil
 	},
 }
 
 // Execute runs the root command.
 func Execute() {
+	markUsageErrors(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
-		ui.Error("%s", err)
-		os.Exit(1)
+		if !isSilent(err) {
+			ui.Error("%s", err)
+		}
+		os.Exit(exitCodeFor(err))
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
 }
@@ -0,0 +1,496 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/output"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/server"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+const (
+	// serveExpiryWarning is how long before its expiry a token is reported
+	// as expiring on the event stream.
+	serveExpiryWarning = 15 * time.Minute
+	// serveTokenInterval is how often the event stream rereads credentials.
+	serveTokenInterval = time.Minute
+)
+
+var servePoll time.Duration
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local JSON API for editors and tools",
+	Long: `Serve answers HTTP requests on a Unix socket (~/.claude-switch/serve.sock,
+mode 0600), so editor and terminal integrations get structured answers
+without running cs and parsing its output. Responses are the same
+documents 'cs <command> -o json' prints; errors are documents of kind
+Error, sent with status 400, 404 or 500.
+
+  GET    /v1/profiles          profiles, as 'cs list'
+  GET    /v1/current           the active profile, as 'cs current'
+  POST   /v1/use               switch profiles: {"profile": "work"}
+  GET    /v1/status            token status of every profile, as 'cs status'
+  GET    /v1/status/{name}     token status of one profile
+  GET    /v1/limits            as 'cs limits'; ?profile=name or ?all=true
+  POST   /v1/sessions          start an exec session: {"profile": "work"}
+  GET    /v1/sessions          the running exec sessions
+  DELETE /v1/sessions/{id}     stop one; ?writeback=false keeps refreshed
+                               credentials out of the profile
+  GET    /v1/events            server-sent events
+
+An exec session is the isolated environment 'cs exec' runs a command in.
+Its document carries the variables to run the command with, secrets of
+environment profiles included. Stopping it saves refreshed credentials
+back into the profile and removes the environment; sessions still running
+when cs serve exits are stopped too.
+
+The event stream sends a "switch" event when the active profile changes,
+by any means, and a "token" event when an OAuth token is about to expire,
+has expired, or is valid again after a refresh.
+
+Example:
+  curl --unix-socket ~/.claude-switch/serve.sock http://cs/v1/current`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if servePoll <= 0 {
+			return withExitCode(exitUsage, fmt.Errorf("--poll must be positive"))
+		}
+
+		api := newServeAPI()
+		if err := api.watcher.Poll(time.Now()); err != nil {
+			return err
+		}
+
+		socketPath, err := server.SocketPath()
+		if err != nil {
+			return err
+		}
+		ln, err := server.Listen(socketPath)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(socketPath)
+
+		// Cancelling ctx also ends open event streams, which Shutdown
+		// would otherwise wait for.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go api.watcher.Run(ctx, servePoll)
+
+		srv := &http.Server{
+			Handler:           api.routes(),
+			ReadHeaderTimeout: 30 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+		}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, claude.ForwardedSignals...)
+		defer signal.Stop(stop)
+		go func() {
+			<-stop
+			cancel()
+			shutdownCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+			defer done()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+
+		ui.Success("Serving the cs API on %s", socketPath)
+		serveErr := srv.Serve(ln)
+		api.stopSessions()
+		if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			return serveErr
+		}
+		return nil
+	},
+}
+
+// serveAPI answers the requests of cs serve.
+type serveAPI struct {
+	// mu serializes requests; lockState only excludes other processes.
+	mu       sync.Mutex
+	hub      *server.Hub
+	watcher  *server.Watcher
+	sessions map[string]*execSession
+}
+
+type execSession struct {
+	env     *profile.IsolatedEnv
+	started time.Time
+}
+
+// execSessionDoc describes an exec session. Unlike the documents in
+// output.go, Env holds the profile's variables with their values: the
+// caller needs them to run its command, and only the owner can reach the
+// socket.
+type execSessionDoc struct {
+	ID        string            `json:"id"`
+	Profile   string            `json:"profile"`
+	ConfigDir string            `json:"config_dir"`
+	Env       map[string]string `json:"env"`
+	StartedAt time.Time         `json:"started_at"`
+	// Saved lists the files written back into the profile on stop.
+	Saved []string `json:"saved,omitempty"`
+}
+
+type execSessionListDoc struct {
+	Sessions []execSessionDoc `json:"sessions"`
+}
+
+type errorDoc struct {
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// profileRequest is the body of POST /v1/use and POST /v1/sessions.
+type profileRequest struct {
+	Profile string `json:"profile"`
+}
+
+// apiHandler handles one request and returns the kind and data of the
+// document to answer with.
+type apiHandler func(r *http.Request) (string, any, error)
+
+func newServeAPI() *serveAPI {
+	hub := server.NewHub()
+	return &serveAPI{
+		hub:      hub,
+		watcher:  &server.Watcher{Hub: hub, Warning: serveExpiryWarning, TokenInterval: serveTokenInterval},
+		sessions: map[string]*execSession{},
+	}
+}
+
+func (a *serveAPI) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /v1/profiles", a.handle(a.listProfiles))
+	mux.Handle("GET /v1/current", a.handle(a.current))
+	mux.Handle("POST /v1/use", a.handle(a.use))
+	mux.Handle("GET /v1/status", a.handle(a.status))
+	mux.Handle("GET /v1/status/{name}", a.handle(a.tokenStatus))
+	mux.Handle("GET /v1/limits", a.handle(a.limits))
+	mux.Handle("POST /v1/sessions", a.handle(a.startSession))
+	mux.Handle("GET /v1/sessions", a.handle(a.listSessions))
+	mux.Handle("DELETE /v1/sessions/{id}", a.handle(a.stopSession))
+	mux.Handle("GET /v1/events", a.hub)
+	mux.Handle("/", a.handle(func(r *http.Request) (string, any, error) {
+		return "", nil, withExitCode(exitNotFound, fmt.Errorf("no endpoint %s %s", r.Method, r.URL.Path))
+	}))
+	return mux
+}
+
+// handle runs h with the other requests held off and writes its result, or
+// its error with the HTTP status matching the exit code cs would use.
+func (a *serveAPI) handle(h apiHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		kind, data, err := h(r)
+		a.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			code := exitCodeFor(err)
+			switch code {
+			case exitUsage:
+				w.WriteHeader(http.StatusBadRequest)
+			case exitNotFound:
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			kind, data = "Error", errorDoc{Message: err.Error(), ExitCode: code}
+		}
+		_ = output.Write(w, output.JSON, kind, data)
+	})
+}
+
+func (a *serveAPI) listProfiles(r *http.Request) (string, any, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	profiles := profile.NewManager(cfg).List()
+	doc := profileListDoc{Active: cfg.ActiveProfile, Profiles: make([]profileDoc, 0, len(profiles))}
+	for _, p := range profiles {
+		doc.Profiles = append(doc.Profiles, newProfileDoc(p))
+	}
+	return "ProfileList", doc, nil
+}
+
+func (a *serveAPI) current(r *http.Request) (string, any, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	mgr := profile.NewManager(cfg)
+	p, err := mgr.Current()
+	if err != nil {
+		return "", nil, withExitCode(exitNotFound, err)
+	}
+	return "CurrentProfile", currentDoc{Profile: newProfileDoc(*p), Drift: newDriftDoc(mgr.DetectDrift())}, nil
+}
+
+func (a *serveAPI) use(r *http.Request) (string, any, error) {
+	name, err := requestedProfile(r)
+	if err != nil {
+		return "", nil, err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	entry, ok := cfg.Profiles[name]
+	if !ok {
+		return "", nil, withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+	}
+	if entry.IsEnv() {
+		return "", nil, withExitCode(exitUsage, fmt.Errorf("profile %q holds environment variables and cannot be switched to — start a session with it instead", name))
+	}
+	if err := switchProfile(name); err != nil {
+		return "", nil, err
+	}
+	// Tell the event stream now rather than at the next poll.
+	_ = a.watcher.Poll(time.Now())
+	return a.current(r)
+}
+
+func (a *serveAPI) status(r *http.Request) (string, any, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	mgr := profile.NewManager(cfg)
+	profiles := mgr.List()
+	doc := statusDoc{Profiles: make([]statusEntryDoc, 0, len(profiles))}
+	for _, p := range profiles {
+		doc.Profiles = append(doc.Profiles, newStatusEntryDoc(p, profile.CheckTokenStatus(p.Name)))
+	}
+	doc.Drift = newDriftDoc(mgr.DetectDrift())
+	return "ProfileStatus", doc, nil
+}
+
+func (a *serveAPI) tokenStatus(r *http.Request) (string, any, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	name := r.PathValue("name")
+	for _, p := range profile.NewManager(cfg).List() {
+		if p.Name == name {
+			return "TokenStatus", newStatusEntryDoc(p, profile.CheckTokenStatus(name)), nil
+		}
+	}
+	return "", nil, withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+}
+
+func (a *serveAPI) limits(r *http.Request) (string, any, error) {
+	query := r.URL.Query()
+	all := false
+	if v := query.Get("all"); v != "" {
+		var err error
+		if all, err = strconv.ParseBool(v); err != nil {
+			return "", nil, withExitCode(exitUsage, fmt.Errorf("invalid all=%q", v))
+		}
+	}
+	name := query.Get("profile")
+	if all && name != "" {
+		return "", nil, withExitCode(exitUsage, fmt.Errorf("all and profile cannot be used together"))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case all:
+	case name != "":
+		if _, ok := cfg.Profiles[name]; !ok {
+			return "", nil, withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+		}
+	case cfg.ActiveProfile == "":
+		return "", nil, withExitCode(exitNotFound, fmt.Errorf("no active profile — import a profile first with 'cs import <name>'"))
+	default:
+		name = cfg.ActiveProfile
+	}
+
+	store, err := refreshLimits(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	if all {
+		doc, err := limitsComparison(cfg)
+		return "UsageInfoList", doc, err
+	}
+
+	info, err := profileUsageInfo(cfg, name)
+	if err != nil {
+		return "", nil, err
+	}
+	doc := limitsDoc{Profile: name, UsageInfo: info}
+	if query.Get("profile") == "" {
+		now := time.Now()
+		doc.Profiles = []profileLimitDoc{}
+		for _, p := range profile.NewManager(cfg).List() {
+			doc.Profiles = append(doc.Profiles, newProfileLimitDoc(p.Name, store, now))
+		}
+	}
+	return "UsageInfo", doc, nil
+}
+
+func (a *serveAPI) startSession(r *http.Request) (string, any, error) {
+	name, err := requestedProfile(r)
+	if err != nil {
+		return "", nil, err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	if _, ok := cfg.Profiles[name]; !ok {
+		return "", nil, withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+	}
+	env, err := profile.SetupIsolatedEnv(name, cfg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		_ = env.Cleanup()
+		return "", nil, err
+	}
+	s := &execSession{env: env, started: time.Now()}
+	a.sessions[id] = s
+	ui.Info("Started exec session %s for profile %q", id, name)
+	return "ExecSession", s.doc(id), nil
+}
+
+func (a *serveAPI) listSessions(r *http.Request) (string, any, error) {
+	ids := make([]string, 0, len(a.sessions))
+	for id := range a.sessions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return a.sessions[ids[i]].started.Before(a.sessions[ids[j]].started) })
+
+	doc := execSessionListDoc{Sessions: make([]execSessionDoc, 0, len(ids))}
+	for _, id := range ids {
+		doc.Sessions = append(doc.Sessions, a.sessions[id].doc(id))
+	}
+	return "ExecSessionList", doc, nil
+}
+
+func (a *serveAPI) stopSession(r *http.Request) (string, any, error) {
+	id := r.PathValue("id")
+	s, ok := a.sessions[id]
+	if !ok {
+		return "", nil, withExitCode(exitNotFound, fmt.Errorf("no exec session %q", id))
+	}
+	writeback := true
+	if v := r.URL.Query().Get("writeback"); v != "" {
+		var err error
+		if writeback, err = strconv.ParseBool(v); err != nil {
+			return "", nil, withExitCode(exitUsage, fmt.Errorf("invalid writeback=%q", v))
+		}
+	}
+
+	doc := s.doc(id)
+	if writeback {
+		saved, err := s.writeBack()
+		if err != nil {
+			// Keep the session so the credentials aren't lost.
+			return "", nil, err
+		}
+		doc.Saved = saved
+	}
+	if err := s.env.Cleanup(); err != nil {
+		return "", nil, fmt.Errorf("cannot remove %s: %w", s.env.TempDir, err)
+	}
+	delete(a.sessions, id)
+	ui.Info("Stopped exec session %s", id)
+	return "ExecSession", doc, nil
+}
+
+// stopSessions saves back and removes the sessions still running when the
+// server exits.
+func (a *serveAPI) stopSessions() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, s := range a.sessions {
+		if _, err := s.writeBack(); err != nil {
+			ui.Warn("Credentials refreshed in exec session %s were not saved: %s", id, err)
+		}
+		_ = s.env.Cleanup()
+		delete(a.sessions, id)
+	}
+}
+
+// writeBack saves credentials refreshed in the session into its profile.
+func (s *execSession) writeBack() ([]string, error) {
+	unlock, err := lockState()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return s.env.WriteBack(cfg)
+}
+
+func (s *execSession) doc(id string) execSessionDoc {
+	env := map[string]string{}
+	for _, kv := range sessionExports(s.env) {
+		env[kv[0]] = kv[1]
+	}
+	return execSessionDoc{
+		ID:        id,
+		Profile:   s.env.ProfileName,
+		ConfigDir: s.env.TempDir,
+		Env:       env,
+		StartedAt: s.started.UTC(),
+	}
+}
+
+// requestedProfile reads the profile named in a request body.
+func requestedProfile(r *http.Request) (string, error) {
+	var req profileRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		return "", withExitCode(exitUsage, fmt.Errorf("invalid request body: %w", err))
+	}
+	if req.Profile == "" {
+		return "", withExitCode(exitUsage, fmt.Errorf("no profile given"))
+	}
+	return req.Profile, nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func init() {
+	serveCmd.Flags().DurationVar(&servePoll, "poll", 2*time.Second, "How often to check for switches and expiring tokens")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <profile>",
+	Short: "Start a shell that uses a profile without switching globally",
+	Long: `Shell starts $SHELL with CLAUDE_CONFIG_DIR pointing at a persistent
+per-profile directory (~/.claude-switch/sessions/<profile>) and CS_PROFILE
+set to the profile name. Claude Code run from that shell uses the profile,
+while other terminals and the global ~/.claude session are left alone.
+
+The session directory is kept between runs, so history and tokens refreshed
+by Claude Code carry over; refreshed tokens are also saved back into the
+profile when the shell exits. Exit the shell to return.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if _, ok := cfg.Profiles[name]; !ok {
+			return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+		}
+
+		if current := os.Getenv(profileEnvVar); current != "" {
+			ui.Warn("Already in a shell for profile %q — starting a nested shell", current)
+		}
+
+		env, err := profile.SetupSessionEnv(name, cfg)
+		if err != nil {
+			return err
+		}
+
+		shell := userShell()
+		ui.Info("Starting %s with profile %q — exit to return", filepath.Base(shell), name)
+
+		child := exec.Command(shell)
+		child.Stdin = os.Stdin
+		child.Stdout = os.Stdout
+		child.Stderr = os.Stderr
+		child.Env = sessionEnviron(env)
+
+		err = child.Run()
+		// Claude Code may have rotated the session's tokens; save them so
+		// the next 'cs use' or 'cs exec' doesn't start from revoked ones.
+		writeBackCredentials(env)
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return exitStatus(exitErr.ExitCode())
+			}
+			return fmt.Errorf("cannot start %s: %w", shell, err)
+		}
+		return nil
+	},
+}
+
+var (
+	envShell string
+	envUnset bool
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env <profile>",
+	Short: "Print export statements that point a shell at a profile",
+	Long: `Env prints the statements that make the current shell use a profile,
+like 'cs shell' but without starting a new shell:
+
+  bash/zsh:    eval "$(cs env work)"
+  fish:        cs env work --shell fish | source
+  PowerShell:  cs env work --shell powershell | Invoke-Expression
+
+Use --unset to print the statements that undo them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		shell := envShell
+		if shell == "" {
+			shell = detectShell()
+		}
+		if shell == "ps" || shell == "pwsh" {
+			shell = "powershell"
+		}
+		switch shell {
+		case "bash", "zsh", "fish", "powershell":
+		default:
+			return withExitCode(exitUsage, fmt.Errorf("unsupported shell: %s (use bash, zsh, fish, or powershell)", shell))
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		entry, ok := cfg.Profiles[name]
+		if !ok {
+			return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+		}
+
+		if envUnset {
+			for _, key := range append(append([]string{}, entry.EnvVars...), claudeConfigDirVar, profileEnvVar) {
+				fmt.Println(shellUnset(shell, key))
+			}
+			return nil
+		}
+
+		// Keep stdout clean for eval if the vault needs to say anything.
+		restore := ui.SetOutput(os.Stderr)
+		env, err := profile.SetupSessionEnv(name, cfg)
+		restore()
+		if err != nil {
+			return err
+		}
+
+		for _, kv := range sessionExports(env) {
+			fmt.Println(shellExport(shell, kv[0], kv[1]))
+		}
+		return nil
+	},
+}
+
+// sessionExports lists the variables that put a shell on a profile, in a
+// stable order: the profile's own variables, then CLAUDE_CONFIG_DIR and
+// CS_PROFILE.
+func sessionExports(env *profile.IsolatedEnv) [][2]string {
+	keys := make([]string, 0, len(env.Vars))
+	for key := range env.Vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	exports := make([][2]string, 0, len(keys)+2)
+	for _, key := range keys {
+		exports = append(exports, [2]string{key, env.Vars[key]})
+	}
+	return append(exports,
+		[2]string{claudeConfigDirVar, env.TempDir},
+		[2]string{profileEnvVar, env.ProfileName},
+	)
+}
+
+// sessionEnviron returns the environment for a shell started on a profile.
+// The shell hook's bookkeeping is dropped so a hook running inside the new
+// shell starts fresh instead of undoing the session.
+func sessionEnviron(env *profile.IsolatedEnv) []string {
+	var result []string
+	for _, kv := range env.Env() {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, "_CS_HOOK_") || key == profileEnvVar {
+			continue
+		}
+		result = append(result, kv)
+	}
+	return append(result, profileEnvVar+"="+env.ProfileName)
+}
+
+// userShell returns the login shell to start for 'cs shell'.
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		if path, err := exec.LookPath("pwsh"); err == nil {
+			return path
+		}
+		if path, err := exec.LookPath("powershell"); err == nil {
+			return path
+		}
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+	}
+	return "/bin/sh"
+}
+
+// detectShell guesses the syntax 'cs env' should print from $SHELL.
+func detectShell() string {
+	switch base := strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe"); base {
+	case "zsh", "fish", "bash":
+		return base
+	case "pwsh", "powershell":
+		return "powershell"
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "bash"
+}
+
+func init() {
+	envCmd.Flags().StringVar(&envShell, "shell", "", "Shell syntax: bash, zsh, fish, or powershell (default: detected from $SHELL)")
+	envCmd.Flags().BoolVar(&envUnset, "unset", false, "Print statements that undo 'cs env'")
+	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(envCmd)
+}
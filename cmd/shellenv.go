@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shellExport returns a statement that sets an environment variable in the
+// given shell (bash, zsh, fish or powershell).
+func shellExport(shell, key, value string) string {
+	switch shell {
+	case "fish":
+		return fmt.Sprintf("set -gx %s %s;", key, fishQuote(value))
+	case "powershell":
+		return fmt.Sprintf("$env:%s = %s", key, powerShellQuote(value))
+	default:
+		return fmt.Sprintf("export %s=%s;", key, posixQuote(value))
+	}
+}
+
+// shellUnset returns a statement that removes an environment variable.
+func shellUnset(shell, key string) string {
+	switch shell {
+	case "fish":
+		return fmt.Sprintf("set -e %s;", key)
+	case "powershell":
+		return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue", key)
+	default:
+		return fmt.Sprintf("unset %s;", key)
+	}
+}
+
+// posixQuote single-quotes s for sh-compatible shells.
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes s for fish, where \ and ' are escaped inside quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// powerShellQuote single-quotes s for PowerShell, doubling embedded quotes.
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -23,12 +23,21 @@ are valid, expired, or missing.`,
 		mgr := profile.NewManager(cfg)
 		profiles := mgr.List()
 
+		if machineOutput() {
+			doc := statusDoc{Profiles: make([]statusEntryDoc, 0, len(profiles))}
+			for _, p := range profiles {
+				doc.Profiles = append(doc.Profiles, newStatusEntryDoc(p, profile.CheckTokenStatus(p.Name)))
+			}
+			doc.Drift = newDriftDoc(mgr.DetectDrift())
+			return writeOutput("ProfileStatus", doc)
+		}
+
 		if len(profiles) == 0 {
 			ui.Info("No profiles saved yet.")
 			return nil
 		}
 
-		table := ui.NewTable("", "PROFILE", "EMAIL", "STATUS", "EXPIRES")
+		table := ui.NewTable("", "PROFILE", "KIND", "EMAIL", "PLAN", "STATUS", "EXPIRES")
 
 		for _, p := range profiles {
 			status := profile.CheckTokenStatus(p.Name)
@@ -45,8 +54,18 @@ are valid, expired, or missing.`,
 				email = ui.Colorize(ui.Gray, "-")
 			}
 
+			plan := status.SubscriptionType
+			if plan == "" {
+				plan = ui.Colorize(ui.Gray, "-")
+			}
+
+			kind := profileKindLabel(status)
+
 			var statusStr, expiresStr string
-			if !status.HasCreds {
+			if status.Kind == config.ProfileKindEnv {
+				statusStr = ui.Colorize(ui.Green, "configured")
+				expiresStr = ui.Colorize(ui.Gray, "-")
+			} else if !status.HasCreds {
 				statusStr = ui.Colorize(ui.Red, "no credentials")
 				expiresStr = ui.Colorize(ui.Gray, "-")
 			} else if status.IsExpired {
@@ -60,18 +79,23 @@ are valid, expired, or missing.`,
 				expiresStr = ui.Colorize(ui.Gray, "-")
 			}
 
-			table.AddRow(marker, name, email, statusStr, expiresStr)
+			table.AddRow(marker, name, kind, email, plan, statusStr, expiresStr)
 		}
 
 		table.Render()
 		fmt.Printf("\n%d profile(s)\n", len(profiles))
 
+		if report := mgr.DetectDrift(); report.Drifted() {
+			fmt.Println()
+			ui.Warn("%s. Run: cs sync", report.Describe())
+		}
+
 		// Token refresh hint
 		for _, p := range profiles {
 			status := profile.CheckTokenStatus(p.Name)
 			if status.IsExpired {
 				fmt.Println()
-				ui.Warn("Profile %q has expired credentials. Run: cs login %s", p.Name, p.Name)
+				ui.Warn("Profile %q has expired credentials. Run: cs refresh %s (or cs login %s)", p.Name, p.Name, p.Name)
 				break
 			}
 		}
@@ -80,6 +104,19 @@ are valid, expired, or missing.`,
 	},
 }
 
+// profileKindLabel describes how a profile authenticates: the login kind of
+// a credential profile, or "env:<provider>" for an environment profile.
+func profileKindLabel(status profile.TokenStatus) string {
+	switch {
+	case status.Kind == config.ProfileKindEnv:
+		return "env:" + status.Provider
+	case status.Kind != "":
+		return status.Kind
+	default:
+		return ui.Colorize(ui.Gray, "-")
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
 }
@@ -48,6 +48,18 @@ and lets you pick one to switch to.`,
 			return err
 		}
 
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		// Another cs process may have switched while the menu was open.
+		if cfg, err = config.Load(); err != nil {
+			return err
+		}
+		mgr = profile.NewManager(cfg)
+
 		if cfg.ActiveProfile == selected {
 			ui.Info("Already using profile %q", selected)
 			return nil
@@ -62,7 +74,7 @@ and lets you pick one to switch to.`,
 		// Token health warning
 		status := profile.CheckTokenStatus(selected)
 		if status.IsExpired {
-			ui.Warn("This profile's token appears expired. Run: cs login %s", selected)
+			ui.Warn("This profile's token appears expired. Run: cs refresh %s", selected)
 		}
 
 		return nil
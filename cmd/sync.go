@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var (
+	syncImportName  string
+	syncDescription string
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Reconcile the active profile with the live Claude session",
+	Long: `Sync compares the account Claude Code is currently logged into with
+every saved profile.
+
+If the live session belongs to another saved profile, that profile is
+marked active. If it belongs to an account no profile knows about, sync
+offers to import it as a new profile (use --import <name> to skip the prompt).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if err := config.EnsureDirs(); err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		mgr := profile.NewManager(cfg)
+		report := mgr.DetectDrift()
+
+		switch report.Kind {
+		case profile.DriftNone:
+			ui.Success("In sync: %s", report.Describe())
+			return nil
+
+		case profile.DriftNoSession:
+			ui.Info("No live Claude session found — nothing to sync.")
+			return nil
+
+		case profile.DriftOtherProfile:
+			if err := mgr.Adopt(report.Match); err != nil {
+				return err
+			}
+			ui.Success("Active profile set to %q (was %q)", report.Match, report.Active)
+			return nil
+		}
+
+		// Unknown account: import it as a new profile.
+		ui.Warn("%s", report.Describe())
+
+		name := syncImportName
+		if name == "" {
+			if !ui.Confirm("Import it as a new profile?") {
+				return nil
+			}
+			name, err = ui.Prompt("Profile name: ")
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, exists := cfg.Profiles[name]; exists {
+			return fmt.Errorf("profile %q already exists — choose another name", name)
+		}
+
+		if err := mgr.Import(name, syncDescription); err != nil {
+			return err
+		}
+		if err := mgr.Adopt(name); err != nil {
+			return err
+		}
+
+		ui.Success("Imported live session as profile %q and marked it active", name)
+		return nil
+	},
+}
+
+// warnDrift prints a warning when the live Claude session does not belong
+// to the active profile.
+func warnDrift(mgr *profile.Manager) {
+	if report := mgr.DetectDrift(); report.Drifted() {
+		ui.Warn("%s. Run: cs sync", report.Describe())
+	}
+}
+
+func init() {
+	syncCmd.Flags().StringVar(&syncImportName, "import", "", "Import an unknown live account under this name without prompting")
+	syncCmd.Flags().StringVarP(&syncDescription, "description", "d", "", "Description for an imported profile")
+	rootCmd.AddCommand(syncCmd)
+}
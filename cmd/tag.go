@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+var tagRemove bool
+
+var tagCmd = &cobra.Command{
+	Use:   "tag <profile> [tag...]",
+	Short: "Add, remove or list the tags of a profile",
+	Long: `Tag groups profiles so commands like 'cs exec-all --tag <tag>' can act
+on several at once. Without tags it prints the profile's tags.
+
+Examples:
+  cs tag work team batch
+  cs tag work --remove batch`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, tags := args[0], args[1:]
+
+		if len(tags) == 0 {
+			if tagRemove {
+				return withExitCode(exitUsage, fmt.Errorf("--remove needs at least one tag"))
+			}
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			p, ok := cfg.Profiles[name]
+			if !ok {
+				return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+			}
+			if len(p.Tags) > 0 {
+				fmt.Println(strings.Join(p.Tags, " "))
+			}
+			return nil
+		}
+
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if _, ok := cfg.Profiles[name]; !ok {
+			return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", name))
+		}
+
+		mgr := profile.NewManager(cfg)
+		if tagRemove {
+			err = mgr.Tag(name, nil, tags)
+		} else {
+			err = mgr.Tag(name, tags, nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		if current := cfg.Profiles[name].Tags; len(current) > 0 {
+			ui.Success("Profile %q tagged: %s", name, strings.Join(current, ", "))
+		} else {
+			ui.Success("Profile %q has no tags", name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	tagCmd.Flags().BoolVar(&tagRemove, "remove", false, "Remove the given tags instead of adding them")
+	rootCmd.AddCommand(tagCmd)
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+	"github.com/caeser1996/claude-switch/internal/usage"
+)
+
+var (
+	usageProfile string
+	usageSince   string
+	usageBy      string
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage per profile from Claude Code transcripts",
+	Long: `Usage adds up the tokens recorded in Claude Code's transcripts: the
+ones in ~/.claude/projects, attributed to the profile that was active when
+each message was sent, and the ones kept per profile by 'cs exec', 'cs
+shell' and the shell hook.
+
+Transcripts are indexed in ~/.claude-switch/usage-index.json, so later
+runs only read what was added since. Usage from before the first recorded
+switch is attributed to the profile that was active then, as far as cs
+can tell.
+
+Examples:
+  cs usage --since 5h
+  cs usage --profile work --by day --since 7d
+  cs usage --by model -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		by := usageBy
+		if !validGroupBy(by) {
+			return withExitCode(exitUsage, fmt.Errorf("invalid --by %q (use %s)", by, strings.Join(usage.GroupBys(), ", ")))
+		}
+		var since time.Time
+		if usageSince != "" {
+			var err error
+			if since, err = parseSince(usageSince, time.Now()); err != nil {
+				return withExitCode(exitUsage, err)
+			}
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if usageProfile != "" {
+			if _, ok := cfg.Profiles[usageProfile]; !ok {
+				return withExitCode(exitNotFound, fmt.Errorf("profile %q not found", usageProfile))
+			}
+		}
+
+		ix, err := loadUsageIndex()
+		if err != nil {
+			return err
+		}
+		history, err := profile.LoadHistory()
+		if err != nil {
+			return err
+		}
+
+		groups, total := ix.Report(usage.Query{
+			Since:   since,
+			Profile: usageProfile,
+			By:      by,
+			Attribute: func(t time.Time) string {
+				return history.ProfileAt(t, cfg.ActiveProfile)
+			},
+		})
+
+		doc := usageDoc{By: by, Profile: usageProfile, Groups: groups, Total: total}
+		if !since.IsZero() {
+			s := since.UTC()
+			doc.Since = &s
+		}
+		if machineOutput() {
+			return writeOutput("UsageReport", doc)
+		}
+
+		if len(groups) == 0 {
+			ui.Info("No usage recorded%s.", sinceSuffix(usageSince))
+			return nil
+		}
+		table := ui.NewTable(strings.ToUpper(by), "MESSAGES", "INPUT", "OUTPUT", "CACHE WRITE", "CACHE READ")
+		row := func(key string, t usage.Totals) {
+			table.AddRow(key, strconv.Itoa(t.Messages), formatTokens(t.Input), formatTokens(t.Output),
+				formatTokens(t.CacheCreate), formatTokens(t.CacheRead))
+		}
+		for _, g := range groups {
+			row(g.Key, g.Totals)
+		}
+		if len(groups) > 1 {
+			row("total", total)
+		}
+		table.Render()
+		return nil
+	},
+}
+
+// loadUsageIndex brings the usage index up to date with the transcripts and
+// debug logs in the live Claude directory and in each profile's session
+// directory.
+func loadUsageIndex() (*usage.Index, error) {
+	path, err := config.UsageIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	claudeDir, err := config.ClaudeConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	sources := []usage.Source{{Dir: claudeDir}}
+	if sessionsDir, err := config.SessionsDir(); err == nil {
+		entries, _ := os.ReadDir(sessionsDir)
+		for _, e := range entries {
+			if e.IsDir() {
+				sources = append(sources, usage.Source{
+					Dir:     filepath.Join(sessionsDir, e.Name()),
+					Profile: e.Name(),
+				})
+			}
+		}
+	}
+
+	ix := usage.LoadIndex(path)
+	changed, err := ix.Update(sources)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := config.EnsureDirs(); err != nil {
+			return nil, err
+		}
+		if err := ix.Save(path); err != nil && verbose {
+			ui.Warn("Usage index not saved: %s", err)
+		}
+	}
+	return ix, nil
+}
+
+func validGroupBy(by string) bool {
+	for _, b := range usage.GroupBys() {
+		if b == by {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSince reads a --since value: a duration back from now such as
+// "90m", "5h", "7d" or "2w", or a date (2006-01-02).
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if n := len(s); n > 1 {
+		if mult, ok := unit[s[n-1]]; ok {
+			count, err := strconv.Atoi(s[:n-1])
+			if err == nil && count >= 0 {
+				return now.Add(-time.Duration(count) * mult), nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q (use e.g. 5h, 24h, 7d, or a date like 2026-01-31)", s)
+	}
+	return now.Add(-d), nil
+}
+
+func sinceSuffix(since string) string {
+	if since == "" {
+		return ""
+	}
+	return " in the last " + since
+}
+
+// formatTokens renders a token count compactly: 950, 12.3k, 4.56M.
+func formatTokens(n int64) string {
+	switch {
+	case n < 1000:
+		return strconv.FormatInt(n, 10)
+	case n < 1000000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	case n < 1000000000:
+		return fmt.Sprintf("%.2fM", float64(n)/1e6)
+	default:
+		return fmt.Sprintf("%.2fB", float64(n)/1e9)
+	}
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageProfile, "profile", "", "Only count this profile's usage")
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "Only count usage since a time ago (5h, 24h, 7d) or a date")
+	usageCmd.Flags().StringVar(&usageBy, "by", usage.ByProfile, "Group by "+strings.Join(usage.GroupBys(), ", "))
+	rootCmd.AddCommand(usageCmd)
+}
@@ -32,42 +32,73 @@ directory (or any parent), that profile is used automatically.`,
 			ui.Info("Detected .claude-profile: %s", name)
 		}
 
-		cfg, err := config.Load()
-		if err != nil {
-			return err
-		}
+		return switchProfile(name)
+	},
+}
 
-		if cfg.ActiveProfile == name {
-			ui.Info("Already using profile %q", name)
-			return nil
-		}
+// switchProfile makes name the active profile, as 'cs use' does: adopting
+// a live session that already belongs to it, warning before replacing an
+// unknown login, and checking the token afterwards.
+func switchProfile(name string) error {
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-		mgr := profile.NewManager(cfg)
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
 
-		if verbose {
-			ui.Info("Switching from %q to %q...", cfg.ActiveProfile, name)
-		}
+	mgr := profile.NewManager(cfg)
+	report := mgr.DetectDrift()
 
-		if err := mgr.Use(name); err != nil {
+	if report.Kind == profile.DriftOtherProfile && report.Match == name {
+		// Claude is already logged into this profile's account; keep the
+		// live tokens and just fix up the config.
+		if err := mgr.Adopt(name); err != nil {
 			return err
 		}
+		ui.Success("Switched to profile %q (live session already belonged to it)", name)
+		return nil
+	}
 
-		ui.Success("Switched to profile %q", name)
+	if cfg.ActiveProfile == name && !report.Drifted() {
+		ui.Info("Already using profile %q", name)
+		return nil
+	}
 
-		if p, ok := cfg.Profiles[name]; ok && p.Email != "" {
-			ui.Info("Email: %s", p.Email)
+	if report.Kind == profile.DriftUnknown {
+		ui.Warn("%s — it will be replaced", report.Describe())
+		if cfg.Settings.AutoBackup {
+			ui.Info("A backup is taken first; see 'cs backup list'")
 		}
+	}
 
-		// Token health check after switch
-		status := profile.CheckTokenStatus(name)
-		if status.IsExpired {
-			ui.Warn("Token for %q appears expired. Run: cs login %s", name, name)
-		} else if profile.NeedsRefresh(status, 24*time.Hour) {
-			ui.Warn("Token for %q expires soon (%s). Consider: cs login %s", name, status.ExpiresIn, name)
-		}
+	if verbose {
+		ui.Info("Switching from %q to %q...", cfg.ActiveProfile, name)
+	}
 
-		return nil
-	},
+	if err := mgr.Use(name); err != nil {
+		return err
+	}
+
+	ui.Success("Switched to profile %q", name)
+
+	if p, ok := cfg.Profiles[name]; ok && p.Email != "" {
+		ui.Info("Email: %s", p.Email)
+	}
+
+	// Token health check after switch
+	status := profile.CheckTokenStatus(name)
+	if status.IsExpired {
+		ui.Warn("Token for %q appears expired. Run: cs refresh %s (or cs login %s)", name, name, name)
+	} else if profile.NeedsRefresh(status, 24*time.Hour) {
+		ui.Warn("Token for %q expires soon (%s). Consider: cs refresh %s", name, status.ExpiresIn, name)
+	}
+
+	return nil
 }
 
 func init() {
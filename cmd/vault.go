@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+	"github.com/caeser1996/claude-switch/internal/ui"
+	"github.com/caeser1996/claude-switch/internal/vault"
+)
+
+var (
+	vaultKeyFile  string
+	vaultTTL      time.Duration
+	vaultAgentTTL time.Duration
+)
+
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Encrypt stored profiles and backups at rest",
+	Long: `Vault encrypts every credential file under ~/.claude-switch (profiles and
+backups) with AES-256-GCM, using a key derived from a master passphrase.
+
+The live Claude session is never encrypted; files are decrypted transparently
+when you switch, exec, export or restore a backup.
+
+The passphrase is taken, in order, from CS_VAULT_PASSPHRASE, the configured
+key file, a running unlock agent ('cs vault unlock'), or an interactive prompt.`,
+}
+
+var vaultStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the vault is enabled and unlocked",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		v := cfg.Settings.Vault
+
+		enabled := ui.Colorize(ui.Gray, "disabled")
+		if v.Enabled {
+			enabled = ui.Colorize(ui.Green, "enabled")
+		}
+		fmt.Printf("  Vault:     %s\n", enabled)
+
+		if v.Enabled {
+			source := ui.Colorize(ui.Yellow, "locked (will prompt)")
+			switch {
+			case os.Getenv(vault.PassphraseEnvVar) != "":
+				source = "environment (" + vault.PassphraseEnvVar + ")"
+			case v.KeyFile != "":
+				source = "key file (" + v.KeyFile + ")"
+			case vault.AgentRunning():
+				source = ui.Colorize(ui.Green, "unlocked (agent)")
+			}
+			fmt.Printf("  Key:       %s\n", source)
+		}
+
+		sealed, plain, err := profile.CountSealed()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  Encrypted: %d file(s)\n", sealed)
+		fmt.Printf("  Plaintext: %d file(s)\n", plain)
+
+		if v.Enabled && plain > 0 {
+			ui.Warn("Some stored files are not encrypted — run 'cs vault rekey' to seal them")
+		}
+		return nil
+	},
+}
+
+var vaultEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Encrypt all stored profiles and backups",
+	Long: `Enable sets a master passphrase and encrypts every stored profile and
+backup with it. New profiles and backups are encrypted as they are written.
+
+With --key-file the passphrase is read from that file instead of prompting,
+and the file is remembered for later decryption.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if cfg.Settings.Vault.Enabled {
+			return fmt.Errorf("vault is already enabled — use 'cs vault rekey' to change the passphrase")
+		}
+
+		passphrase, keyFile, err := readNewVaultPassphrase(true)
+		if err != nil {
+			return err
+		}
+
+		settings, key, err := vault.Create(passphrase)
+		if err != nil {
+			return err
+		}
+		settings.KeyFile = keyFile
+
+		// Save the vault first: if sealing is interrupted, the key needed to
+		// read the already-sealed files is on record.
+		cfg.Settings.Vault = settings
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		profile.ConfigureVault(settings)
+
+		n, err := profile.ResealStore(nil, key)
+		if err != nil {
+			return fmt.Errorf("vault enabled, but sealing failed: %w", err)
+		}
+
+		ui.Success("Vault enabled — %d file(s) encrypted", n)
+		if keyFile == "" {
+			ui.Info("Run 'cs vault unlock' to avoid entering the passphrase for every command")
+		}
+		return nil
+	},
+}
+
+var vaultDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Decrypt all stored profiles and backups",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if !cfg.Settings.Vault.Enabled {
+			ui.Info("Vault is not enabled.")
+			return nil
+		}
+
+		key, err := vault.Key(cfg.Settings.Vault)
+		if err != nil {
+			return err
+		}
+
+		n, err := profile.ResealStore(key, nil)
+		if err != nil {
+			return fmt.Errorf("decryption failed: %w", err)
+		}
+
+		cfg.Settings.Vault = config.VaultSettings{}
+		if err := cfg.Save(); err != nil {
+			return err
+		}
+		_ = vault.StopAgent()
+		vault.Forget()
+
+		ui.Success("Vault disabled — %d file(s) decrypted", n)
+		return nil
+	},
+}
+
+var vaultRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Change the vault passphrase and re-encrypt stored files",
+	Long: `Rekey re-encrypts every stored profile and backup under a new passphrase.
+Any plaintext files left in the store are encrypted as well.
+
+The new passphrase is prompted for, or read from --key-file. A key file
+configured earlier is dropped unless it is passed again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		unlock, err := lockState()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if !cfg.Settings.Vault.Enabled {
+			return fmt.Errorf("vault is not enabled — run 'cs vault enable' first")
+		}
+
+		oldKey, err := vault.Key(cfg.Settings.Vault)
+		if err != nil {
+			return err
+		}
+
+		ui.Info("Choose the new vault passphrase.")
+		passphrase, keyFile, err := readNewVaultPassphrase(false)
+		if err != nil {
+			return err
+		}
+		settings, newKey, err := vault.Create(passphrase)
+		if err != nil {
+			return err
+		}
+		settings.KeyFile = keyFile
+
+		n, err := profile.ResealStore(oldKey, newKey)
+		if err != nil {
+			return fmt.Errorf("re-encryption failed: %w", err)
+		}
+
+		cfg.Settings.Vault = settings
+		if err := cfg.Save(); err != nil {
+			// Put the files back under the key the saved config still describes.
+			if _, rerr := profile.ResealStore(newKey, oldKey); rerr != nil {
+				return fmt.Errorf("cannot save config (%v) and cannot roll back: %w", err, rerr)
+			}
+			return err
+		}
+		_ = vault.StopAgent()
+
+		ui.Success("Vault passphrase changed — %d file(s) re-encrypted", n)
+		return nil
+	},
+}
+
+var vaultUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Cache the vault key in a background agent",
+	Long: `Unlock asks for the vault passphrase once and starts a background agent
+that hands the key to later cs commands over a private Unix socket in
+~/.claude-switch. The agent exits after --ttl or on 'cs vault lock'.
+
+In scripts, set CS_VAULT_PASSPHRASE instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if !cfg.Settings.Vault.Enabled {
+			return fmt.Errorf("vault is not enabled — run 'cs vault enable' first")
+		}
+
+		if key, err := vault.AgentKey(); err == nil && vault.Verify(cfg.Settings.Vault, key) {
+			ui.Info("Vault is already unlocked.")
+			return nil
+		}
+
+		// Unlock is the one place the passphrase may come from a pipe.
+		vault.SetPrompt(func() (string, error) {
+			return ui.ReadPassword("Vault passphrase: ")
+		})
+		key, err := vault.Key(cfg.Settings.Vault)
+		if err != nil {
+			return err
+		}
+
+		if err := vault.SpawnAgent(key, vaultTTL); err != nil {
+			return err
+		}
+
+		ui.Success("Vault unlocked for %s", vaultTTL)
+		return nil
+	},
+}
+
+var vaultLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Stop the unlock agent and forget the cached key",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vault.Forget()
+		if err := vault.StopAgent(); err != nil {
+			ui.Info("Vault is already locked.")
+			return nil
+		}
+		ui.Success("Vault locked")
+		return nil
+	},
+}
+
+var vaultAgentCmd = &cobra.Command{
+	Use:    "agent",
+	Short:  "Serve the vault key to other cs processes",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("cannot read key: %w", err)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(line))
+		if err != nil {
+			return fmt.Errorf("invalid key: %w", err)
+		}
+		return vault.ServeAgent(key, vaultAgentTTL)
+	},
+}
+
+// readNewVaultPassphrase returns a new vault passphrase from --key-file,
+// CS_VAULT_PASSPHRASE (when allowEnv is set), or an interactive prompt,
+// along with the absolute key file path when one was used.
+func readNewVaultPassphrase(allowEnv bool) (string, string, error) {
+	if vaultKeyFile != "" {
+		path, err := filepath.Abs(vaultKeyFile)
+		if err != nil {
+			return "", "", err
+		}
+		passphrase, err := vault.ReadKeyFile(path)
+		return passphrase, path, err
+	}
+
+	if passphrase := os.Getenv(vault.PassphraseEnvVar); allowEnv && passphrase != "" {
+		return passphrase, "", nil
+	}
+
+	passphrase, err := ui.ReadPassword("Enter vault passphrase: ")
+	if err != nil {
+		return "", "", err
+	}
+	if len(passphrase) < 8 {
+		return "", "", fmt.Errorf("passphrase must be at least 8 characters")
+	}
+	confirm, err := ui.ReadPassword("Confirm passphrase: ")
+	if err != nil {
+		return "", "", err
+	}
+	if passphrase != confirm {
+		return "", "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, "", nil
+}
+
+func init() {
+	vaultEnableCmd.Flags().StringVar(&vaultKeyFile, "key-file", "", "Read the passphrase from this file")
+	vaultRekeyCmd.Flags().StringVar(&vaultKeyFile, "key-file", "", "Read the new passphrase from this file")
+	vaultUnlockCmd.Flags().DurationVar(&vaultTTL, "ttl", 8*time.Hour, "How long the key stays cached")
+	vaultAgentCmd.Flags().DurationVar(&vaultAgentTTL, "ttl", 8*time.Hour, "How long to serve the key")
+
+	vaultCmd.AddCommand(vaultStatusCmd)
+	vaultCmd.AddCommand(vaultEnableCmd)
+	vaultCmd.AddCommand(vaultDisableCmd)
+	vaultCmd.AddCommand(vaultRekeyCmd)
+	vaultCmd.AddCommand(vaultUnlockCmd)
+	vaultCmd.AddCommand(vaultLockCmd)
+	vaultCmd.AddCommand(vaultAgentCmd)
+	rootCmd.AddCommand(vaultCmd)
+}
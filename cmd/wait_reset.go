@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/ui"
+)
+
+// waitResetLogInterval is how often a wait that isn't on a terminal reports
+// its progress.
+const waitResetLogInterval = 5 * time.Minute
+
+var (
+	waitResetProfile  string
+	waitResetAny      bool
+	waitResetThenUse  bool
+	waitResetThenExec bool
+	waitResetTimeout  time.Duration
+)
+
+var waitResetCmd = &cobra.Command{
+	Use:   "wait-reset [--profile name | --any] [--then-use] [--then-exec -- <command> [args...]]",
+	Short: "Wait until a profile's usage limit resets",
+	Long: `Wait-reset blocks until a profile is no longer limited, using the limits
+recorded by 'cs limits' and 'cs exec --pool', and prints the name of that
+profile on stdout.
+
+It waits for the active profile, the profile given with --profile, or with
+--any for whichever profile comes free first (the active one is preferred
+if several are; with --then-use, profiles holding environment variables are
+left out). If the profile isn't limited it returns at once. On a
+terminal it shows a live countdown; otherwise it logs a line every few
+minutes to stderr.
+
+--then-use switches to the profile once it is available. --then-exec runs
+the command after -- under it, as 'cs exec' would, and exits with the
+command's status; the profile name is then not printed.
+
+Examples:
+  cs wait-reset --any
+  cs wait-reset --profile work --then-use
+  cs wait-reset --any --timeout 6h --then-exec -- -p "finish the refactor"`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if waitResetThenExec {
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}
+		return cobra.NoArgs(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if waitResetAny && waitResetProfile != "" {
+			return withExitCode(exitUsage, fmt.Errorf("--any and --profile cannot be used together"))
+		}
+
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, claude.ForwardedSignals...)
+		defer signal.Stop(interrupted)
+
+		// stdout carries only the profile name, or the command's output.
+		defer ui.SetOutput(os.Stderr)()
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		candidates, err := waitResetCandidates(cfg)
+		if err != nil {
+			return err
+		}
+
+		store, err := refreshLimits(cfg)
+		if err != nil {
+			return err
+		}
+		var deadline time.Time
+		if waitResetTimeout > 0 {
+			deadline = time.Now().Add(waitResetTimeout)
+		}
+
+		var name string
+		for {
+			var reset time.Time
+			name, reset = nextAvailable(candidates, store, time.Now())
+			if reset.IsZero() {
+				break
+			}
+			if !deadline.IsZero() && reset.After(deadline) {
+				if err := waitUntil(deadline, name, reset, interrupted); err != nil {
+					return err
+				}
+				return fmt.Errorf("timed out after %s — %s resets at %s", waitResetTimeout, name, reset.Local().Format("Jan 2 15:04"))
+			}
+			if err := waitUntil(reset, name, reset, interrupted); err != nil {
+				return err
+			}
+			// A profile may have hit another limit in the meantime.
+			if store, err = claude.LoadLimits(); err != nil {
+				return err
+			}
+		}
+
+		if waitResetThenUse {
+			if err := switchProfile(name); err != nil {
+				return err
+			}
+			if cfg, err = config.Load(); err != nil {
+				return err
+			}
+		}
+		if !waitResetThenExec {
+			fmt.Println(name)
+			return nil
+		}
+
+		ui.Info("Profile %q is available", name)
+		program, cmdArgs := execProgram(args, false)
+		runErr := runInProfile(cfg, name, nil, interrupted, claude.RunOptions{
+			Program:          program,
+			Args:             cmdArgs,
+			ForwardInterrupt: !ui.IsTerminal(),
+		})
+		return execResult(program, runErr)
+	},
+}
+
+// waitResetCandidates lists the profiles wait-reset may return, in order
+// of preference.
+func waitResetCandidates(cfg *config.Config) ([]string, error) {
+	if waitResetProfile != "" {
+		entry, ok := cfg.Profiles[waitResetProfile]
+		if !ok {
+			return nil, withExitCode(exitNotFound, fmt.Errorf("profile %q not found", waitResetProfile))
+		}
+		if waitResetThenUse && entry.IsEnv() {
+			return nil, withExitCode(exitUsage, fmt.Errorf("profile %q holds environment variables and cannot be switched to — use --then-exec", waitResetProfile))
+		}
+		return []string{waitResetProfile}, nil
+	}
+	if !waitResetAny {
+		if cfg.ActiveProfile == "" {
+			return nil, withExitCode(exitNotFound, fmt.Errorf("no active profile — name one with --profile, or use --any"))
+		}
+		return []string{cfg.ActiveProfile}, nil
+	}
+
+	// --then-use can only switch to profiles with a login.
+	var names []string
+	for name, entry := range cfg.Profiles {
+		if name != cfg.ActiveProfile && !(waitResetThenUse && entry.IsEnv()) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := cfg.Profiles[cfg.ActiveProfile]; ok {
+		names = append([]string{cfg.ActiveProfile}, names...)
+	}
+	if len(names) == 0 {
+		return nil, withExitCode(exitNotFound, fmt.Errorf("no profiles to wait for — import one first with 'cs import <name>'"))
+	}
+	return names, nil
+}
+
+// nextAvailable returns the first candidate that isn't limited at now, or
+// else the one whose limit resets first, with its reset time.
+func nextAvailable(candidates []string, store claude.LimitStore, now time.Time) (string, time.Time) {
+	var (
+		next  string
+		reset time.Time
+	)
+	for _, name := range candidates {
+		ev, ok := store.Active(name, now)
+		if !ok {
+			return name, time.Time{}
+		}
+		if reset.IsZero() || ev.ResetAt.Before(reset) {
+			next, reset = name, ev.ResetAt
+		}
+	}
+	return next, reset
+}
+
+// waitUntil sleeps until t while showing how long is left until the reset
+// of profile name. It returns an exit status if a termination signal
+// arrives first.
+func waitUntil(t time.Time, name string, reset time.Time, interrupted <-chan os.Signal) error {
+	live := ui.IsTerminalFile(os.Stderr)
+	var logged time.Time
+	show := func(now time.Time) {
+		left := reset.Sub(now)
+		if live {
+			fmt.Fprintf(os.Stderr, "\r\033[KWaiting for %s — resets at %s, in %s",
+				ui.Colorize(ui.Bold, name), reset.Local().Format("15:04"), formatClock(left))
+			return
+		}
+		if logged.IsZero() || now.Sub(logged) >= waitResetLogInterval {
+			fmt.Fprintf(os.Stderr, "Waiting for %s: resets at %s, in %s\n",
+				name, reset.Local().Format("Jan 2 15:04"), claude.FormatCountdown(left))
+			logged = now
+		}
+	}
+	done := func() {
+		if live {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+		}
+	}
+
+	// Tick rather than sleep: a suspended machine can wake up past t.
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for now := time.Now(); now.Before(t); now = time.Now() {
+		show(now)
+		select {
+		case <-ticker.C:
+		case sig := <-interrupted:
+			done()
+			return exitStatus(claude.SignalExitCode(sig))
+		}
+	}
+	done()
+	return nil
+}
+
+// formatClock renders a duration as h:mm:ss for a live countdown.
+func formatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	s := int(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+func init() {
+	waitResetCmd.Flags().StringVar(&waitResetProfile, "profile", "", "Wait for this profile instead of the active one")
+	waitResetCmd.Flags().BoolVar(&waitResetAny, "any", false, "Wait for whichever profile comes free first")
+	waitResetCmd.Flags().BoolVar(&waitResetThenUse, "then-use", false, "Switch to the profile once it is available")
+	waitResetCmd.Flags().BoolVar(&waitResetThenExec, "then-exec", false, "Run the command after -- under the profile once it is available")
+	waitResetCmd.Flags().DurationVar(&waitResetTimeout, "timeout", 0, "Give up after this long (e.g. 6h)")
+	rootCmd.AddCommand(waitResetCmd)
+}
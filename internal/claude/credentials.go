@@ -0,0 +1,257 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Credential kinds understood by ParseCredentials.
+const (
+	KindOAuth  = "oauth"   // claude.ai login, nested under "claudeAiOauth"
+	KindAPIKey = "api-key" // Anthropic Console API key login
+	KindLegacy = "legacy"  // older flat credential files
+)
+
+// Credentials is a typed view of a Claude Code login, combining the
+// credential store (.credentials.json or Keychain) with the account
+// identity recorded in ~/.claude.json.
+type Credentials struct {
+	Kind string
+
+	AccessToken      string
+	RefreshToken     string
+	ExpiresAt        *time.Time
+	Scopes           []string
+	SubscriptionType string
+	RateLimitTier    string
+	APIKey           string
+
+	Email       string
+	DisplayName string
+	AccountUUID string
+	OrgUUID     string
+	OrgName     string
+	Plan        string
+}
+
+// oauthShape is the nested structure Claude Code writes for claude.ai logins.
+type oauthShape struct {
+	AccessToken      string          `json:"accessToken"`
+	RefreshToken     string          `json:"refreshToken"`
+	ExpiresAt        json.RawMessage `json:"expiresAt"`
+	Scopes           []string        `json:"scopes"`
+	SubscriptionType string          `json:"subscriptionType"`
+	RateLimitTier    string          `json:"rateLimitTier"`
+}
+
+// credentialFile covers the nested OAuth shape as well as legacy flat shapes.
+type credentialFile struct {
+	ClaudeAiOauth *oauthShape `json:"claudeAiOauth"`
+
+	Email        string          `json:"email"`
+	Plan         string          `json:"plan"`
+	OrgName      string          `json:"orgName"`
+	OrgNameSnake string          `json:"org_name"`
+	Token        string          `json:"token"`
+	AccessToken  string          `json:"accessToken"`
+	RefreshToken string          `json:"refreshToken"`
+	APIKey       string          `json:"apiKey"`
+	ExpiresAt    json.RawMessage `json:"expiresAt"`
+	ExpiresAtAlt json.RawMessage `json:"expires_at"`
+	Exp          json.RawMessage `json:"exp"`
+}
+
+// homeFile is the subset of ~/.claude.json that identifies the account.
+type homeFile struct {
+	PrimaryAPIKey string `json:"primaryApiKey"`
+	OAuthAccount  *struct {
+		AccountUUID      string `json:"accountUuid"`
+		EmailAddress     string `json:"emailAddress"`
+		OrganizationUUID string `json:"organizationUuid"`
+		OrganizationName string `json:"organizationName"`
+		DisplayName      string `json:"displayName"`
+	} `json:"oauthAccount"`
+}
+
+// ParseCredentials builds a Credentials value from the raw contents of a
+// credentials file and of ~/.claude.json. Either argument may be empty, but
+// an error is returned if neither yields any login information.
+func ParseCredentials(credData, homeData []byte) (*Credentials, error) {
+	c := &Credentials{}
+
+	if len(credData) > 0 {
+		var f credentialFile
+		if err := json.Unmarshal(credData, &f); err != nil {
+			return nil, fmt.Errorf("invalid credentials JSON: %w", err)
+		}
+		c.applyCredentialFile(&f)
+	}
+
+	if len(homeData) > 0 {
+		var h homeFile
+		if err := json.Unmarshal(homeData, &h); err == nil {
+			c.applyHomeFile(&h)
+		}
+	}
+
+	if c.Kind == "" {
+		return nil, fmt.Errorf("no login information found")
+	}
+	return c, nil
+}
+
+// LoadCredentials reads and parses a credentials file and an optional
+// ~/.claude.json-style file. Missing files are treated as empty.
+func LoadCredentials(credPath, homePath string) (*Credentials, error) {
+	var credData, homeData []byte
+	var err error
+
+	if credPath != "" {
+		credData, err = os.ReadFile(credPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot read %s: %w", credPath, err)
+		}
+	}
+	if homePath != "" {
+		homeData, err = os.ReadFile(homePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot read %s: %w", homePath, err)
+		}
+	}
+
+	return ParseCredentials(credData, homeData)
+}
+
+// IsExpired reports whether the access token has a known expiry in the past.
+func (c *Credentials) IsExpired() bool {
+	return c.ExpiresAt != nil && time.Now().After(*c.ExpiresAt)
+}
+
+// PlanName returns a display name for the account's plan.
+func (c *Credentials) PlanName() string {
+	if c.Plan != "" {
+		return c.Plan
+	}
+	switch strings.ToLower(c.SubscriptionType) {
+	case "":
+		if c.Kind == KindAPIKey {
+			return "API"
+		}
+		return ""
+	case "pro":
+		return "Pro"
+	case "max":
+		return "Max"
+	case "team":
+		return "Team"
+	case "enterprise":
+		return "Enterprise"
+	default:
+		return c.SubscriptionType
+	}
+}
+
+func (c *Credentials) applyCredentialFile(f *credentialFile) {
+	if o := f.ClaudeAiOauth; o != nil {
+		c.Kind = KindOAuth
+		c.AccessToken = o.AccessToken
+		c.RefreshToken = o.RefreshToken
+		c.ExpiresAt = parseTimestamp(o.ExpiresAt)
+		c.Scopes = o.Scopes
+		c.SubscriptionType = o.SubscriptionType
+		c.RateLimitTier = o.RateLimitTier
+	}
+
+	// Legacy flat fields; nested values above take precedence.
+	c.Email = f.Email
+	c.Plan = f.Plan
+	c.OrgName = f.OrgName
+	if c.OrgName == "" {
+		c.OrgName = f.OrgNameSnake
+	}
+
+	if c.Kind == "" {
+		c.AccessToken = firstNonEmpty(f.AccessToken, f.Token)
+		c.RefreshToken = f.RefreshToken
+		for _, raw := range []json.RawMessage{f.ExpiresAt, f.ExpiresAtAlt, f.Exp} {
+			if t := parseTimestamp(raw); t != nil {
+				c.ExpiresAt = t
+				break
+			}
+		}
+		if c.AccessToken != "" || c.RefreshToken != "" || c.Email != "" || c.ExpiresAt != nil {
+			c.Kind = KindLegacy
+		}
+	}
+
+	if f.APIKey != "" {
+		c.APIKey = f.APIKey
+		if c.Kind == "" {
+			c.Kind = KindAPIKey
+		}
+	}
+}
+
+func (c *Credentials) applyHomeFile(h *homeFile) {
+	if a := h.OAuthAccount; a != nil {
+		c.AccountUUID = a.AccountUUID
+		c.OrgUUID = a.OrganizationUUID
+		c.DisplayName = a.DisplayName
+		if a.EmailAddress != "" {
+			c.Email = a.EmailAddress
+		}
+		if a.OrganizationName != "" {
+			c.OrgName = a.OrganizationName
+		}
+		if c.Kind == "" && (a.AccountUUID != "" || a.EmailAddress != "") {
+			c.Kind = KindOAuth
+		}
+	}
+
+	if h.PrimaryAPIKey != "" {
+		c.APIKey = h.PrimaryAPIKey
+		if c.Kind == "" {
+			c.Kind = KindAPIKey
+		}
+	}
+}
+
+// parseTimestamp accepts RFC 3339 strings and numeric Unix timestamps in
+// seconds or milliseconds.
+func parseTimestamp(raw json.RawMessage) *time.Time {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return &t
+		}
+		return nil
+	}
+
+	var n float64
+	if err := json.Unmarshal(raw, &n); err != nil || n <= 0 {
+		return nil
+	}
+	var t time.Time
+	if n > 1e12 {
+		t = time.UnixMilli(int64(n))
+	} else {
+		t = time.Unix(int64(n), 0)
+	}
+	return &t
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package claude
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCredentialsNestedOAuth(t *testing.T) {
+	cred := []byte(`{"claudeAiOauth": {
+		"accessToken": "sk-ant-oat01-abc",
+		"refreshToken": "sk-ant-ort01-def",
+		"expiresAt": 1893456000000,
+		"scopes": ["user:inference", "user:profile"],
+		"subscriptionType": "pro"
+	}}`)
+	home := []byte(`{"oauthAccount": {
+		"accountUuid": "acc-123",
+		"emailAddress": "user@example.com",
+		"organizationUuid": "org-456",
+		"organizationName": "Example Org"
+	}, "projects": {}}`)
+
+	c, err := ParseCredentials(cred, home)
+	if err != nil {
+		t.Fatalf("ParseCredentials failed: %v", err)
+	}
+
+	if c.Kind != KindOAuth {
+		t.Errorf("expected kind %q, got %q", KindOAuth, c.Kind)
+	}
+	if c.RefreshToken != "sk-ant-ort01-def" {
+		t.Errorf("unexpected refresh token %q", c.RefreshToken)
+	}
+	if c.ExpiresAt == nil || !c.ExpiresAt.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected millisecond expiry 2030-01-01, got %v", c.ExpiresAt)
+	}
+	if len(c.Scopes) != 2 {
+		t.Errorf("expected 2 scopes, got %v", c.Scopes)
+	}
+	if c.PlanName() != "Pro" {
+		t.Errorf("expected plan 'Pro', got %q", c.PlanName())
+	}
+	if c.Email != "user@example.com" || c.AccountUUID != "acc-123" || c.OrgUUID != "org-456" || c.OrgName != "Example Org" {
+		t.Errorf("account identity not parsed: %+v", c)
+	}
+}
+
+func TestParseCredentialsLegacy(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want time.Time
+	}{
+		{"rfc3339", `{"email": "a@example.com", "expiresAt": "2030-01-01T00:00:00Z"}`, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"seconds", `{"email": "a@example.com", "expires_at": 1893456000}`, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"exp", `{"email": "a@example.com", "exp": 1893456000}`, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCredentials([]byte(tt.data), nil)
+		if err != nil {
+			t.Fatalf("%s: ParseCredentials failed: %v", tt.name, err)
+		}
+		if c.Kind != KindLegacy {
+			t.Errorf("%s: expected kind %q, got %q", tt.name, KindLegacy, c.Kind)
+		}
+		if c.Email != "a@example.com" {
+			t.Errorf("%s: expected email, got %q", tt.name, c.Email)
+		}
+		if c.ExpiresAt == nil || !c.ExpiresAt.Equal(tt.want) {
+			t.Errorf("%s: expected expiry %v, got %v", tt.name, tt.want, c.ExpiresAt)
+		}
+	}
+}
+
+func TestParseCredentialsAPIKey(t *testing.T) {
+	c, err := ParseCredentials(nil, []byte(`{"primaryApiKey": "sk-ant-api03-xyz"}`))
+	if err != nil {
+		t.Fatalf("ParseCredentials failed: %v", err)
+	}
+	if c.Kind != KindAPIKey {
+		t.Errorf("expected kind %q, got %q", KindAPIKey, c.Kind)
+	}
+	if c.PlanName() != "API" {
+		t.Errorf("expected plan 'API', got %q", c.PlanName())
+	}
+}
+
+func TestParseCredentialsEmpty(t *testing.T) {
+	if _, err := ParseCredentials([]byte(`{}`), nil); err == nil {
+		t.Error("expected error for credentials without login information")
+	}
+	if _, err := ParseCredentials([]byte(`not json`), nil); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
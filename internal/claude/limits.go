@@ -13,70 +13,115 @@ import (
 
 // UsageInfo represents parsed usage/limit information.
 type UsageInfo struct {
-	Plan        string `json:"plan,omitempty"`
-	Model       string `json:"model,omitempty"`
-	Email       string `json:"email,omitempty"`
-	OrgName     string `json:"org_name,omitempty"`
-	RateLimited bool   `json:"rate_limited"`
-	ResetTime   string `json:"reset_time,omitempty"`
+	Plan             string     `json:"plan,omitempty"`
+	SubscriptionType string     `json:"subscription_type,omitempty"`
+	Model            string     `json:"model,omitempty"`
+	Email            string     `json:"email,omitempty"`
+	OrgName          string     `json:"org_name,omitempty"`
+	OrgUUID          string     `json:"org_uuid,omitempty"`
+	AccountUUID      string     `json:"account_uuid,omitempty"`
+	Scopes           []string   `json:"scopes,omitempty"`
+	TokenExpiresAt   *time.Time `json:"token_expires_at,omitempty"`
+	RateLimited      bool       `json:"rate_limited"`
+	LimitKind        string     `json:"limit_kind,omitempty"`
+	ResetTime        string     `json:"reset_time,omitempty"`
 }
 
-// GetUsageInfo tries to extract usage information from Claude's local data files.
-// This is best-effort since Claude Code doesn't expose a formal API for this.
-func GetUsageInfo() (*UsageInfo, error) {
-	info := &UsageInfo{}
+// UsageSource says where GetUsageInfo finds a profile's account details:
+// the live Claude directory, a stored profile, or an isolated environment.
+type UsageSource struct {
+	// Profile names the profile whose recorded limit state applies; empty
+	// for none.
+	Profile string
+	// CredentialsPath, HomePath and StatsigPath locate the profile's
+	// .credentials.json, .claude.json and statsig_metadata.
+	CredentialsPath string
+	HomePath        string
+	StatsigPath     string
+	// ReadFile reads those files; nil means os.ReadFile. Stored profiles
+	// use a reader that decrypts sealed files.
+	ReadFile func(path string) ([]byte, error)
+}
 
-	// Try reading from credentials for email/plan info
+// LiveUsageSource reads the live Claude directory, which holds the
+// credentials of the given (active) profile.
+func LiveUsageSource(profile string) (UsageSource, error) {
 	credPath, err := config.ClaudeCredentialsPath()
-	if err == nil {
-		parseCredentialsInfo(credPath, info)
+	if err != nil {
+		return UsageSource{}, err
 	}
+	homePath, err := config.ClaudeAuthJSONPath()
+	if err != nil {
+		return UsageSource{}, err
+	}
+	return UsageSource{
+		Profile:         profile,
+		CredentialsPath: credPath,
+		HomePath:        homePath,
+		StatsigPath:     filepath.Join(filepath.Dir(credPath), "statsig_metadata"),
+	}, nil
+}
 
-	// Try reading from statsig metadata for plan/model info
-	claudeDir, err := config.ClaudeConfigDir()
-	if err == nil {
-		parseStatsigMetadata(filepath.Join(claudeDir, "statsig_metadata"), info)
+// DirUsageSource reads a Claude config directory that keeps its own
+// .claude.json, such as an isolated environment or session directory.
+func DirUsageSource(profile, dir string) UsageSource {
+	return UsageSource{
+		Profile:         profile,
+		CredentialsPath: filepath.Join(dir, ".credentials.json"),
+		HomePath:        filepath.Join(dir, ".claude.json"),
+		StatsigPath:     filepath.Join(dir, "statsig_metadata"),
 	}
-
-	return info, nil
 }
 
-// parseCredentialsInfo extracts email and account info from credentials.
-func parseCredentialsInfo(path string, info *UsageInfo) {
-	data, err := os.ReadFile(path)
+func (src UsageSource) read(path string) []byte {
+	if path == "" {
+		return nil
+	}
+	read := src.ReadFile
+	if read == nil {
+		read = os.ReadFile
+	}
+	data, err := read(path)
 	if err != nil {
-		return
+		return nil
 	}
+	return data
+}
 
-	// Try to parse as JSON and extract known fields
-	var creds map[string]interface{}
-	if err := json.Unmarshal(data, &creds); err != nil {
-		return
-	}
+// GetUsageInfo tries to extract usage information from a profile's local
+// data files. This is best-effort since Claude Code doesn't expose a
+// formal API for this.
+func GetUsageInfo(src UsageSource) (*UsageInfo, error) {
+	info := &UsageInfo{}
 
-	if email, ok := creds["email"].(string); ok {
-		info.Email = email
+	// Account info from the credentials and .claude.json
+	if creds, err := ParseCredentials(src.read(src.CredentialsPath), src.read(src.HomePath)); err == nil {
+		applyCredentials(creds, info)
 	}
 
-	if plan, ok := creds["plan"].(string); ok {
-		info.Plan = plan
-	}
+	// Model info from the statsig metadata
+	applyStatsigMetadata(src.read(src.StatsigPath), info)
 
-	if org, ok := creds["orgName"].(string); ok {
-		info.OrgName = org
-	}
-	if org, ok := creds["org_name"].(string); ok && info.OrgName == "" {
-		info.OrgName = org
-	}
+	// Limits are recorded per profile by cs exec --pool and cs limits.
+	applyLimitState(info, src.Profile)
+
+	return info, nil
 }
 
-// parseStatsigMetadata extracts model/plan info from statsig files.
-func parseStatsigMetadata(path string, info *UsageInfo) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return
-	}
+// applyCredentials copies account details from parsed credentials into info.
+func applyCredentials(creds *Credentials, info *UsageInfo) {
+	info.Email = creds.Email
+	info.Plan = creds.PlanName()
+	info.SubscriptionType = creds.SubscriptionType
+	info.OrgName = creds.OrgName
+	info.OrgUUID = creds.OrgUUID
+	info.AccountUUID = creds.AccountUUID
+	info.Scopes = creds.Scopes
+	info.TokenExpiresAt = creds.ExpiresAt
+}
 
+// applyStatsigMetadata copies the model from statsig metadata into info.
+func applyStatsigMetadata(data []byte, info *UsageInfo) {
 	var meta map[string]interface{}
 	if err := json.Unmarshal(data, &meta); err != nil {
 		return
@@ -106,15 +151,30 @@ func FormatUsageInfo(info *UsageInfo, profileName string) string {
 	rows := []struct {
 		label, value string
 	}{
-		{"Plan", valueOrDash(info.Plan)},
-		{"Email", valueOrDash(info.Email)},
-		{"Model", valueOrDash(info.Model)},
-		{"Organization", valueOrDash(info.OrgName)},
-		{"Rate Limited", fmt.Sprintf("%v", info.RateLimited)},
+		{"Plan", ValueOrDash(info.Plan)},
+		{"Email", ValueOrDash(info.Email)},
+		{"Model", ValueOrDash(info.Model)},
+		{"Organization", ValueOrDash(info.OrgName)},
+	}
+	if info.TokenExpiresAt != nil {
+		expires := info.TokenExpiresAt.Local().Format("Jan 2 15:04 MST")
+		if time.Now().After(*info.TokenExpiresAt) {
+			expires += " (expired)"
+		}
+		rows = append(rows, struct{ label, value string }{"Token expires", expires})
 	}
+	rows = append(rows, struct{ label, value string }{"Rate Limited", fmt.Sprintf("%v", info.RateLimited)})
 
 	if info.ResetTime != "" {
-		rows = append(rows, struct{ label, value string }{"Reset", info.ResetTime})
+		reset := info.ResetTime
+		t, err := time.Parse(time.RFC3339, reset)
+		if err == nil {
+			reset = t.Local().Format("Jan 2 15:04 MST")
+		}
+		rows = append(rows, struct{ label, value string }{"Reset", reset})
+		if err == nil && time.Until(t) > 0 {
+			rows = append(rows, struct{ label, value string }{"Resets in", FormatCountdown(time.Until(t))})
+		}
 	}
 
 	for _, r := range rows {
@@ -139,7 +199,8 @@ func FormatUsageInfo(info *UsageInfo, profileName string) string {
 	return b.String()
 }
 
-func valueOrDash(s string) string {
+// ValueOrDash returns s, or "-" standing in for an unknown value.
+func ValueOrDash(s string) string {
 	if s == "" {
 		return "-"
 	}
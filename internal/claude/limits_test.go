@@ -10,7 +10,11 @@ import (
 
 func TestGetUsageInfo(t *testing.T) {
 	// This is a best-effort test; in CI there may be no Claude config
-	info, err := GetUsageInfo()
+	src, err := LiveUsageSource("")
+	if err != nil {
+		t.Fatalf("LiveUsageSource failed: %v", err)
+	}
+	info, err := GetUsageInfo(src)
 	if err != nil {
 		t.Fatalf("GetUsageInfo should not error: %v", err)
 	}
@@ -19,7 +23,37 @@ func TestGetUsageInfo(t *testing.T) {
 	}
 }
 
-func TestParseCredentialsInfo(t *testing.T) {
+func TestGetUsageInfoFromDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	dir := t.TempDir()
+	creds := `{"claudeAiOauth":{"accessToken":"tok","expiresAt":1893456000000,"subscriptionType":"max"}}`
+	if err := os.WriteFile(filepath.Join(dir, ".credentials.json"), []byte(creds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	account := `{"oauthAccount":{"emailAddress":"dev@example.com","organizationName":"Acme"}}`
+	if err := os.WriteFile(filepath.Join(dir, ".claude.json"), []byte(account), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := GetUsageInfo(DirUsageSource("work", dir))
+	if err != nil {
+		t.Fatalf("GetUsageInfo failed: %v", err)
+	}
+	if info.Email != "dev@example.com" || info.OrgName != "Acme" || info.SubscriptionType != "max" {
+		t.Errorf("unexpected account details: %+v", info)
+	}
+	if info.TokenExpiresAt == nil || info.TokenExpiresAt.Year() != 2030 {
+		t.Errorf("unexpected token expiry: %v", info.TokenExpiresAt)
+	}
+	if info.RateLimited {
+		t.Error("a profile without recorded limits should not be limited")
+	}
+}
+
+func TestGetUsageInfoCredentials(t *testing.T) {
 	tmpDir := t.TempDir()
 
 	creds := map[string]interface{}{
@@ -36,9 +70,10 @@ func TestParseCredentialsInfo(t *testing.T) {
 		t.Fatalf("WriteFile failed: %v", err)
 	}
 
-	info := &UsageInfo{}
-	parseCredentialsInfo(credPath, info)
-
+	info, err := GetUsageInfo(UsageSource{CredentialsPath: credPath})
+	if err != nil {
+		t.Fatalf("GetUsageInfo failed: %v", err)
+	}
 	if info.Email != "test@example.com" {
 		t.Errorf("expected email 'test@example.com', got %q", info.Email)
 	}
@@ -50,19 +85,19 @@ func TestParseCredentialsInfo(t *testing.T) {
 	}
 }
 
-func TestParseCredentialsInfoMissing(t *testing.T) {
-	info := &UsageInfo{}
-	parseCredentialsInfo("/nonexistent/path", info)
+func TestGetUsageInfoMissingCredentials(t *testing.T) {
+	info, err := GetUsageInfo(UsageSource{CredentialsPath: "/nonexistent/path"})
+	if err != nil {
+		t.Fatalf("GetUsageInfo failed: %v", err)
+	}
 
-	// Should not panic, just leave fields empty
+	// Should not fail, just leave fields empty
 	if info.Email != "" {
 		t.Errorf("expected empty email, got %q", info.Email)
 	}
 }
 
-func TestParseStatsigMetadata(t *testing.T) {
-	tmpDir := t.TempDir()
-
+func TestApplyStatsigMetadata(t *testing.T) {
 	meta := map[string]interface{}{
 		"model": "opus-4.5",
 	}
@@ -70,13 +105,9 @@ func TestParseStatsigMetadata(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Marshal failed: %v", err)
 	}
-	metaPath := filepath.Join(tmpDir, "statsig_metadata")
-	if err := os.WriteFile(metaPath, data, 0600); err != nil {
-		t.Fatalf("WriteFile failed: %v", err)
-	}
 
 	info := &UsageInfo{}
-	parseStatsigMetadata(metaPath, info)
+	applyStatsigMetadata(data, info)
 
 	if info.Model != "opus-4.5" {
 		t.Errorf("expected model 'opus-4.5', got %q", info.Model)
@@ -125,10 +156,10 @@ func TestFormatUsageInfoEmpty(t *testing.T) {
 }
 
 func TestValueOrDash(t *testing.T) {
-	if valueOrDash("") != "-" {
+	if ValueOrDash("") != "-" {
 		t.Error("empty string should return dash")
 	}
-	if valueOrDash("hello") != "hello" {
+	if ValueOrDash("hello") != "hello" {
 		t.Error("non-empty string should return itself")
 	}
 }
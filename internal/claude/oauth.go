@@ -0,0 +1,105 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpClient is used for OAuth requests; timeout prevents hangs on slow networks.
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// TokenResponse is the body returned by the OAuth token endpoint.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope"`
+	TokenType    string `json:"token_type"`
+}
+
+// RefreshOAuthToken performs the OAuth refresh-token grant against tokenURL.
+func RefreshOAuthToken(tokenURL, clientID, refreshToken string) (*TokenResponse, error) {
+	if refreshToken == "" {
+		return nil, fmt.Errorf("no refresh token available")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+		"client_id":     clientID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode token request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("token endpoint returned status %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var tok TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("cannot parse token response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return nil, fmt.Errorf("token response did not include an access token")
+	}
+	return &tok, nil
+}
+
+// ApplyTokenResponse writes a refreshed token into raw credentials JSON in
+// the nested claudeAiOauth shape, preserving every other field. now is used
+// to turn expires_in into an absolute millisecond timestamp.
+func ApplyTokenResponse(credData []byte, tok *TokenResponse, now time.Time) ([]byte, error) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(credData, &root); err != nil {
+		return nil, fmt.Errorf("invalid credentials JSON: %w", err)
+	}
+
+	raw, ok := root["claudeAiOauth"]
+	if !ok {
+		return nil, fmt.Errorf("credentials do not contain an OAuth login")
+	}
+	var oauth map[string]interface{}
+	if err := json.Unmarshal(raw, &oauth); err != nil {
+		return nil, fmt.Errorf("invalid claudeAiOauth section: %w", err)
+	}
+
+	oauth["accessToken"] = tok.AccessToken
+	if tok.RefreshToken != "" {
+		oauth["refreshToken"] = tok.RefreshToken
+	}
+	if tok.ExpiresIn > 0 {
+		oauth["expiresAt"] = now.Add(time.Duration(tok.ExpiresIn) * time.Second).UnixMilli()
+	}
+	if tok.Scope != "" {
+		oauth["scopes"] = strings.Fields(tok.Scope)
+	}
+
+	updated, err := json.Marshal(oauth)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode credentials: %w", err)
+	}
+	root["claudeAiOauth"] = updated
+
+	return json.Marshal(root)
+}
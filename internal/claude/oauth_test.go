@@ -0,0 +1,56 @@
+package claude
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRefreshOAuthTokenError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if _, err := RefreshOAuthToken(srv.URL, "client", "revoked"); err == nil {
+		t.Error("expected error for rejected refresh token")
+	}
+	if _, err := RefreshOAuthToken(srv.URL, "client", ""); err == nil {
+		t.Error("expected error for empty refresh token")
+	}
+}
+
+func TestApplyTokenResponse(t *testing.T) {
+	cred := []byte(`{"claudeAiOauth": {"accessToken": "a", "refreshToken": "r", "rateLimitTier": "t1"}, "other": 1}`)
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	out, err := ApplyTokenResponse(cred, &TokenResponse{AccessToken: "a2", ExpiresIn: 60, Scope: "user:inference user:profile"}, now)
+	if err != nil {
+		t.Fatalf("ApplyTokenResponse failed: %v", err)
+	}
+
+	c, err := ParseCredentials(out, nil)
+	if err != nil {
+		t.Fatalf("ParseCredentials failed: %v", err)
+	}
+	if c.AccessToken != "a2" || c.RefreshToken != "r" || c.RateLimitTier != "t1" {
+		t.Errorf("unexpected credentials after refresh: %+v", c)
+	}
+	if c.ExpiresAt == nil || !c.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected expiry %v, got %v", now.Add(time.Minute), c.ExpiresAt)
+	}
+	if len(c.Scopes) != 2 {
+		t.Errorf("expected scopes to be updated, got %v", c.Scopes)
+	}
+
+	var root map[string]interface{}
+	if err := json.Unmarshal(out, &root); err != nil || root["other"] == nil {
+		t.Error("top-level fields should be preserved")
+	}
+
+	if _, err := ApplyTokenResponse([]byte(`{"email": "x"}`), &TokenResponse{AccessToken: "a"}, now); err == nil {
+		t.Error("expected error for non-OAuth credentials")
+	}
+}
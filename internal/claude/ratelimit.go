@@ -0,0 +1,249 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// Kinds of limit Claude Code reports.
+const (
+	// LimitUsage means the plan's usage window (5-hour, weekly) is used up.
+	LimitUsage = "usage"
+	// LimitRate means the API answered 429 Too Many Requests.
+	LimitRate = "rate"
+	// LimitOverloaded means the API answered 529 Overloaded. It affects
+	// every account, so it never puts a profile under a limit.
+	LimitOverloaded = "overloaded"
+)
+
+// How long a limit is assumed to last when the message gives no reset time.
+const (
+	usageLimitCooldown = 5 * time.Hour
+	rateLimitCooldown  = time.Minute
+)
+
+// LimitEvent is a usage or rate limit hit by a profile.
+type LimitEvent struct {
+	Kind    string    `json:"kind"`
+	HitAt   time.Time `json:"hit_at"`
+	ResetAt time.Time `json:"reset_at"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Active reports whether the limit is still in force at now.
+func (e LimitEvent) Active(now time.Time) bool {
+	return e.ResetAt.After(now)
+}
+
+var (
+	// "Claude AI usage limit reached|1760738400", printed by older versions.
+	epochLimitRe = regexp.MustCompile(`(?i)usage limit reached\|(\d{9,})`)
+	// "5-hour limit reached ∙ resets 3pm", "You've reached your usage limit".
+	// Only Claude Code's own wording counts: exec --pool scans the output of
+	// any command, where "retry limit reached" must not switch accounts.
+	usageLimitRe = regexp.MustCompile(`(?i)\b(?:usage|session|weekly|opus|sonnet|\d+-hour) limit reached|\blimit reached\b.*\bresets?\b|you(?:'|’)?ve reached your (?:usage |session |weekly )?limit|out of extra usage`)
+	// "API Error: 429 {...}", "API error (attempt 1): 429 ...".
+	rateLimitRe  = regexp.MustCompile(`(?i)\bapi error[^:\n]*:\s*429\b|\brate_limit_error\b`)
+	overloadedRe = regexp.MustCompile(`(?i)\bapi error[^:\n]*:\s*529\b|\berror:\s*529 overloaded\b|\boverloaded_error\b`)
+	resetsRe     = regexp.MustCompile(`(?i)\bresets?\s+(?:at\s+)?(.+)$`)
+	resetClockRe = regexp.MustCompile(`(?i)^(?:([a-z]{3})[a-z]*\.?\s+(\d{1,2}),?\s+(?:at\s+)?)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)`)
+	resetZoneRe  = regexp.MustCompile(`\(([A-Za-z_]+(?:/[A-Za-z_+\-0-9]+)*)\)`)
+)
+
+// DetectLimit looks for a usage or rate limit message in the output of a
+// Claude Code run and returns the last one found. A usage limit without a
+// readable reset time is assumed to last five hours, a rate limit one
+// minute; an overloaded error ends when it happens.
+func DetectLimit(output []byte, now time.Time) (LimitEvent, bool) {
+	lines := bytes.Split(output, []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(string(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if m := epochLimitRe.FindStringSubmatch(line); m != nil {
+			if secs, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+				return LimitEvent{Kind: LimitUsage, HitAt: now, ResetAt: time.Unix(secs, 0), Message: line}, true
+			}
+		}
+		if usageLimitRe.MatchString(line) {
+			ev := LimitEvent{Kind: LimitUsage, HitAt: now, Message: line}
+			if m := resetsRe.FindStringSubmatch(line); m != nil {
+				ev.ResetAt = ParseResetTime(m[1], now)
+			}
+			if ev.ResetAt.IsZero() {
+				ev.ResetAt = now.Add(usageLimitCooldown)
+			}
+			return ev, true
+		}
+		if rateLimitRe.MatchString(line) {
+			return LimitEvent{Kind: LimitRate, HitAt: now, ResetAt: now.Add(rateLimitCooldown), Message: line}, true
+		}
+		if overloadedRe.MatchString(line) {
+			return LimitEvent{Kind: LimitOverloaded, HitAt: now, ResetAt: now, Message: line}, true
+		}
+	}
+	return LimitEvent{}, false
+}
+
+// ParseResetTime reads reset times the way Claude Code prints them: "3pm",
+// "3:30pm (Europe/Berlin)", "Oct 20, 3pm". A time without a date is the
+// next such time after now. It returns the zero time if s is not readable.
+func ParseResetTime(s string, now time.Time) time.Time {
+	loc := now.Location()
+	if m := resetZoneRe.FindStringSubmatch(s); m != nil {
+		if l, err := time.LoadLocation(m[1]); err == nil {
+			loc = l
+		}
+	}
+
+	m := resetClockRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}
+	}
+	hour, _ := strconv.Atoi(m[3])
+	minute := 0
+	if m[4] != "" {
+		minute, _ = strconv.Atoi(m[4])
+	}
+	if hour < 1 || hour > 12 || minute > 59 {
+		return time.Time{}
+	}
+	hour %= 12
+	if strings.EqualFold(m[5], "pm") {
+		hour += 12
+	}
+
+	local := now.In(loc)
+	if m[1] == "" {
+		t := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t
+	}
+
+	month, err := time.Parse("Jan", strings.ToUpper(m[1][:1])+strings.ToLower(m[1][1:]))
+	if err != nil {
+		return time.Time{}
+	}
+	day, _ := strconv.Atoi(m[2])
+	t := time.Date(local.Year(), month.Month(), day, hour, minute, 0, 0, loc)
+	if t.Before(now.AddDate(0, 0, -1)) {
+		t = t.AddDate(1, 0, 0) // "Jan 2" seen in late December
+	}
+	return t
+}
+
+// LimitStore records the last limit each profile hit, keyed by profile
+// name. It is kept in config.LimitsPath.
+type LimitStore map[string]LimitEvent
+
+// LoadLimits reads the recorded limits. A missing file is an empty store.
+func LoadLimits() (LimitStore, error) {
+	path, err := config.LimitsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return LimitStore{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read limits: %w", err)
+	}
+
+	store := LimitStore{}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Save writes the store back to disk.
+func (s LimitStore) Save() error {
+	if err := config.EnsureDirs(); err != nil {
+		return err
+	}
+	path, err := config.LimitsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot serialize limits: %w", err)
+	}
+	return fsutil.WriteFile(path, data, 0600)
+}
+
+// Record stores ev as the latest limit event of the profile, unless the
+// store already has a newer one, or ev would cut short a limit still in
+// force when ev happened (an overloaded error during a usage limit). It
+// reports whether the store changed.
+func (s LimitStore) Record(profile string, ev LimitEvent) bool {
+	cur, ok := s[profile]
+	if ok {
+		if !ev.HitAt.After(cur.HitAt) {
+			return false
+		}
+		if cur.Active(ev.HitAt) && ev.ResetAt.Before(cur.ResetAt) {
+			return false
+		}
+	}
+	s[profile] = ev
+	return true
+}
+
+// Active returns the limit the profile is under at now, if any.
+func (s LimitStore) Active(profile string, now time.Time) (LimitEvent, bool) {
+	ev, ok := s[profile]
+	if !ok || !ev.Active(now) {
+		return LimitEvent{}, false
+	}
+	return ev, true
+}
+
+// FormatCountdown renders the time left until a reset: "2h 05m", "12m",
+// "40s", or "3d 4h" for long waits.
+func FormatCountdown(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+// applyLimitState marks info as rate-limited if the recorded state says
+// the profile is under a limit.
+func applyLimitState(info *UsageInfo, profile string) {
+	if profile == "" {
+		return
+	}
+	store, err := LoadLimits()
+	if err != nil {
+		return
+	}
+	if ev, ok := store.Active(profile, time.Now()); ok {
+		info.RateLimited = true
+		info.LimitKind = ev.Kind
+		info.ResetTime = ev.ResetAt.UTC().Format(time.RFC3339)
+	}
+}
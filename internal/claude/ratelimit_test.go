@@ -0,0 +1,139 @@
+package claude
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectLimit(t *testing.T) {
+	now := time.Date(2026, 3, 10, 13, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		output    string
+		wantKind  string
+		wantReset time.Time
+	}{
+		{"epoch", "Claude AI usage limit reached|1773158400\n", LimitUsage, time.Unix(1773158400, 0)},
+		{"session clock", "some output\n5-hour limit reached ∙ resets 3pm\n", LimitUsage, time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)},
+		{"past clock is tomorrow", "Session limit reached · resets 11:30am", LimitUsage, time.Date(2026, 3, 11, 11, 30, 0, 0, time.UTC)},
+		{"weekly with date", "Weekly limit reached ∙ resets Mar 12, 9am", LimitUsage, time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)},
+		{"no reset time", "You've reached your usage limit.", LimitUsage, now.Add(usageLimitCooldown)},
+		{"api 429", `API Error: 429 {"type":"error","error":{"type":"rate_limit_error"}}`, LimitRate, now.Add(rateLimitCooldown)},
+		{"debug log 429", "API error (attempt 3): 429 Too Many Requests", LimitRate, now.Add(rateLimitCooldown)},
+		{"api 529", `API Error: 529 {"type":"error","error":{"type":"overloaded_error"}}`, LimitOverloaded, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, ok := DetectLimit([]byte(tt.output), now)
+			if !ok {
+				t.Fatal("limit not detected")
+			}
+			if ev.Kind != tt.wantKind {
+				t.Errorf("Kind = %q, want %q", ev.Kind, tt.wantKind)
+			}
+			if !ev.ResetAt.Equal(tt.wantReset) {
+				t.Errorf("ResetAt = %v, want %v", ev.ResetAt, tt.wantReset)
+			}
+		})
+	}
+
+	for _, output := range []string{
+		"Error: file not found",
+		"main.go:429: undefined: foo",
+		"failed at line 429",
+		"retry limit reached, giving up",
+		"connection limit reached",
+		"HTTP 429 Too Many Requests from registry.example",
+		"the server is overloaded with work",
+	} {
+		if ev, ok := DetectLimit([]byte(output+"\n"), now); ok {
+			t.Errorf("ordinary output %q detected as a %s limit", output, ev.Kind)
+		}
+	}
+}
+
+func TestParseResetTimeZone(t *testing.T) {
+	now := time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)
+	got := ParseResetTime("5pm (America/New_York)", now)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	if want := time.Date(2026, 3, 10, 17, 0, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("ParseResetTime = %v, want %v", got, want)
+	}
+	if got := ParseResetTime("soon", now); !got.IsZero() {
+		t.Errorf("unreadable reset time parsed as %v", got)
+	}
+}
+
+func TestLimitStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	now := time.Now()
+	store, err := LoadLimits()
+	if err != nil {
+		t.Fatalf("LoadLimits failed: %v", err)
+	}
+	store["work"] = LimitEvent{Kind: LimitUsage, HitAt: now, ResetAt: now.Add(time.Hour)}
+	store["old"] = LimitEvent{Kind: LimitRate, HitAt: now.Add(-time.Hour), ResetAt: now.Add(-time.Minute)}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadLimits()
+	if err != nil {
+		t.Fatalf("LoadLimits failed: %v", err)
+	}
+	if _, ok := loaded.Active("work", now); !ok {
+		t.Error("work should be limited")
+	}
+	if _, ok := loaded.Active("old", now); ok {
+		t.Error("an expired limit should not be active")
+	}
+
+	info := &UsageInfo{}
+	applyLimitState(info, "work")
+	if !info.RateLimited || info.ResetTime == "" {
+		t.Errorf("UsageInfo not marked as limited: %+v", info)
+	}
+}
+
+func TestLimitStoreRecord(t *testing.T) {
+	hit := time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)
+	usage := LimitEvent{Kind: LimitUsage, HitAt: hit, ResetAt: hit.Add(2 * time.Hour)}
+	store := LimitStore{}
+
+	if !store.Record("work", usage) {
+		t.Fatal("first event should be recorded")
+	}
+	if store.Record("work", usage) {
+		t.Error("the same event again should not change the store")
+	}
+	overloaded := LimitEvent{Kind: LimitOverloaded, HitAt: hit.Add(time.Minute), ResetAt: hit.Add(time.Minute)}
+	if store.Record("work", overloaded) {
+		t.Error("an overloaded error should not cut short an active usage limit")
+	}
+	later := LimitEvent{Kind: LimitRate, HitAt: hit.Add(3 * time.Hour), ResetAt: hit.Add(3*time.Hour + time.Minute)}
+	if !store.Record("work", later) || store["work"].Kind != LimitRate {
+		t.Errorf("a later event should replace an expired limit: %+v", store["work"])
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Second:                 "0s",
+		40 * time.Second:             "40s",
+		12*time.Minute + time.Second: "12m",
+		2*time.Hour + 5*time.Minute:  "2h 05m",
+		76 * time.Hour:               "3d 4h",
+	}
+	for d, want := range tests {
+		if got := FormatCountdown(d); got != want {
+			t.Errorf("FormatCountdown(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -1,14 +1,21 @@
 package claude
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"syscall"
 )
 
-// RunOptions configures how claude is executed.
+// RunOptions configures how claude (or another program) is executed.
 type RunOptions struct {
-	// Args are the arguments to pass to the claude binary.
+	// Program is the executable to run. If empty, the claude binary is used.
+	Program string
+
+	// Args are the arguments to pass to the program.
 	Args []string
 
 	// Env overrides the process environment. If nil, inherits current env.
@@ -16,20 +23,47 @@ type RunOptions struct {
 
 	// Dir sets the working directory. If empty, uses current dir.
 	Dir string
+
+	// Stdin, Stdout and Stderr replace the program's standard streams. If
+	// nil, the program shares cs's own.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// ForwardInterrupt also forwards SIGINT to the program. Leave it off when
+	// the program shares cs's terminal: the terminal already delivers Ctrl-C
+	// to it, and a second copy makes claude exit.
+	ForwardInterrupt bool
 }
 
-// Run executes the claude binary with the given options.
-// It connects stdin/stdout/stderr so the user can interact with claude.
+// ForwardedSignals are the signals Run passes on to the program. While it
+// runs, they don't terminate cs itself.
+var ForwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// Run executes the program (claude by default) with the given options and
+// waits for it. It connects stdin/stdout/stderr so the user can interact
+// with it, and forwards ForwardedSignals to it.
 func Run(opts RunOptions) error {
-	result := Detect()
-	if !result.Found {
-		return fmt.Errorf("claude binary not found on PATH — install Claude Code first")
+	path := opts.Program
+	if path == "" {
+		result := Detect()
+		if !result.Found {
+			return fmt.Errorf("claude binary not found on PATH — install Claude Code first")
+		}
+		path = result.Path
 	}
 
-	cmd := exec.Command(result.Path, opts.Args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := exec.Command(path, opts.Args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if opts.Stdin != nil {
+		cmd.Stdin = opts.Stdin
+	}
+	if opts.Stdout != nil {
+		cmd.Stdout = opts.Stdout
+	}
+	if opts.Stderr != nil {
+		cmd.Stderr = opts.Stderr
+	}
 
 	if opts.Env != nil {
 		cmd.Env = opts.Env
@@ -38,7 +72,53 @@ func Run(opts RunOptions) error {
 		cmd.Dir = opts.Dir
 	}
 
-	return cmd.Run()
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, ForwardedSignals...)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if sig == os.Interrupt && !opts.ForwardInterrupt {
+					continue
+				}
+				_ = cmd.Process.Signal(sig) // unsupported on Windows; best-effort
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return cmd.Wait()
+}
+
+// ExitCode returns the exit status a shell would report for an error from
+// Run: the program's exit code, or 128+N if it was killed by signal N. ok is
+// false if err is not an exit status.
+func ExitCode(err error) (code int, ok bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+	if ws, isWait := exitErr.Sys().(syscall.WaitStatus); isWait && ws.Signaled() {
+		return 128 + int(ws.Signal()), true
+	}
+	return exitErr.ExitCode(), true
+}
+
+// SignalExitCode returns the conventional exit status for dying from sig.
+func SignalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
 }
 
 // RunWithOutput executes the claude binary and captures stdout.
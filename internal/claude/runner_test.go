@@ -0,0 +1,31 @@
+package claude
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestRunProgramExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	err := Run(RunOptions{Program: "sh", Args: []string{"-c", "exit 7"}})
+	if code, ok := ExitCode(err); !ok || code != 7 {
+		t.Errorf("ExitCode = %d, %v; want 7, true (err: %v)", code, ok, err)
+	}
+
+	err = Run(RunOptions{Program: "sh", Args: []string{"-c", "kill -TERM $$"}})
+	if code, ok := ExitCode(err); !ok || code != 128+int(syscall.SIGTERM) {
+		t.Errorf("ExitCode = %d, %v; want %d for a signalled child", code, ok, 128+int(syscall.SIGTERM))
+	}
+
+	if err := Run(RunOptions{Program: "sh", Args: []string{"-c", "test \"$PWD\" = /"}, Dir: "/"}); err != nil {
+		t.Errorf("Dir was not honoured: %v", err)
+	}
+
+	if _, ok := ExitCode(nil); ok {
+		t.Error("ExitCode(nil) should not report an exit status")
+	}
+}
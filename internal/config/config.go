@@ -6,44 +6,321 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"time"
+
+	"github.com/caeser1996/claude-switch/internal/fsutil"
 )
 
 const (
-	AppName    = "claude-switch"
-	AppDir     = ".claude-switch"
-	ConfigFile = "config.json"
+	AppName        = "claude-switch"
+	AppDir         = ".claude-switch"
+	ConfigFile     = "config.json"
+	LockFile       = "cs.lock"
+	LimitsFile     = "limits.json"
+	HistoryFile    = "history.jsonl"
+	UsageIndexFile = "usage-index.json"
 )
 
+// ProfileKindEnv marks a profile made of environment variables (API key,
+// Bedrock, Vertex, gateway) rather than a snapshot of Claude's credential files.
+const ProfileKindEnv = "env"
+
 // ProfileEntry holds metadata about a saved profile.
 type ProfileEntry struct {
-	Name        string    `json:"name"`
-	Email       string    `json:"email,omitempty"`
-	Description string    `json:"description,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	IsActive    bool      `json:"is_active"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email,omitempty"`
+	Subscription string    `json:"subscription,omitempty"`
+	OrgName      string    `json:"org_name,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	IsActive     bool      `json:"is_active"`
+
+	// Kind is empty for credential-file profiles and ProfileKindEnv for
+	// environment profiles. Provider names the backend of an env profile.
+	Kind     string `json:"kind,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	// EnvVars lists the variable names of an env profile. Their values are
+	// stored in the profile directory, never in config.json.
+	EnvVars []string `json:"env_vars,omitempty"`
+
+	// Tags group profiles for commands that act on several at once.
+	Tags []string `json:"tags,omitempty"`
+
+	// Resources overrides the global resource policy for this profile.
+	Resources *ResourcePolicy `json:"resources,omitempty"`
+}
+
+// IsEnv reports whether the profile is an environment-variable profile.
+func (p ProfileEntry) IsEnv() bool {
+	return p.Kind == ProfileKindEnv
+}
+
+// HasTag reports whether the profile carries tag.
+func (p ProfileEntry) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultIdentityKeys are the top-level ~/.claude.json keys that identify
+// the logged-in account. Only these keys are saved into and restored from
+// profiles; project history, MCP servers and onboarding state are left alone.
+var DefaultIdentityKeys = []string{
+	"oauthAccount",
+	"userID",
+	"primaryApiKey",
+	"customApiKeyResponses",
 }
 
+// Defaults for the OAuth refresh-token grant used by 'cs refresh'.
+const (
+	DefaultOAuthTokenURL = "https://console.anthropic.com/v1/oauth/token"
+	DefaultOAuthClientID = "9d1c250a-e61b-44d9-88ed-5944d1962f5e"
+)
+
+// DefaultProxyUpstream is the API that 'cs proxy' forwards requests to.
+const DefaultProxyUpstream = "https://api.anthropic.com"
+
 // Settings holds application-level settings.
 type Settings struct {
-	AutoBackup  bool `json:"auto_backup"`
-	MaxBackups  int  `json:"max_backups"`
-	ColorOutput bool `json:"color_output"`
+	AutoBackup   bool     `json:"auto_backup"`
+	MaxBackups   int      `json:"max_backups"`
+	ColorOutput  bool     `json:"color_output"`
+	IdentityKeys []string `json:"identity_keys,omitempty"`
+
+	// OAuthTokenURL and OAuthClientID override the endpoint and client used
+	// to refresh OAuth tokens (e.g. to point at a local stand-in server).
+	OAuthTokenURL string `json:"oauth_token_url,omitempty"`
+	OAuthClientID string `json:"oauth_client_id,omitempty"`
+
+	// CredentialStore selects where Claude Code's live credentials are kept:
+	// "auto" (default), "file", "keychain", "pass" or "helper". The
+	// CS_CREDENTIAL_STORE environment variable takes precedence.
+	CredentialStore  string `json:"credential_store,omitempty"`
+	CredentialHelper string `json:"credential_helper,omitempty"`
+	PassEntry        string `json:"pass_entry,omitempty"`
+
+	Vault VaultSettings `json:"vault"`
+
+	// Resources overrides the default resource policy for all profiles.
+	Resources *ResourcePolicy `json:"resources,omitempty"`
+
+	// ProxyUpstream overrides the API 'cs proxy' forwards to (e.g. a local
+	// stand-in server).
+	ProxyUpstream string `json:"proxy_upstream,omitempty"`
+
+	// HookMode selects what the shell hook ('cs hook') does when it finds a
+	// .claude-profile: HookModeShell (default) or HookModeGlobal.
+	HookMode string `json:"hook_mode,omitempty"`
+}
+
+// Shell hook modes.
+const (
+	// HookModeShell points only the current shell at the profile, through
+	// CLAUDE_CONFIG_DIR and CS_PROFILE.
+	HookModeShell = "shell"
+	// HookModeGlobal switches the global active profile, like 'cs use'.
+	HookModeGlobal = "global"
+)
+
+// VaultSettings controls encryption of stored profiles and backups at rest.
+type VaultSettings struct {
+	Enabled bool `json:"enabled"`
+	// KeyFile, if set, is read instead of prompting for a passphrase.
+	KeyFile string `json:"key_file,omitempty"`
+	// Salt is the scrypt salt for deriving the vault key.
+	Salt []byte `json:"salt,omitempty"`
+	// Verifier is a known value encrypted with the vault key, used to
+	// reject a wrong passphrase before any file is touched.
+	Verifier []byte `json:"verifier,omitempty"`
+}
+
+// Resource modes: how a path of ~/.claude is made available in the config
+// directory of 'cs exec' and 'cs shell'.
+const (
+	// ResourceShared paths are symlinked to ~/.claude, so changes are shared.
+	ResourceShared = "shared"
+	// ResourceCopied paths are copied from ~/.claude; changes are discarded.
+	ResourceCopied = "copied"
+	// ResourcePersistent paths are kept per profile across runs.
+	ResourcePersistent = "persistent"
+)
+
+// ResourcePolicy lists paths relative to ~/.claude by resource mode. A nil
+// list inherits the next level (defaults, then global, then profile); an
+// empty list clears it.
+type ResourcePolicy struct {
+	Shared     []string `json:"shared,omitempty"`
+	Copied     []string `json:"copied,omitempty"`
+	Persistent []string `json:"persistent,omitempty"`
+}
+
+// DefaultResourcePolicy shares Claude Code's configuration and extensions
+// with ~/.claude and keeps each profile's transcripts.
+func DefaultResourcePolicy() ResourcePolicy {
+	return ResourcePolicy{
+		Shared: []string{
+			"CLAUDE.md",
+			"agents",
+			"commands",
+			"hooks",
+			"output-styles",
+			"plugins",
+			"settings.json",
+			"settings.local.json",
+			"skills",
+			"todos",
+		},
+		Copied:     []string{},
+		Persistent: []string{"projects"},
+	}
+}
+
+// ResourceModes returns the effective resource mode of each path for the
+// named profile. A list set at a more specific level replaces the same list
+// from the level before, and a path moved to another list there takes that
+// list's mode.
+func (c *Config) ResourceModes(name string) map[string]string {
+	defaults := DefaultResourcePolicy()
+	layers := []*ResourcePolicy{&defaults, c.Settings.Resources}
+	if p, ok := c.Profiles[name]; ok {
+		layers = append(layers, p.Resources)
+	}
+
+	modes := make(map[string]string)
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		for _, list := range []struct {
+			mode  string
+			paths []string
+		}{
+			{ResourceShared, layer.Shared},
+			{ResourceCopied, layer.Copied},
+			{ResourcePersistent, layer.Persistent},
+		} {
+			if list.paths == nil {
+				continue
+			}
+			for path, mode := range modes {
+				if mode == list.mode {
+					delete(modes, path)
+				}
+			}
+			for _, path := range list.paths {
+				modes[path] = list.mode
+			}
+		}
+	}
+	return modes
+}
+
+// EffectiveResources returns the resource policy in effect for the named
+// profile, with each list sorted.
+func (c *Config) EffectiveResources(name string) ResourcePolicy {
+	var policy ResourcePolicy
+	for path, mode := range c.ResourceModes(name) {
+		switch mode {
+		case ResourceShared:
+			policy.Shared = append(policy.Shared, path)
+		case ResourceCopied:
+			policy.Copied = append(policy.Copied, path)
+		case ResourcePersistent:
+			policy.Persistent = append(policy.Persistent, path)
+		}
+	}
+	sort.Strings(policy.Shared)
+	sort.Strings(policy.Copied)
+	sort.Strings(policy.Persistent)
+	return policy
+}
+
+// AccountKeys returns the ~/.claude.json keys to capture and restore,
+// falling back to DefaultIdentityKeys when none are configured.
+func (s Settings) AccountKeys() []string {
+	if len(s.IdentityKeys) == 0 {
+		return DefaultIdentityKeys
+	}
+	return s.IdentityKeys
+}
+
+// TokenURL returns the OAuth token endpoint, falling back to the default.
+func (s Settings) TokenURL() string {
+	if s.OAuthTokenURL == "" {
+		return DefaultOAuthTokenURL
+	}
+	return s.OAuthTokenURL
+}
+
+// ClientID returns the OAuth client ID, falling back to the default.
+func (s Settings) ClientID() string {
+	if s.OAuthClientID == "" {
+		return DefaultOAuthClientID
+	}
+	return s.OAuthClientID
+}
+
+// Upstream returns the API 'cs proxy' forwards to, falling back to the
+// default.
+func (s Settings) Upstream() string {
+	if s.ProxyUpstream == "" {
+		return DefaultProxyUpstream
+	}
+	return s.ProxyUpstream
 }
 
 // Config is the top-level configuration.
 type Config struct {
 	ActiveProfile string                  `json:"active_profile"`
 	Profiles      map[string]ProfileEntry `json:"profiles"`
+	Pools         map[string][]PoolMember `json:"pools,omitempty"`
 	Settings      Settings                `json:"settings"`
 }
 
+// PoolMember is a profile in a pool. Members with a lower Priority are
+// tried first; members with the same priority keep their order.
+type PoolMember struct {
+	Profile  string `json:"profile"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// PoolProfiles returns the profiles of the named pool in the order they
+// are tried.
+func (c *Config) PoolProfiles(name string) ([]string, error) {
+	members, ok := c.Pools[name]
+	if !ok {
+		return nil, fmt.Errorf("pool %q not found", name)
+	}
+	sorted := append([]PoolMember(nil), members...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	names := make([]string, 0, len(sorted))
+	for _, m := range sorted {
+		if _, ok := c.Profiles[m.Profile]; !ok {
+			return nil, fmt.Errorf("pool %q names unknown profile %q", name, m.Profile)
+		}
+		names = append(names, m.Profile)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("pool %q has no profiles", name)
+	}
+	return names, nil
+}
+
 // DefaultSettings returns sensible defaults.
 func DefaultSettings() Settings {
 	return Settings{
-		AutoBackup:  true,
-		MaxBackups:  10,
-		ColorOutput: true,
+		AutoBackup:   true,
+		MaxBackups:   10,
+		ColorOutput:  true,
+		IdentityKeys: append([]string{}, DefaultIdentityKeys...),
 	}
 }
 
@@ -82,6 +359,16 @@ func BackupsDir() (string, error) {
 	return filepath.Join(base, "backups"), nil
 }
 
+// SessionsDir returns the path to ~/.claude-switch/sessions/, which holds the
+// persistent per-profile config directories used by shell sessions.
+func SessionsDir() (string, error) {
+	base, err := AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "sessions"), nil
+}
+
 // ConfigPath returns the full path to the config file.
 func ConfigPath() (string, error) {
 	base, err := AppDataDir()
@@ -91,6 +378,46 @@ func ConfigPath() (string, error) {
 	return filepath.Join(base, ConfigFile), nil
 }
 
+// LockPath returns the path of the lock file that serialises changes
+// between concurrent cs processes.
+func LockPath() (string, error) {
+	base, err := AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, LockFile), nil
+}
+
+// LimitsPath returns the path of the file recording which profiles hit a
+// usage limit and when it resets.
+func LimitsPath() (string, error) {
+	base, err := AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, LimitsFile), nil
+}
+
+// HistoryPath returns the path of the switch history, a log of every change
+// of the active profile with one JSON object per line.
+func HistoryPath() (string, error) {
+	base, err := AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, HistoryFile), nil
+}
+
+// UsageIndexPath returns the path of the index of token usage read from
+// Claude Code transcripts.
+func UsageIndexPath() (string, error) {
+	base, err := AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, UsageIndexFile), nil
+}
+
 // ClaudeConfigDir returns the path to Claude's config directory.
 // On all platforms this is ~/.claude/
 func ClaudeConfigDir() (string, error) {
@@ -157,7 +484,7 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
-// Save writes the config to disk with secure permissions.
+// Save writes the config to disk atomically with secure permissions.
 func (c *Config) Save() error {
 	if err := EnsureDirs(); err != nil {
 		return err
@@ -173,7 +500,7 @@ func (c *Config) Save() error {
 		return fmt.Errorf("cannot serialize config: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0600); err != nil {
+	if err := fsutil.WriteFile(path, data, 0600); err != nil {
 		return fmt.Errorf("cannot write config: %w", err)
 	}
 	return nil
@@ -162,3 +162,70 @@ func TestEnsureDirs(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceModes(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Settings.Resources = &ResourcePolicy{
+		Copied: []string{"settings.json"},
+	}
+	cfg.Profiles["work"] = ProfileEntry{
+		Name: "work",
+		Resources: &ResourcePolicy{
+			Persistent: []string{"todos", "agents"},
+		},
+	}
+
+	modes := cfg.ResourceModes("work")
+	tests := map[string]string{
+		"commands":      ResourceShared,
+		"settings.json": ResourceCopied,
+		"agents":        ResourcePersistent,
+		"todos":         ResourcePersistent,
+	}
+	for path, want := range tests {
+		if got := modes[path]; got != want {
+			t.Errorf("mode of %s = %q, want %q", path, got, want)
+		}
+	}
+	// The profile's persistent list replaces the default one.
+	if _, ok := modes["projects"]; ok {
+		t.Errorf("projects should have no mode, got %q", modes["projects"])
+	}
+
+	if got := cfg.ResourceModes("other")["projects"]; got != ResourcePersistent {
+		t.Errorf("default mode of projects = %q, want %q", got, ResourcePersistent)
+	}
+}
+
+func TestPoolProfiles(t *testing.T) {
+	cfg := NewConfig()
+	for _, name := range []string{"a", "b", "c"} {
+		cfg.Profiles[name] = ProfileEntry{Name: name}
+	}
+	cfg.Pools = map[string][]PoolMember{
+		"main":   {{Profile: "a", Priority: 2}, {Profile: "b"}, {Profile: "c"}},
+		"broken": {{Profile: "missing"}},
+	}
+
+	got, err := cfg.PoolProfiles("main")
+	if err != nil {
+		t.Fatalf("PoolProfiles failed: %v", err)
+	}
+	want := []string{"b", "c", "a"}
+	if len(got) != len(want) {
+		t.Fatalf("PoolProfiles = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PoolProfiles = %v, want %v", got, want)
+			break
+		}
+	}
+
+	if _, err := cfg.PoolProfiles("broken"); err == nil {
+		t.Error("a pool naming an unknown profile should fail")
+	}
+	if _, err := cfg.PoolProfiles("nope"); err == nil {
+		t.Error("an unknown pool should fail")
+	}
+}
@@ -29,41 +29,51 @@ type EncryptedPayload struct {
 	Data    []byte `json:"data"`
 }
 
+// KeyedVersion marks payloads encrypted with a pre-derived key (no salt).
+const KeyedVersion = 2
+
 // deriveKey uses scrypt to derive an AES-256 key from a passphrase and salt.
 func deriveKey(passphrase string, salt []byte) ([]byte, error) {
 	// scrypt parameters: N=32768, r=8, p=1 (good balance of security/speed)
 	return scrypt.Key([]byte(passphrase), salt, 32768, 8, 1, KeySize)
 }
 
-// Encrypt encrypts plaintext with a passphrase using AES-256-GCM.
-func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+// DeriveKey derives an AES-256 key from a passphrase and salt. Callers that
+// encrypt many small files can derive once and use EncryptWithKey.
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive key: %w", err)
+	}
+	return key, nil
+}
+
+// NewSalt returns a random salt suitable for DeriveKey.
+func NewSalt() ([]byte, error) {
 	salt := make([]byte, SaltSize)
 	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
 		return nil, fmt.Errorf("cannot generate salt: %w", err)
 	}
+	return salt, nil
+}
 
-	key, err := deriveKey(passphrase, salt)
+// Encrypt encrypts plaintext with a passphrase using AES-256-GCM.
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt, err := NewSalt()
 	if err != nil {
-		return nil, fmt.Errorf("cannot derive key: %w", err)
+		return nil, err
 	}
 
-	block, err := aes.NewCipher(key)
+	key, err := deriveKey(passphrase, salt)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create cipher: %w", err)
+		return nil, fmt.Errorf("cannot derive key: %w", err)
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	nonce, ciphertext, err := seal(plaintext, key)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create GCM: %w", err)
+		return nil, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, fmt.Errorf("cannot generate nonce: %w", err)
-	}
-
-	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
-
 	payload := EncryptedPayload{
 		Version: 1,
 		Salt:    salt,
@@ -90,24 +100,100 @@ func Decrypt(encrypted []byte, passphrase string) ([]byte, error) {
 		return nil, fmt.Errorf("cannot derive key: %w", err)
 	}
 
-	block, err := aes.NewCipher(key)
+	plaintext, err := open(payload.Nonce, payload.Data, key)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create cipher: %w", err)
+		return nil, fmt.Errorf("decryption failed (wrong passphrase?): %w", err)
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	return plaintext, nil
+}
+
+// EncryptWithKey encrypts plaintext with a pre-derived AES-256 key.
+func EncryptWithKey(plaintext, key []byte) ([]byte, error) {
+	nonce, ciphertext, err := seal(plaintext, key)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create GCM: %w", err)
+		return nil, err
+	}
+
+	return json.Marshal(EncryptedPayload{
+		Version: KeyedVersion,
+		Nonce:   nonce,
+		Data:    ciphertext,
+	})
+}
+
+// DecryptWithKey decrypts a payload produced by EncryptWithKey.
+func DecryptWithKey(encrypted, key []byte) ([]byte, error) {
+	var payload EncryptedPayload
+	if err := json.Unmarshal(encrypted, &payload); err != nil {
+		return nil, fmt.Errorf("invalid encrypted payload: %w", err)
+	}
+
+	if payload.Version != KeyedVersion {
+		return nil, fmt.Errorf("unsupported encryption version: %d", payload.Version)
 	}
 
-	plaintext, err := gcm.Open(nil, payload.Nonce, payload.Data, nil)
+	plaintext, err := open(payload.Nonce, payload.Data, key)
 	if err != nil {
-		return nil, fmt.Errorf("decryption failed (wrong passphrase?): %w", err)
+		return nil, fmt.Errorf("decryption failed (wrong key?): %w", err)
 	}
 
 	return plaintext, nil
 }
 
+// IsEncrypted reports whether data looks like a payload produced by
+// Encrypt or EncryptWithKey.
+func IsEncrypted(data []byte) bool {
+	var payload EncryptedPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return false
+	}
+	if payload.Version != 1 && payload.Version != KeyedVersion {
+		return false
+	}
+	return len(payload.Nonce) == NonceSize && len(payload.Data) > 0
+}
+
+// seal encrypts plaintext with AES-256-GCM under key and a fresh nonce.
+func seal(plaintext, key []byte) (nonce, ciphertext []byte, err error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("cannot generate nonce: %w", err)
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// open decrypts AES-256-GCM ciphertext under key.
+func open(nonce, ciphertext, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d", len(nonce))
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
 // HashPassphrase returns a SHA-256 hash of a passphrase (for display/verification, not storage).
 func HashPassphrase(passphrase string) string {
 	h := sha256.Sum256([]byte(passphrase))
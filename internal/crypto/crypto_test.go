@@ -128,3 +128,52 @@ func TestEncryptLargeData(t *testing.T) {
 		t.Error("large data round-trip failed")
 	}
 }
+
+func TestEncryptWithKey(t *testing.T) {
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatalf("NewSalt failed: %v", err)
+	}
+	key, err := DeriveKey("vault-passphrase", salt)
+	if err != nil {
+		t.Fatalf("DeriveKey failed: %v", err)
+	}
+
+	plaintext := []byte(`{"claudeAiOauth": {}}`)
+	encrypted, err := EncryptWithKey(plaintext, key)
+	if err != nil {
+		t.Fatalf("EncryptWithKey failed: %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Error("IsEncrypted should detect keyed payload")
+	}
+
+	decrypted, err := DecryptWithKey(encrypted, key)
+	if err != nil {
+		t.Fatalf("DecryptWithKey failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("decrypted data doesn't match: got %q, want %q", decrypted, plaintext)
+	}
+
+	otherKey, _ := DeriveKey("other-passphrase", salt)
+	if _, err := DecryptWithKey(encrypted, otherKey); err == nil {
+		t.Error("expected error with wrong key")
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	encrypted, err := Encrypt([]byte("data"), "passphrase")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Error("IsEncrypted should detect passphrase payload")
+	}
+
+	for _, plain := range []string{`{"email": "a@example.com"}`, `not json`, `{"version": 1}`} {
+		if IsEncrypted([]byte(plain)) {
+			t.Errorf("IsEncrypted(%q) should be false", plain)
+		}
+	}
+}
@@ -13,9 +13,9 @@ import (
 
 // CheckResult represents the result of a single diagnostic check.
 type CheckResult struct {
-	Name    string
-	Status  string // "ok", "warn", "fail"
-	Message string
+	Name    string `json:"name"`
+	Status  string `json:"status"` // "ok", "warn", "fail"
+	Message string `json:"message"`
 }
 
 // RunAll executes all diagnostic checks and returns the results.
@@ -29,6 +29,7 @@ func RunAll(cfg *config.Config) []CheckResult {
 	results = append(results, checkConfigFile())
 	results = append(results, checkProfiles(cfg))
 	results = append(results, checkPermissions())
+	results = append(results, checkTempDirs())
 
 	return results
 }
@@ -122,7 +123,7 @@ func checkClaudeHome() CheckResult {
 }
 
 func checkCredentials() CheckResult {
-	path, err := config.ClaudeCredentialsPath()
+	store, err := profile.CurrentStore()
 	if err != nil {
 		return CheckResult{
 			Name:    "Credentials",
@@ -130,17 +131,18 @@ func checkCredentials() CheckResult {
 			Message: err.Error(),
 		}
 	}
-	if !profile.FileExists(path) {
+	data, err := store.Read()
+	if err != nil || len(data) == 0 {
 		return CheckResult{
 			Name:    "Credentials",
 			Status:  "warn",
-			Message: "no credentials file found — are you logged in?",
+			Message: fmt.Sprintf("no credentials in %s — are you logged in?", store.Describe()),
 		}
 	}
 	return CheckResult{
 		Name:    "Credentials",
 		Status:  "ok",
-		Message: "credentials file present",
+		Message: fmt.Sprintf("credentials present in %s", store.Describe()),
 	}
 }
 
@@ -269,3 +271,43 @@ func checkPermissions() CheckResult {
 		Message: fmt.Sprintf("%s (0%o)", appDir, perm),
 	}
 }
+
+func checkTempDirs() CheckResult {
+	dirs, err := profile.ListTempDirs()
+	if err != nil {
+		return CheckResult{
+			Name:    "Temp Files",
+			Status:  "warn",
+			Message: err.Error(),
+		}
+	}
+
+	inUse, orphaned := 0, 0
+	for _, d := range dirs {
+		if d.Orphaned {
+			orphaned++
+		} else {
+			inUse++
+		}
+	}
+
+	if orphaned > 0 {
+		return CheckResult{
+			Name:    "Temp Files",
+			Status:  "warn",
+			Message: fmt.Sprintf("%d leftover credential copies in %s — run: cs gc", orphaned, os.TempDir()),
+		}
+	}
+	if inUse > 0 {
+		return CheckResult{
+			Name:    "Temp Files",
+			Status:  "ok",
+			Message: fmt.Sprintf("%d in use by running cs processes", inUse),
+		}
+	}
+	return CheckResult{
+		Name:    "Temp Files",
+		Status:  "ok",
+		Message: "no leftover credential copies",
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
 )
 
 func TestRunAll(t *testing.T) {
@@ -72,6 +73,7 @@ func TestCheckCredentials(t *testing.T) {
 	origHome := os.Getenv("HOME")
 	os.Setenv("HOME", tmpDir)
 	defer os.Setenv("HOME", origHome)
+	t.Setenv(profile.StoreEnvVar, "file")
 
 	// Without credentials
 	r := checkCredentials()
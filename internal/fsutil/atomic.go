@@ -0,0 +1,62 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to path atomically: it writes a temporary file in
+// the same directory, syncs it to disk and renames it over path. Readers
+// see either the old contents or the new ones, never a partial write.
+// If path is a symlink, its target is replaced and the link is kept.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cannot create temp file in %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		return fail(fmt.Errorf("cannot set permissions on %s: %w", tmpPath, err))
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return fail(fmt.Errorf("cannot write %s: %w", tmpPath, err))
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(fmt.Errorf("cannot sync %s: %w", tmpPath, err))
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot close %s: %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot replace %s: %w", path, err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a directory entry so a completed rename survives a crash.
+// Errors are ignored: not every platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
@@ -0,0 +1,137 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockPoll is how often a blocked Acquire retries.
+const lockPoll = 100 * time.Millisecond
+
+// BusyError is returned when another process keeps holding a lock past the timeout.
+type BusyError struct {
+	Path   string
+	PID    int // holder's process ID, or 0 if unknown
+	Waited time.Duration
+}
+
+func (e *BusyError) Error() string {
+	holder := "another cs process"
+	if e.PID > 0 {
+		holder = fmt.Sprintf("another cs process (pid %d)", e.PID)
+	}
+	return fmt.Sprintf("%s is changing profiles — gave up waiting after %s (lock: %s)",
+		holder, e.Waited.Round(time.Millisecond), e.Path)
+}
+
+// Lock is an advisory, cross-process file lock. Within one process the
+// lock is re-entrant: nested Acquire calls on the same path share it.
+type Lock struct {
+	path string
+}
+
+var (
+	heldMu sync.Mutex
+	held   = map[string]*heldLock{}
+)
+
+type heldLock struct {
+	file  *os.File
+	count int
+}
+
+// Acquire takes the lock at path, waiting up to timeout for another process
+// to release it. notify, if non-nil, is called once when Acquire has to
+// wait, with the holder's PID (0 if unknown).
+func Acquire(path string, timeout time.Duration, notify func(pid int)) (*Lock, error) {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+
+	if h, ok := held[path]; ok {
+		h.count++
+		return &Lock{path: path}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("cannot create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open lock file: %w", err)
+	}
+
+	start := time.Now()
+	notified := false
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("cannot lock %s: %w", path, err)
+		}
+		if ok {
+			break
+		}
+
+		waited := time.Since(start)
+		if waited >= timeout {
+			pid := readHolder(path)
+			f.Close()
+			return nil, &BusyError{Path: path, PID: pid, Waited: waited}
+		}
+		if !notified && notify != nil {
+			notify(readHolder(path))
+			notified = true
+		}
+		time.Sleep(lockPoll)
+	}
+
+	// Record ourselves as the holder for the benefit of waiting processes.
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	held[path] = &heldLock{file: f, count: 1}
+	return &Lock{path: path}, nil
+}
+
+// Release drops this hold on the lock; the file lock itself is released
+// when the outermost holder in the process lets go. Release is idempotent.
+func (l *Lock) Release() error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	heldMu.Lock()
+	defer heldMu.Unlock()
+
+	path := l.path
+	l.path = ""
+	h, ok := held[path]
+	if !ok {
+		return nil
+	}
+	h.count--
+	if h.count > 0 {
+		return nil
+	}
+	delete(held, path)
+
+	_ = h.file.Truncate(0)
+	err := unlock(h.file)
+	h.file.Close()
+	return err
+}
+
+// readHolder returns the PID recorded in the lock file, or 0.
+func readHolder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
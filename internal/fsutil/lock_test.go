@@ -0,0 +1,92 @@
+package fsutil
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHelperHoldLock is not a real test: it is run as a child process by
+// TestAcquireAcrossProcesses to hold the lock from another PID.
+func TestHelperHoldLock(t *testing.T) {
+	path := os.Getenv("CS_TEST_LOCK_PATH")
+	if path == "" {
+		t.Skip("helper process only")
+	}
+	lock, err := Acquire(path, time.Second, nil)
+	if err != nil {
+		t.Fatalf("helper Acquire() error: %v", err)
+	}
+	os.Stdout.WriteString("locked\n")
+	// Hold the lock until the parent closes our stdin.
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	lock.Release()
+}
+
+func TestAcquireAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cs.lock")
+
+	child := exec.Command(os.Args[0], "-test.run=^TestHelperHoldLock$")
+	child.Env = append(os.Environ(), "CS_TEST_LOCK_PATH="+path)
+	stdin, _ := child.StdinPipe()
+	stdout, _ := child.StdoutPipe()
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer child.Wait()
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || line != "locked\n" {
+		stdin.Close()
+		t.Fatalf("helper did not take the lock: %q, %v", line, err)
+	}
+
+	notified := -1
+	_, err = Acquire(path, 300*time.Millisecond, func(pid int) { notified = pid })
+	var busy *BusyError
+	if !errors.As(err, &busy) {
+		stdin.Close()
+		t.Fatalf("Acquire() error = %v, want *BusyError", err)
+	}
+	if busy.PID != child.Process.Pid {
+		t.Errorf("BusyError.PID = %d, want %d", busy.PID, child.Process.Pid)
+	}
+	if notified != child.Process.Pid {
+		t.Errorf("notify got pid %d, want %d", notified, child.Process.Pid)
+	}
+
+	// Once the helper releases, the lock is ours.
+	stdin.Close()
+	lock, err := Acquire(path, 5*time.Second, nil)
+	if err != nil {
+		t.Fatalf("Acquire() after release error: %v", err)
+	}
+	lock.Release()
+}
+
+func TestAcquireReentrant(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cs.lock")
+
+	outer, err := Acquire(path, time.Second, nil)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	inner, err := Acquire(path, 0, nil)
+	if err != nil {
+		t.Fatalf("nested Acquire() error: %v", err)
+	}
+	inner.Release()
+	inner.Release() // idempotent
+
+	if _, ok := held[path]; !ok {
+		t.Fatal("inner Release() dropped the outer hold")
+	}
+	outer.Release()
+	if _, ok := held[path]; ok {
+		t.Fatal("lock still held after outer Release()")
+	}
+}
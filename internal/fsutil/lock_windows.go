@@ -0,0 +1,50 @@
+//go:build windows
+
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileExclusiveLock   = 0x2
+	lockfileFailImmediately = 0x1
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// lockOffset places the locked byte past the PID so waiters can still read it.
+const lockOffset = 1 << 30
+
+// tryLock takes an exclusive LockFileEx lock without blocking. It reports
+// false if another process holds the lock.
+func tryLock(f *os.File) (bool, error) {
+	ol := syscall.Overlapped{Offset: lockOffset}
+	r, _, err := procLockFileEx.Call(f.Fd(),
+		lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0,
+		uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return true, nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return false, nil
+	}
+	return false, err
+}
+
+func unlock(f *os.File) error {
+	ol := syscall.Overlapped{Offset: lockOffset}
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package fsutil
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ProcessAlive reports whether a process with the given pid exists.
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
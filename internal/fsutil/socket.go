@@ -0,0 +1,40 @@
+package fsutil
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ListenUnix listens on a Unix socket at path that only the owner can
+// connect to, replacing a stale socket file. The socket is created inside
+// a directory restricted to the owner (0700), so it is out of reach of
+// others before it is set to mode 0600, whatever the umask.
+func ListenUnix(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create directory %s: %w", dir, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(dir, 0700); err != nil {
+			return nil, fmt.Errorf("cannot restrict %s to its owner: %w", dir, err)
+		}
+	}
+	_ = os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("cannot secure %s: %w", path, err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,39 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestListenUnixRestrictsAccess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions")
+	}
+	dir := filepath.Join(t.TempDir(), "app")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "test.sock")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := ListenUnix(path)
+	if err != nil {
+		t.Fatalf("ListenUnix() error: %v", err)
+	}
+	defer ln.Close()
+
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0700 {
+		t.Errorf("directory mode = %o, want 700", info.Mode().Perm())
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want a socket with mode 600", info.Mode())
+	}
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// APIVersion identifies the schema of machine-readable documents. It only
+// changes when a field is removed or changes meaning; fields may be added.
+const APIVersion = "cs/v1"
+
+// Output formats accepted by --output.
+const (
+	Text = "text"
+	JSON = "json"
+	YAML = "yaml"
+	TSV  = "tsv"
+)
+
+// Formats lists the accepted output formats.
+func Formats() []string {
+	return []string{Text, JSON, YAML, TSV}
+}
+
+// Validate returns an error if format is not one of Formats.
+func Validate(format string) error {
+	for _, f := range Formats() {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (use %s)", format, strings.Join(Formats(), ", "))
+}
+
+// Document is the envelope shared by every JSON and YAML document.
+type Document struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Data       interface{} `json:"data"`
+}
+
+// Tabular is implemented by data that can be written as TSV. Rows returns
+// the column names and one row of values per record.
+type Tabular interface {
+	Rows() (header []string, rows [][]string)
+}
+
+// Write renders data as a document of the given kind in format.
+func Write(w io.Writer, format, kind string, data interface{}) error {
+	doc := Document{APIVersion: APIVersion, Kind: kind, Data: data}
+
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case YAML:
+		out, err := MarshalYAML(doc)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case TSV:
+		t, ok := data.(Tabular)
+		if !ok {
+			return fmt.Errorf("%s cannot be written as tsv — use json or yaml", kind)
+		}
+		header, rows := t.Rows()
+		return writeTSV(w, header, rows)
+	default:
+		return Validate(format)
+	}
+}
+
+// writeTSV writes a header line and rows, replacing tabs and newlines in
+// values with spaces so every record stays on one line.
+func writeTSV(w io.Writer, header []string, rows [][]string) error {
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	line := func(cols []string) error {
+		out := make([]string, len(cols))
+		for i, c := range cols {
+			out[i] = clean.Replace(c)
+		}
+		_, err := fmt.Fprintln(w, strings.Join(out, "\t"))
+		return err
+	}
+
+	if err := line(header); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := line(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
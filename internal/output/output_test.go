@@ -0,0 +1,123 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testRow struct {
+	Name  string   `json:"name"`
+	Note  string   `json:"note"`
+	Tags  []string `json:"tags"`
+	Count int      `json:"count"`
+}
+
+type testList struct {
+	Items []testRow `json:"items"`
+}
+
+func (l testList) Rows() ([]string, [][]string) {
+	var rows [][]string
+	for _, r := range l.Items {
+		rows = append(rows, []string{r.Name, r.Note})
+	}
+	return []string{"NAME", "NOTE"}, rows
+}
+
+var sample = testList{Items: []testRow{
+	{Name: "work", Note: "key: value", Tags: []string{"a", "b"}, Count: 2},
+	{Name: "true", Note: "tab\there", Tags: []string{}},
+}}
+
+func TestValidate(t *testing.T) {
+	for _, f := range Formats() {
+		if err := Validate(f); err != nil {
+			t.Errorf("Validate(%q) = %v", f, err)
+		}
+	}
+	if err := Validate("xml"); err == nil {
+		t.Error("Validate should reject unknown formats")
+	}
+}
+
+func TestWriteJSONEnvelope(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, JSON, "TestList", sample); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var doc struct {
+		APIVersion string   `json:"apiVersion"`
+		Kind       string   `json:"kind"`
+		Data       testList `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if doc.APIVersion != APIVersion || doc.Kind != "TestList" || len(doc.Data.Items) != 2 {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, YAML, "TestList", sample); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	want := `apiVersion: cs/v1
+kind: TestList
+data:
+  items:
+    - name: work
+      note: "key: value"
+      tags:
+        - a
+        - b
+      count: 2
+    - name: "true"
+      note: "tab\there"
+      tags: []
+      count: 0
+`
+	if buf.String() != want {
+		t.Errorf("yaml output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteTSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, TSV, "TestList", sample); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	want := "NAME\tNOTE\nwork\tkey: value\ntrue\ttab here\n"
+	if buf.String() != want {
+		t.Errorf("tsv output = %q, want %q", buf.String(), want)
+	}
+
+	err := Write(&buf, TSV, "Plain", map[string]string{"a": "b"})
+	if err == nil || !strings.Contains(err.Error(), "tsv") {
+		t.Errorf("non-tabular data should be rejected for tsv, got %v", err)
+	}
+}
+
+func TestQuoteYAML(t *testing.T) {
+	tests := map[string]string{
+		"plain":       "plain",
+		"":            `""`,
+		"null":        `"null"`,
+		"2026-01-01":  `"2026-01-01"`,
+		"- item":      `"- item"`,
+		"a # comment": `"a # comment"`,
+		"line\nbreak": `"line\nbreak"`,
+		"has space":   "has space",
+	}
+	for in, want := range tests {
+		if got := quoteYAML(in); got != want {
+			t.Errorf("quoteYAML(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarshalYAML encodes v as block-style YAML. v is first encoded as JSON, so
+// json struct tags apply and field order is preserved.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := parseNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode yaml: %w", err)
+	}
+
+	var b strings.Builder
+	writeNode(&b, root, 0)
+	return []byte(b.String()), nil
+}
+
+// yamlNode is a JSON value with object key order preserved.
+type yamlNode struct {
+	scalar string // set for strings, numbers, booleans and null
+	isMap  bool
+	isList bool
+	keys   []string
+	values []*yamlNode
+}
+
+func parseNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &yamlNode{isMap: t == '{', isList: t == '['}
+		for dec.More() {
+			if n.isMap {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, _ := keyTok.(string)
+				n.keys = append(n.keys, key)
+			}
+			child, err := parseNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, child)
+		}
+		if _, err := dec.Token(); err != nil { // closing delimiter
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &yamlNode{scalar: quoteYAML(t)}, nil
+	case json.Number:
+		return &yamlNode{scalar: t.String()}, nil
+	case bool:
+		return &yamlNode{scalar: fmt.Sprintf("%t", t)}, nil
+	case nil:
+		return &yamlNode{scalar: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+func (n *yamlNode) isEmptyCollection() bool {
+	return (n.isMap || n.isList) && len(n.values) == 0
+}
+
+func (n *yamlNode) inline() string {
+	switch {
+	case n.isMap:
+		return "{}"
+	case n.isList:
+		return "[]"
+	default:
+		return n.scalar
+	}
+}
+
+func writeNode(b *strings.Builder, n *yamlNode, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	switch {
+	case n.isEmptyCollection() || (!n.isMap && !n.isList):
+		b.WriteString(pad + n.inline() + "\n")
+
+	case n.isMap:
+		for i, key := range n.keys {
+			writeEntry(b, pad+quoteYAML(key)+":", n.values[i], indent)
+		}
+
+	case n.isList:
+		for _, item := range n.values {
+			if item.isMap && !item.isEmptyCollection() {
+				// First key shares the dash line; the rest align under it.
+				var inner strings.Builder
+				writeNode(&inner, item, indent+2)
+				b.WriteString(pad + "- " + strings.TrimPrefix(inner.String(), pad+"  "))
+				continue
+			}
+			writeEntry(b, pad+"-", item, indent)
+		}
+	}
+}
+
+// writeEntry writes "prefix value" for scalars and empty collections, or
+// prefix followed by the nested block.
+func writeEntry(b *strings.Builder, prefix string, v *yamlNode, indent int) {
+	if v.isEmptyCollection() || (!v.isMap && !v.isList) {
+		b.WriteString(prefix + " " + v.inline() + "\n")
+		return
+	}
+	b.WriteString(prefix + "\n")
+	writeNode(b, v, indent+2)
+}
+
+// quoteYAML returns s as a plain scalar when that is unambiguous, or as a
+// double-quoted scalar (JSON string syntax is valid YAML) otherwise.
+func quoteYAML(s string) string {
+	if needsQuoting(s) {
+		q, _ := json.Marshal(s)
+		return string(q)
+	}
+	return s
+}
+
+func needsQuoting(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`0123456789.+") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
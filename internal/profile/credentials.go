@@ -2,9 +2,10 @@ package profile
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/caeser1996/claude-switch/internal/fsutil"
 )
 
 // CredentialFiles lists the files we track from the Claude config directory.
@@ -19,35 +20,18 @@ var HomeCredentialFiles = []string{
 	".claude.json",
 }
 
-// CopyFile copies a single file preserving permissions.
+// CopyFile copies a single file, replacing dst atomically with 0600 permissions.
 func CopyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
+	data, err := os.ReadFile(src)
 	if err != nil {
 		return fmt.Errorf("cannot open %s: %w", src, err)
 	}
-	defer srcFile.Close()
-
-	info, err := srcFile.Stat()
-	if err != nil {
-		return fmt.Errorf("cannot stat %s: %w", src, err)
-	}
 
 	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
 		return fmt.Errorf("cannot create directory for %s: %w", dst, err)
 	}
 
-	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
-	if err != nil {
-		return fmt.Errorf("cannot create %s: %w", dst, err)
-	}
-	defer dstFile.Close()
-
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		return fmt.Errorf("cannot copy to %s: %w", dst, err)
-	}
-
-	// Ensure secure permissions for credential files
-	return os.Chmod(dst, 0600)
+	return fsutil.WriteFile(dst, data, 0600)
 }
 
 // FileExists checks if a file exists and is not a directory.
@@ -0,0 +1,147 @@
+package profile
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+)
+
+// DriftKind classifies how the live Claude session relates to the stored profiles.
+type DriftKind string
+
+const (
+	// DriftNone means the live session belongs to the active profile.
+	DriftNone DriftKind = "in-sync"
+	// DriftOtherProfile means the live session belongs to a different stored profile.
+	DriftOtherProfile DriftKind = "other-profile"
+	// DriftUnknown means the live session belongs to an account no profile knows about.
+	DriftUnknown DriftKind = "unknown-account"
+	// DriftNoSession means no live credentials were found.
+	DriftNoSession DriftKind = "no-session"
+)
+
+// DriftReport describes the result of comparing the live session with the stored profiles.
+type DriftReport struct {
+	Kind   DriftKind
+	Active string // profile recorded as active in config
+	Match  string // profile the live session matches, if any
+	Email  string // email of the live session, if known
+}
+
+// Drifted reports whether the live session disagrees with the active profile.
+func (r DriftReport) Drifted() bool {
+	return r.Kind == DriftOtherProfile || r.Kind == DriftUnknown
+}
+
+// Describe returns a one-line human-readable summary of the report.
+func (r DriftReport) Describe() string {
+	switch r.Kind {
+	case DriftNone:
+		return fmt.Sprintf("live session matches active profile %q", r.Active)
+	case DriftOtherProfile:
+		return fmt.Sprintf("live session matches profile %q, but %q is marked active", r.Match, r.Active)
+	case DriftUnknown:
+		if r.Email != "" {
+			return fmt.Sprintf("live session is an unknown account (%s)", r.Email)
+		}
+		return "live session is an unknown account"
+	default:
+		return "no live Claude session found"
+	}
+}
+
+// DetectDrift compares the live credential store and ~/.claude.json against
+// every stored profile, matching by account identity or token fingerprint.
+func (m *Manager) DetectDrift() DriftReport {
+	report := DriftReport{Kind: DriftNoSession, Active: m.Config.ActiveProfile}
+
+	live, err := ReadCurrentCredentials()
+	if err != nil || len(live) == 0 {
+		return report
+	}
+
+	id := liveIdentity(live)
+	report.Email = id.Email
+	fp := tokenFingerprint(live)
+
+	// The active profile wins ties, e.g. when two profiles share an email.
+	if report.Active != "" {
+		if dir, err := m.profileDir(report.Active); err == nil && m.matchesProfile(dir, id, fp) {
+			report.Kind = DriftNone
+			report.Match = report.Active
+			return report
+		}
+	}
+
+	for _, p := range m.List() {
+		if p.Name == report.Active {
+			continue
+		}
+		dir, err := m.profileDir(p.Name)
+		if err != nil {
+			continue
+		}
+		if m.matchesProfile(dir, id, fp) {
+			report.Kind = DriftOtherProfile
+			report.Match = p.Name
+			return report
+		}
+	}
+
+	report.Kind = DriftUnknown
+	return report
+}
+
+// Adopt marks the given profile as active without touching the live session.
+// It is used to reconcile the config after the user switched accounts manually.
+func (m *Manager) Adopt(name string) error {
+	if _, ok := m.Config.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	m.setActive(name)
+
+	// The live session now belongs to this profile, so keep its tokens.
+	if err := m.persistLiveCredentials(); err != nil {
+		return err
+	}
+
+	return m.Config.Save()
+}
+
+// matchesProfile reports whether the stored profile belongs to the live account.
+func (m *Manager) matchesProfile(profileDir string, id accountID, fp string) bool {
+	if !DirExists(profileDir) {
+		return false
+	}
+	if id.Matches(profileIdentity(profileDir)) {
+		return true
+	}
+	if fp == "" {
+		return false
+	}
+	stored, err := ReadSealed(filepath.Join(profileDir, ".credentials.json"))
+	if err != nil {
+		return false
+	}
+	return tokenFingerprint(stored) == fp
+}
+
+// tokenFingerprint returns a short hash of the refresh token (or, failing
+// that, the access token or API key), or "" if none is present.
+func tokenFingerprint(credData []byte) string {
+	creds, err := claude.ParseCredentials(credData, nil)
+	if err != nil {
+		return ""
+	}
+
+	for _, tok := range []string{creds.RefreshToken, creds.AccessToken, creds.APIKey} {
+		if tok != "" {
+			sum := sha256.Sum256([]byte(tok))
+			return fmt.Sprintf("%x", sum[:8])
+		}
+	}
+	return ""
+}
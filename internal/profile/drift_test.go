@@ -0,0 +1,127 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+func TestDetectDriftInSync(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("work", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	report := mgr.DetectDrift()
+	if report.Kind != DriftNone {
+		t.Errorf("expected %q, got %q", DriftNone, report.Kind)
+	}
+	if report.Drifted() {
+		t.Error("in-sync report should not be drifted")
+	}
+}
+
+func TestDetectDriftOtherProfileAndAdopt(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("work", ""); err != nil {
+		t.Fatalf("Import work failed: %v", err)
+	}
+
+	livePath := filepath.Join(tmpDir, ".claude", ".credentials.json")
+	if err := os.WriteFile(livePath,
+		[]byte(`{"email": "home@example.com", "token": "home-token"}`), 0600); err != nil {
+		t.Fatalf("cannot write credentials: %v", err)
+	}
+	if err := mgr.Import("home", ""); err != nil {
+		t.Fatalf("Import home failed: %v", err)
+	}
+
+	report := mgr.DetectDrift()
+	if report.Kind != DriftOtherProfile || report.Match != "home" {
+		t.Fatalf("expected drift to 'home', got %+v", report)
+	}
+
+	if err := mgr.Adopt("home"); err != nil {
+		t.Fatalf("Adopt failed: %v", err)
+	}
+	if cfg.ActiveProfile != "home" || !cfg.Profiles["home"].IsActive || cfg.Profiles["work"].IsActive {
+		t.Errorf("Adopt did not re-point the active profile: %+v", cfg)
+	}
+	if mgr.DetectDrift().Drifted() {
+		t.Error("expected no drift after Adopt")
+	}
+}
+
+func TestDetectDriftUnknownAccount(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("work", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	livePath := filepath.Join(tmpDir, ".claude", ".credentials.json")
+	if err := os.WriteFile(livePath,
+		[]byte(`{"email": "stranger@example.com", "token": "stranger-token"}`), 0600); err != nil {
+		t.Fatalf("cannot write credentials: %v", err)
+	}
+
+	report := mgr.DetectDrift()
+	if report.Kind != DriftUnknown {
+		t.Fatalf("expected %q, got %q", DriftUnknown, report.Kind)
+	}
+	if report.Email != "stranger@example.com" {
+		t.Errorf("expected live email 'stranger@example.com', got %q", report.Email)
+	}
+}
+
+func TestDetectDriftByHomeIdentity(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// Real credentials carry no email; identity comes from ~/.claude.json.
+	livePath := filepath.Join(tmpDir, ".claude", ".credentials.json")
+	homePath := filepath.Join(tmpDir, ".claude.json")
+	writeSession := func(uuid, token string) {
+		t.Helper()
+		if err := os.WriteFile(livePath,
+			[]byte(`{"claudeAiOauth": {"accessToken": "`+token+`", "refreshToken": "`+token+`"}}`), 0600); err != nil {
+			t.Fatalf("cannot write credentials: %v", err)
+		}
+		if err := os.WriteFile(homePath,
+			[]byte(`{"oauthAccount": {"accountUuid": "`+uuid+`"}}`), 0600); err != nil {
+			t.Fatalf("cannot write .claude.json: %v", err)
+		}
+	}
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+
+	writeSession("uuid-a", "token-a")
+	if err := mgr.Import("a", ""); err != nil {
+		t.Fatalf("Import a failed: %v", err)
+	}
+	writeSession("uuid-b", "token-b")
+	if err := mgr.Import("b", ""); err != nil {
+		t.Fatalf("Import b failed: %v", err)
+	}
+
+	// Same account as "a", but with a rotated token.
+	writeSession("uuid-a", "token-a2")
+	report := mgr.DetectDrift()
+	if report.Kind != DriftNone {
+		t.Errorf("expected in-sync with 'a', got %+v", report)
+	}
+}
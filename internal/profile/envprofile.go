@@ -0,0 +1,209 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+// EnvFile holds the variables of an environment profile inside its
+// profile directory. Like credential files it is sealed by the vault.
+const EnvFile = "env.json"
+
+// Providers reported for environment profiles.
+const (
+	ProviderAPIKey  = "api-key"
+	ProviderBedrock = "bedrock"
+	ProviderVertex  = "vertex"
+	ProviderGateway = "gateway"
+	ProviderCustom  = "custom"
+)
+
+// ProviderKind infers which backend an environment profile talks to.
+func ProviderKind(vars map[string]string) string {
+	switch {
+	case isTruthy(vars["CLAUDE_CODE_USE_BEDROCK"]):
+		return ProviderBedrock
+	case isTruthy(vars["CLAUDE_CODE_USE_VERTEX"]):
+		return ProviderVertex
+	case vars["ANTHROPIC_BASE_URL"] != "":
+		return ProviderGateway
+	case vars["ANTHROPIC_API_KEY"] != "" || vars["ANTHROPIC_AUTH_TOKEN"] != "":
+		return ProviderAPIKey
+	default:
+		return ProviderCustom
+	}
+}
+
+func isTruthy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// IsSecretVar reports whether a variable's value should be masked when shown.
+func IsSecretVar(key, value string) bool {
+	upper := strings.ToUpper(key)
+	for _, marker := range []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "CREDENTIAL"} {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	// Proxy URLs often carry user:password.
+	return strings.HasSuffix(upper, "_PROXY") && strings.Contains(value, "@")
+}
+
+// MaskValue hides all but the last four characters of a secret.
+func MaskValue(value string) string {
+	if len(value) <= 8 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
+
+// ParseEnvAssignments parses KEY=VALUE arguments.
+func ParseEnvAssignments(args []string) (map[string]string, error) {
+	vars := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid assignment %q: expected KEY=VALUE", arg)
+		}
+		if err := validateEnvName(key); err != nil {
+			return nil, err
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+func validateEnvName(key string) error {
+	if key == "" {
+		return fmt.Errorf("environment variable name cannot be empty")
+	}
+	for i, c := range key {
+		if !(c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (i > 0 && c >= '0' && c <= '9')) {
+			return fmt.Errorf("invalid environment variable name %q", key)
+		}
+	}
+	if key == "CLAUDE_CONFIG_DIR" {
+		return fmt.Errorf("CLAUDE_CONFIG_DIR is managed by cs and cannot be set in a profile")
+	}
+	return nil
+}
+
+// AddEnv saves an environment profile holding vars. With replace, an
+// existing environment profile of the same name is overwritten.
+func (m *Manager) AddEnv(name, description string, vars map[string]string, replace bool) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+	if len(vars) == 0 {
+		return fmt.Errorf("no variables given — use KEY=VALUE arguments")
+	}
+	for key := range vars {
+		if err := validateEnvName(key); err != nil {
+			return err
+		}
+	}
+
+	existing, exists := m.Config.Profiles[name]
+	if exists && (!existing.IsEnv() || !replace) {
+		return fmt.Errorf("profile %q already exists — remove it first or use --replace", name)
+	}
+
+	profileDir, err := m.profileDir(name)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode variables: %w", err)
+	}
+
+	action := fmt.Sprintf("saving profile %q", name)
+	txn := NewTxn()
+	if err := txn.MkdirAll(profileDir); err != nil {
+		return txn.Fail(action, err)
+	}
+	if err := txn.WriteSealed(filepath.Join(profileDir, EnvFile), data); err != nil {
+		return txn.Fail(action, err)
+	}
+
+	entry := config.ProfileEntry{
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now().UTC(),
+		Kind:        config.ProfileKindEnv,
+		Provider:    ProviderKind(vars),
+		EnvVars:     sortedKeys(vars),
+	}
+	if exists {
+		entry.CreatedAt = existing.CreatedAt
+		entry.IsActive = existing.IsActive
+		if description == "" {
+			entry.Description = existing.Description
+		}
+	}
+
+	m.Config.Profiles[name] = entry
+	if err := m.Config.Save(); err != nil {
+		if exists {
+			m.Config.Profiles[name] = existing
+		} else {
+			delete(m.Config.Profiles, name)
+		}
+		return txn.Fail(action, err)
+	}
+	return nil
+}
+
+// LoadEnvVars reads the variables stored in an environment profile directory.
+func LoadEnvVars(profileDir string) (map[string]string, error) {
+	data, err := ReadSealed(filepath.Join(profileDir, EnvFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("profile has no stored environment variables")
+		}
+		return nil, err
+	}
+	var vars map[string]string
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", EnvFile, err)
+	}
+	return vars, nil
+}
+
+// ProfileEnvVars returns the variables of the named profile, or nil for a
+// credential-file profile.
+func ProfileEnvVars(name string, cfg *config.Config) (map[string]string, error) {
+	entry, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	if !entry.IsEnv() {
+		return nil, nil
+	}
+	profilesDir, err := config.ProfilesDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadEnvVars(filepath.Join(profilesDir, name))
+}
+
+func sortedKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
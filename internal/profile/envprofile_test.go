@@ -0,0 +1,127 @@
+package profile
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+func TestProviderKind(t *testing.T) {
+	tests := []struct {
+		vars map[string]string
+		want string
+	}{
+		{map[string]string{"ANTHROPIC_API_KEY": "sk"}, ProviderAPIKey},
+		{map[string]string{"CLAUDE_CODE_USE_BEDROCK": "1", "AWS_PROFILE": "prod"}, ProviderBedrock},
+		{map[string]string{"CLAUDE_CODE_USE_VERTEX": "true"}, ProviderVertex},
+		{map[string]string{"ANTHROPIC_BASE_URL": "https://gw", "ANTHROPIC_AUTH_TOKEN": "t"}, ProviderGateway},
+		{map[string]string{"CLAUDE_CODE_USE_BEDROCK": "0", "HTTPS_PROXY": "http://p"}, ProviderCustom},
+	}
+	for _, tt := range tests {
+		if got := ProviderKind(tt.vars); got != tt.want {
+			t.Errorf("ProviderKind(%v) = %q, want %q", tt.vars, got, tt.want)
+		}
+	}
+}
+
+func TestParseEnvAssignments(t *testing.T) {
+	vars, err := ParseEnvAssignments([]string{"A=1", "B=x=y", "EMPTY="})
+	if err != nil {
+		t.Fatalf("ParseEnvAssignments() error: %v", err)
+	}
+	if vars["A"] != "1" || vars["B"] != "x=y" || vars["EMPTY"] != "" {
+		t.Errorf("unexpected result: %v", vars)
+	}
+
+	for _, bad := range []string{"NOEQUALS", "=v", "1ABC=v", "BAD-NAME=v", "CLAUDE_CONFIG_DIR=/tmp"} {
+		if _, err := ParseEnvAssignments([]string{bad}); err == nil {
+			t.Errorf("ParseEnvAssignments(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAddEnvKeepsSecretsOutOfConfig(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+
+	vars := map[string]string{"ANTHROPIC_API_KEY": "sk-ant-secret-value", "ANTHROPIC_MODEL": "claude-sonnet"}
+	if err := mgr.AddEnv("api", "API key", vars, false); err != nil {
+		t.Fatalf("AddEnv() error: %v", err)
+	}
+
+	entry := cfg.Profiles["api"]
+	if !entry.IsEnv() || entry.Provider != ProviderAPIKey {
+		t.Errorf("entry = %+v, want env profile with api-key provider", entry)
+	}
+	if cfg.ActiveProfile == "api" {
+		t.Error("env profile should not become the active profile")
+	}
+
+	path, _ := config.ConfigPath()
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "sk-ant-secret-value") {
+		t.Error("secret value leaked into config.json")
+	}
+	if !strings.Contains(string(raw), "ANTHROPIC_API_KEY") {
+		t.Error("config.json should list the variable names")
+	}
+
+	if err := mgr.AddEnv("api", "", vars, false); err == nil {
+		t.Error("AddEnv() should refuse to overwrite without replace")
+	}
+	if err := mgr.AddEnv("api", "", map[string]string{"ANTHROPIC_API_KEY": "new"}, true); err != nil {
+		t.Fatalf("AddEnv(replace) error: %v", err)
+	}
+	if cfg.Profiles["api"].Description != "API key" {
+		t.Error("replace should keep the existing description when none is given")
+	}
+
+	status := CheckTokenStatus("api")
+	if !status.HasCreds || status.Kind != config.ProfileKindEnv || status.Provider != ProviderAPIKey {
+		t.Errorf("CheckTokenStatus() = %+v", status)
+	}
+
+	if err := mgr.Use("api"); err == nil {
+		t.Error("Use() should refuse an env profile")
+	}
+}
+
+func TestIsolatedEnvInjectsVars(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	vars := map[string]string{"CLAUDE_CODE_USE_BEDROCK": "1", "AWS_PROFILE": "prod"}
+	if err := mgr.AddEnv("bedrock", "", vars, false); err != nil {
+		t.Fatalf("AddEnv() error: %v", err)
+	}
+
+	t.Setenv("AWS_PROFILE", "dev")
+
+	env, err := SetupIsolatedEnv("bedrock", cfg)
+	if err != nil {
+		t.Fatalf("SetupIsolatedEnv() error: %v", err)
+	}
+	defer env.Cleanup()
+
+	got := map[string][]string{}
+	for _, kv := range env.Env() {
+		key, value, _ := strings.Cut(kv, "=")
+		got[key] = append(got[key], value)
+	}
+	if v := got["AWS_PROFILE"]; len(v) != 1 || v[0] != "prod" {
+		t.Errorf("AWS_PROFILE = %v, want exactly [prod]", v)
+	}
+	if v := got["CLAUDE_CODE_USE_BEDROCK"]; len(v) != 1 || v[0] != "1" {
+		t.Errorf("CLAUDE_CODE_USE_BEDROCK = %v, want [1]", v)
+	}
+	if v := got["CLAUDE_CONFIG_DIR"]; len(v) != 1 || v[0] != env.TempDir {
+		t.Errorf("CLAUDE_CONFIG_DIR = %v, want [%s]", v, env.TempDir)
+	}
+}
@@ -3,7 +3,6 @@ package profile
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"github.com/caeser1996/claude-switch/internal/config"
@@ -16,6 +15,9 @@ type ExportBundle struct {
 	ProfileName string            `json:"profile_name"`
 	Email       string            `json:"email,omitempty"`
 	Description string            `json:"description,omitempty"`
+	Kind        string            `json:"kind,omitempty"`
+	Provider    string            `json:"provider,omitempty"`
+	EnvVars     []string          `json:"env_vars,omitempty"`
 	Files       map[string][]byte `json:"files"`
 }
 
@@ -41,21 +43,19 @@ func ExportProfile(name string, cfg *config.Config, passphrase string) ([]byte,
 		ProfileName: name,
 		Email:       entry.Email,
 		Description: entry.Description,
+		Kind:        entry.Kind,
+		Provider:    entry.Provider,
+		EnvVars:     entry.EnvVars,
 		Files:       make(map[string][]byte),
 	}
 
-	// Collect all credential files
-	allFiles := append([]string{}, CredentialFiles...)
-	for _, f := range HomeCredentialFiles {
-		allFiles = append(allFiles, "home_"+f)
-	}
-
-	for _, fname := range allFiles {
+	// Collect all credential files, decrypting any sealed by the vault
+	for _, fname := range storedFileNames() {
 		fpath := filepath.Join(profileDir, fname)
 		if !FileExists(fpath) {
 			continue
 		}
-		data, err := os.ReadFile(fpath)
+		data, err := ReadSealed(fpath)
 		if err != nil {
 			return nil, fmt.Errorf("cannot read %s: %w", fname, err)
 		}
@@ -98,31 +98,47 @@ func ImportFromFile(data []byte, passphrase string, overrideName string, cfg *co
 		return "", fmt.Errorf("profile name is empty in bundle")
 	}
 
+	if err := validateProfileName(name); err != nil {
+		return "", err
+	}
+	for fname := range bundle.Files {
+		if !isStoredFileName(fname) {
+			return "", fmt.Errorf("invalid profile bundle: unexpected file %q", fname)
+		}
+	}
+
 	profilesDir, err := config.ProfilesDir()
 	if err != nil {
 		return "", err
 	}
 	profileDir := filepath.Join(profilesDir, name)
 
-	if err := os.MkdirAll(profileDir, 0700); err != nil {
-		return "", fmt.Errorf("cannot create profile directory: %w", err)
+	// Write the files and the config entry as one unit, so a failure can't
+	// leave a half-imported profile or clobber an existing one.
+	action := fmt.Sprintf("import of profile %q", name)
+	txn := NewTxn()
+	if err := txn.MkdirAll(profileDir); err != nil {
+		return "", txn.Fail(action, err)
 	}
-
-	// Write credential files
 	for fname, content := range bundle.Files {
-		fpath := filepath.Join(profileDir, fname)
-		if err := os.WriteFile(fpath, content, 0600); err != nil {
-			return "", fmt.Errorf("cannot write %s: %w", fname, err)
+		if err := txn.WriteSealed(filepath.Join(profileDir, fname), content); err != nil {
+			return "", txn.Fail(action, fmt.Errorf("cannot write %s: %w", fname, err))
 		}
 	}
 
 	// Update config
-	isFirst := len(cfg.Profiles) == 0
+	prevEntry, existed := cfg.Profiles[name]
+	prevActive := cfg.ActiveProfile
+	// The first credential profile becomes active; env profiles are never switched to.
+	isFirst := len(cfg.Profiles) == 0 && bundle.Kind != config.ProfileKindEnv
 	entry := config.ProfileEntry{
 		Name:        name,
 		Email:       bundle.Email,
 		Description: bundle.Description,
 		IsActive:    isFirst,
+		Kind:        bundle.Kind,
+		Provider:    bundle.Provider,
+		EnvVars:     bundle.EnvVars,
 	}
 	cfg.Profiles[name] = entry
 	if isFirst {
@@ -130,7 +146,13 @@ func ImportFromFile(data []byte, passphrase string, overrideName string, cfg *co
 	}
 
 	if err := cfg.Save(); err != nil {
-		return "", err
+		if existed {
+			cfg.Profiles[name] = prevEntry
+		} else {
+			delete(cfg.Profiles, name)
+		}
+		cfg.ActiveProfile = prevActive
+		return "", txn.Fail(action, err)
 	}
 
 	return name, nil
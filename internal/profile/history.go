@@ -0,0 +1,90 @@
+package profile
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+// SwitchEvent records that the active profile changed.
+type SwitchEvent struct {
+	Time     time.Time `json:"time"`
+	Profile  string    `json:"profile"`
+	Previous string    `json:"previous,omitempty"`
+}
+
+// RecordSwitch appends a switch from previous to profile to the history.
+func RecordSwitch(previous, profile string) error {
+	if previous == profile {
+		return nil
+	}
+	path, err := config.HistoryPath()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(SwitchEvent{Time: time.Now().UTC(), Profile: profile, Previous: previous})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open switch history: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// LoadHistory reads the switch history, oldest first. Unreadable lines are
+// skipped.
+func LoadHistory() (History, error) {
+	path, err := config.HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read switch history: %w", err)
+	}
+	defer f.Close()
+
+	var h History
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev SwitchEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil && ev.Profile != "" {
+			h = append(h, ev)
+		}
+	}
+	sort.SliceStable(h, func(i, j int) bool { return h[i].Time.Before(h[j].Time) })
+	return h, scanner.Err()
+}
+
+// History is the switch history, oldest first.
+type History []SwitchEvent
+
+// ProfileAt returns the profile that was active at t. Before the first
+// recorded switch that is the profile switched away from, and with no
+// history at all it is fallback.
+func (h History) ProfileAt(t time.Time, fallback string) string {
+	i := sort.Search(len(h), func(i int) bool { return h[i].Time.After(t) })
+	switch {
+	case i > 0:
+		return h[i-1].Profile
+	case len(h) > 0 && h[0].Previous != "":
+		return h[0].Previous
+	case len(h) > 0:
+		return h[0].Profile
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,38 @@
+package profile
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryProfileAt(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if got := History(nil).ProfileAt(time.Now(), "fallback"); got != "fallback" {
+		t.Errorf("empty history: ProfileAt = %q, want fallback", got)
+	}
+
+	if err := RecordSwitch("home", "work"); err != nil {
+		t.Fatalf("RecordSwitch failed: %v", err)
+	}
+	switched := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	if err := RecordSwitch("work", "work"); err != nil {
+		t.Fatalf("RecordSwitch failed: %v", err)
+	}
+
+	h, err := LoadHistory()
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(h) != 1 {
+		t.Fatalf("history has %d entries, want 1 (a no-op switch is not recorded)", len(h))
+	}
+	if got := h.ProfileAt(switched.Add(-time.Hour), ""); got != "home" {
+		t.Errorf("before the switch: ProfileAt = %q, want home", got)
+	}
+	if got := h.ProfileAt(switched.Add(time.Hour), ""); got != "work" {
+		t.Errorf("after the switch: ProfileAt = %q, want work", got)
+	}
+}
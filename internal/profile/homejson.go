@@ -0,0 +1,136 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// ExtractKeys returns a JSON object containing only the given top-level keys
+// of data. Keys that are absent from data are omitted.
+func ExtractKeys(data []byte, keys []string) ([]byte, error) {
+	var src map[string]json.RawMessage
+	if err := json.Unmarshal(data, &src); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	out := make(map[string]json.RawMessage, len(keys))
+	for _, k := range keys {
+		if v, ok := src[k]; ok {
+			out[k] = v
+		}
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// MergeKeys overlays the given top-level keys from stored onto live and
+// returns the result. Every other key in live is preserved. A key in the set
+// that is missing from stored is removed from live, so the previous account's
+// identity does not leak into the restored one. live may be empty.
+func MergeKeys(live, stored []byte, keys []string) ([]byte, error) {
+	dst := make(map[string]json.RawMessage)
+	if len(live) > 0 {
+		if err := json.Unmarshal(live, &dst); err != nil {
+			return nil, fmt.Errorf("invalid JSON in live file: %w", err)
+		}
+	}
+
+	var src map[string]json.RawMessage
+	if err := json.Unmarshal(stored, &src); err != nil {
+		return nil, fmt.Errorf("invalid JSON in stored file: %w", err)
+	}
+
+	for _, k := range keys {
+		if v, ok := src[k]; ok {
+			dst[k] = v
+		} else {
+			delete(dst, k)
+		}
+	}
+	return json.MarshalIndent(dst, "", "  ")
+}
+
+// SaveHomeFiles captures the identity keys of each HomeCredentialFiles entry
+// found in srcDir and writes them to dstDir as home_<name>. It returns the
+// number of files saved.
+func SaveHomeFiles(srcDir, dstDir string, keys []string) (int, error) {
+	saved := 0
+	for _, fname := range HomeCredentialFiles {
+		src := filepath.Join(srcDir, fname)
+		if !FileExists(src) {
+			continue
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return saved, fmt.Errorf("cannot read %s: %w", fname, err)
+		}
+		subset, err := ExtractKeys(data, keys)
+		if err != nil {
+			return saved, fmt.Errorf("cannot parse %s: %w", fname, err)
+		}
+		if err := WriteSealed(filepath.Join(dstDir, "home_"+fname), subset); err != nil {
+			return saved, fmt.Errorf("cannot save %s: %w", fname, err)
+		}
+		saved++
+	}
+	return saved, nil
+}
+
+// RestoreHomeFiles merges the identity keys stored as home_<name> in srcDir
+// into the corresponding files in dstDir, leaving all other keys untouched.
+// It returns the number of files restored.
+func RestoreHomeFiles(srcDir, dstDir string, keys []string) (int, error) {
+	restored := 0
+	for _, fname := range HomeCredentialFiles {
+		src := filepath.Join(srcDir, "home_"+fname)
+		if !FileExists(src) {
+			continue
+		}
+		stored, err := ReadSealed(src)
+		if err != nil {
+			return restored, fmt.Errorf("cannot read stored %s: %w", fname, err)
+		}
+
+		dst := filepath.Join(dstDir, fname)
+		live, err := os.ReadFile(dst)
+		if err != nil && !os.IsNotExist(err) {
+			return restored, fmt.Errorf("cannot read %s: %w", dst, err)
+		}
+
+		merged, err := MergeKeys(live, stored, keys)
+		if err != nil {
+			return restored, fmt.Errorf("cannot merge %s: %w", fname, err)
+		}
+		if err := fsutil.WriteFile(dst, merged, 0600); err != nil {
+			return restored, fmt.Errorf("cannot restore %s: %w", fname, err)
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// stripAccountKeys removes the identity keys from each HomeCredentialFiles
+// entry in dir, leaving the rest of the file intact.
+func stripAccountKeys(dir string, keys []string) error {
+	for _, fname := range HomeCredentialFiles {
+		path := filepath.Join(dir, fname)
+		live, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", path, err)
+		}
+		stripped, err := MergeKeys(live, []byte("{}"), keys)
+		if err != nil {
+			return fmt.Errorf("cannot update %s: %w", fname, err)
+		}
+		if err := fsutil.WriteFile(path, stripped, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package profile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+func TestExtractKeys(t *testing.T) {
+	data := []byte(`{"oauthAccount": {"emailAddress": "a@example.com"}, "userID": "u1", "projects": {"/tmp": {}}}`)
+
+	out, err := ExtractKeys(data, []string{"oauthAccount", "userID", "primaryApiKey"})
+	if err != nil {
+		t.Fatalf("ExtractKeys failed: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output JSON: %v", err)
+	}
+	if _, ok := got["projects"]; ok {
+		t.Error("projects should not be extracted")
+	}
+	if got["userID"] != "u1" {
+		t.Errorf("expected userID 'u1', got %v", got["userID"])
+	}
+	if _, ok := got["primaryApiKey"]; ok {
+		t.Error("missing keys should not appear in output")
+	}
+}
+
+func TestMergeKeys(t *testing.T) {
+	live := []byte(`{"oauthAccount": {"emailAddress": "old@example.com"}, "primaryApiKey": "sk-old", "projects": {"/work": {"history": []}}, "mcpServers": {"x": {}}}`)
+	stored := []byte(`{"oauthAccount": {"emailAddress": "new@example.com"}}`)
+
+	out, err := MergeKeys(live, stored, []string{"oauthAccount", "primaryApiKey"})
+	if err != nil {
+		t.Fatalf("MergeKeys failed: %v", err)
+	}
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output JSON: %v", err)
+	}
+	var account struct {
+		EmailAddress string `json:"emailAddress"`
+	}
+	if err := json.Unmarshal(got["oauthAccount"], &account); err != nil || account.EmailAddress != "new@example.com" {
+		t.Errorf("oauthAccount not replaced: %s", got["oauthAccount"])
+	}
+	if _, ok := got["primaryApiKey"]; ok {
+		t.Error("identity key absent from stored data should be removed")
+	}
+	if _, ok := got["projects"]; !ok {
+		t.Error("projects should be preserved")
+	}
+	if _, ok := got["mcpServers"]; !ok {
+		t.Error("mcpServers should be preserved")
+	}
+}
+
+func TestMergeKeysEmptyLive(t *testing.T) {
+	out, err := MergeKeys(nil, []byte(`{"userID": "u1"}`), []string{"userID"})
+	if err != nil {
+		t.Fatalf("MergeKeys failed: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("invalid output JSON: %v", err)
+	}
+	if got["userID"] != "u1" {
+		t.Errorf("expected userID 'u1', got %v", got["userID"])
+	}
+}
+
+func TestUsePreservesClaudeJSONState(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	homePath := filepath.Join(tmpDir, ".claude.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(homePath, []byte(content), 0600); err != nil {
+			t.Fatalf("cannot write .claude.json: %v", err)
+		}
+	}
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+
+	write(`{"oauthAccount": {"emailAddress": "test@example.com"}, "projects": {"/old": {}}}`)
+	if err := mgr.Import("first", ""); err != nil {
+		t.Fatalf("Import first failed: %v", err)
+	}
+
+	profilesDir, _ := config.ProfilesDir()
+	stored, err := os.ReadFile(filepath.Join(profilesDir, "first", "home_.claude.json"))
+	if err != nil {
+		t.Fatalf("cannot read stored .claude.json: %v", err)
+	}
+	var storedKeys map[string]interface{}
+	if err := json.Unmarshal(stored, &storedKeys); err != nil {
+		t.Fatalf("invalid stored JSON: %v", err)
+	}
+	if _, ok := storedKeys["projects"]; ok {
+		t.Error("profile should only store identity keys")
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, ".claude", ".credentials.json"),
+		[]byte(`{"email": "other@example.com", "token": "other-token"}`), 0600); err != nil {
+		t.Fatalf("cannot write credentials: %v", err)
+	}
+	write(`{"oauthAccount": {"emailAddress": "other@example.com"}, "projects": {"/new": {}}, "hasCompletedOnboarding": true}`)
+	if err := mgr.Import("second", ""); err != nil {
+		t.Fatalf("Import second failed: %v", err)
+	}
+
+	if err := mgr.Use("first"); err != nil {
+		t.Fatalf("Use first failed: %v", err)
+	}
+
+	data, err := os.ReadFile(homePath)
+	if err != nil {
+		t.Fatalf("cannot read .claude.json: %v", err)
+	}
+	var live struct {
+		OAuthAccount struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"oauthAccount"`
+		Projects               map[string]interface{} `json:"projects"`
+		HasCompletedOnboarding bool                   `json:"hasCompletedOnboarding"`
+	}
+	if err := json.Unmarshal(data, &live); err != nil {
+		t.Fatalf("invalid live JSON: %v", err)
+	}
+	if live.OAuthAccount.EmailAddress != "test@example.com" {
+		t.Errorf("expected restored account 'test@example.com', got %q", live.OAuthAccount.EmailAddress)
+	}
+	if _, ok := live.Projects["/new"]; !ok {
+		t.Error("project history should not be rolled back")
+	}
+	if !live.HasCompletedOnboarding {
+		t.Error("onboarding state should be preserved")
+	}
+}
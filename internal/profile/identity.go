@@ -0,0 +1,59 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+)
+
+// accountID identifies the Claude account that owns a set of credentials.
+type accountID struct {
+	AccountUUID string
+	Email       string
+}
+
+// IsZero reports whether no identifying information was found.
+func (a accountID) IsZero() bool {
+	return a.AccountUUID == "" && a.Email == ""
+}
+
+// Matches reports whether two identities refer to the same account.
+// Unknown identities never match anything.
+func (a accountID) Matches(b accountID) bool {
+	if a.AccountUUID != "" && b.AccountUUID != "" {
+		return a.AccountUUID == b.AccountUUID
+	}
+	if a.Email != "" && b.Email != "" {
+		return strings.EqualFold(a.Email, b.Email)
+	}
+	return false
+}
+
+// accountIdentity extracts an account identity from the raw contents of a
+// credentials file and a ~/.claude.json file. Either argument may be nil.
+func accountIdentity(credData, homeData []byte) accountID {
+	creds, err := claude.ParseCredentials(credData, homeData)
+	if err != nil {
+		return accountID{}
+	}
+	return accountID{AccountUUID: creds.AccountUUID, Email: creds.Email}
+}
+
+// profileIdentity returns the account identity stored in a profile directory.
+func profileIdentity(profileDir string) accountID {
+	credData, _ := ReadSealed(filepath.Join(profileDir, ".credentials.json"))
+	homeData, _ := ReadSealed(filepath.Join(profileDir, "home_.claude.json"))
+	return accountIdentity(credData, homeData)
+}
+
+// liveIdentity returns the account identity of the live Claude session,
+// given the credentials already read from the credential store.
+func liveIdentity(credData []byte) accountID {
+	var homeData []byte
+	if home, err := os.UserHomeDir(); err == nil {
+		homeData, _ = os.ReadFile(filepath.Join(home, ".claude.json"))
+	}
+	return accountIdentity(credData, homeData)
+}
@@ -1,12 +1,14 @@
 package profile
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
 
+	"github.com/caeser1996/claude-switch/internal/claude"
 	"github.com/caeser1996/claude-switch/internal/config"
 )
 
@@ -43,7 +45,7 @@ func (m *Manager) ImportFromDir(name, description, srcDir string) error {
 			continue
 		}
 		dst := filepath.Join(profileDir, fname)
-		if err := CopyFile(src, dst); err != nil {
+		if err := SealFile(src, dst); err != nil {
 			return fmt.Errorf("cannot copy %s: %w", fname, err)
 		}
 		copied++
@@ -54,21 +56,12 @@ func (m *Manager) ImportFromDir(name, description, srcDir string) error {
 		return fmt.Errorf("no credential files found — authentication may not have completed")
 	}
 
-	email := extractEmailFromCredentials(filepath.Join(profileDir, ".credentials.json"))
-
-	isFirst := len(m.Config.Profiles) == 0
-	m.Config.Profiles[name] = config.ProfileEntry{
-		Name:        name,
-		Email:       email,
-		Description: description,
-		CreatedAt:   time.Now().UTC(),
-		IsActive:    isFirst,
-	}
-	if isFirst {
-		m.Config.ActiveProfile = name
+	// With CLAUDE_CONFIG_DIR set, Claude Code keeps .claude.json inside srcDir.
+	if _, err := SaveHomeFiles(srcDir, profileDir, m.Config.Settings.AccountKeys()); err != nil {
+		return err
 	}
 
-	return m.Config.Save()
+	return m.register(name, description, profileDir)
 }
 
 // ImportFromCredentialStore reads credentials from the platform's credential
@@ -101,26 +94,18 @@ func (m *Manager) ImportFromCredentialStore(name, description string) error {
 			}
 			src := filepath.Join(claudeDir, fname)
 			if FileExists(src) {
-				_ = CopyFile(src, filepath.Join(profileDir, fname))
+				_ = SealFile(src, filepath.Join(profileDir, fname))
 			}
 		}
 	}
 
-	email := extractEmailFromCredentials(filepath.Join(profileDir, ".credentials.json"))
-
-	isFirst := len(m.Config.Profiles) == 0
-	m.Config.Profiles[name] = config.ProfileEntry{
-		Name:        name,
-		Email:       email,
-		Description: description,
-		CreatedAt:   time.Now().UTC(),
-		IsActive:    isFirst,
-	}
-	if isFirst {
-		m.Config.ActiveProfile = name
+	if home, err := os.UserHomeDir(); err == nil {
+		if _, err := SaveHomeFiles(home, profileDir, m.Config.Settings.AccountKeys()); err != nil {
+			return err
+		}
 	}
 
-	return m.Config.Save()
+	return m.register(name, description, profileDir)
 }
 
 // Import saves the current Claude credentials as a named profile.
@@ -165,29 +150,23 @@ func (m *Manager) Import(name, description string) error {
 				continue
 			}
 			dst := filepath.Join(profileDir, fname)
-			if err := CopyFile(src, dst); err != nil {
+			if err := SealFile(src, dst); err != nil {
 				return fmt.Errorf("cannot copy %s: %w", fname, err)
 			}
 			copied++
 		}
 	}
 
-	// Copy home-level credential files
+	// Capture the account-identity keys of home-level files
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("cannot determine home directory: %w", err)
 	}
-	for _, fname := range HomeCredentialFiles {
-		src := filepath.Join(home, fname)
-		if !FileExists(src) {
-			continue
-		}
-		dst := filepath.Join(profileDir, "home_"+fname)
-		if err := CopyFile(src, dst); err != nil {
-			return fmt.Errorf("cannot copy %s: %w", fname, err)
-		}
-		copied++
+	saved, err := SaveHomeFiles(home, profileDir, m.Config.Settings.AccountKeys())
+	if err != nil {
+		return err
 	}
+	copied += saved
 
 	if keychainOK {
 		copied++
@@ -198,21 +177,7 @@ func (m *Manager) Import(name, description string) error {
 		return fmt.Errorf("no credential files found — is Claude Code installed and logged in?")
 	}
 
-	email := extractEmailFromCredentials(filepath.Join(profileDir, ".credentials.json"))
-
-	isFirst := len(m.Config.Profiles) == 0
-	m.Config.Profiles[name] = config.ProfileEntry{
-		Name:        name,
-		Email:       email,
-		Description: description,
-		CreatedAt:   time.Now().UTC(),
-		IsActive:    isFirst,
-	}
-	if isFirst {
-		m.Config.ActiveProfile = name
-	}
-
-	return m.Config.Save()
+	return m.register(name, description, profileDir)
 }
 
 // Use switches to the given profile.
@@ -230,6 +195,10 @@ func (m *Manager) Use(name string) error {
 		return fmt.Errorf("profile directory for %q is missing — try re-importing", name)
 	}
 
+	if m.Config.Profiles[name].IsEnv() {
+		return fmt.Errorf("profile %q holds environment variables and cannot be switched to globally — run it with 'cs exec %s' or a 'cs alias' shortcut", name, name)
+	}
+
 	// Auto-backup current credentials before switching
 	if m.Config.Settings.AutoBackup {
 		if err := m.createBackup(); err != nil {
@@ -237,52 +206,54 @@ func (m *Manager) Use(name string) error {
 		}
 	}
 
-	// Restore credentials to the platform store (Keychain on macOS, file on Linux).
-	if err := RestoreCredentialsFromProfile(profileDir); err != nil {
-		return fmt.Errorf("cannot restore credentials: %w", err)
+	// Claude Code rotates its OAuth tokens while running, so save the live
+	// credentials back into the outgoing profile before replacing them.
+	if err := m.persistLiveCredentials(); err != nil {
+		return err
 	}
 
-	claudeDir, err := config.ClaudeConfigDir()
+	// Read everything up front so a bad source can't leave a half switch.
+	staged, err := stageDir(profileDir, true)
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot read profile %q: %w", name, err)
 	}
 
-	// Restore supplementary credential files to Claude config dir
-	for _, fname := range CredentialFiles {
-		src := filepath.Join(profileDir, fname)
-		if !FileExists(src) {
-			continue
-		}
-		dst := filepath.Join(claudeDir, fname)
-		if err := CopyFile(src, dst); err != nil {
-			return fmt.Errorf("cannot restore %s: %w", fname, err)
+	// Apply credentials, ~/.claude files and identity keys as one unit.
+	action := fmt.Sprintf("switch to %q", name)
+	txn := NewTxn()
+	// Take a token refreshed in the profile's shell session over the
+	// stored one, whose refresh token it has rotated, and store it.
+	if creds := sessionCredentials(name, profileDir); creds != nil {
+		if err := txn.WriteSealed(filepath.Join(profileDir, ".credentials.json"), creds); err != nil {
+			return txn.Fail(action, err)
 		}
+		staged.creds = creds
 	}
-
-	// Restore home-level credential files
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("cannot determine home directory: %w", err)
-	}
-	for _, fname := range HomeCredentialFiles {
-		src := filepath.Join(profileDir, "home_"+fname)
-		if !FileExists(src) {
-			continue
-		}
-		dst := filepath.Join(home, fname)
-		if err := CopyFile(src, dst); err != nil {
-			return fmt.Errorf("cannot restore %s: %w", fname, err)
-		}
+	if err := staged.applyToLive(txn, m.Config.Settings.AccountKeys()); err != nil {
+		return txn.Fail(action, err)
 	}
 
 	// Update active profile in config
+	prevActive := m.Config.ActiveProfile
+	m.setActive(name)
+	if err := m.Config.Save(); err != nil {
+		m.setActive(prevActive)
+		return txn.Fail(action, err)
+	}
+
+	// The history attributes usage to profiles; losing an entry is not
+	// worth failing the switch.
+	_ = RecordSwitch(prevActive, name)
+	return nil
+}
+
+// setActive marks name as the active profile in the in-memory config.
+func (m *Manager) setActive(name string) {
 	for k, p := range m.Config.Profiles {
 		p.IsActive = (k == name)
 		m.Config.Profiles[k] = p
 	}
 	m.Config.ActiveProfile = name
-
-	return m.Config.Save()
 }
 
 // Remove deletes a profile.
@@ -304,7 +275,30 @@ func (m *Manager) Remove(name string) error {
 		return fmt.Errorf("cannot remove profile directory: %w", err)
 	}
 
+	// Shell sessions hold a plaintext copy of the credentials.
+	if sessionDir, err := SessionDir(name); err == nil {
+		_ = os.RemoveAll(sessionDir)
+	}
+
+	// A limit recorded for this profile must not carry over to a new
+	// profile of the same name.
+	if limits, err := claude.LoadLimits(); err == nil {
+		if _, ok := limits[name]; ok {
+			delete(limits, name)
+			_ = limits.Save()
+		}
+	}
+
 	delete(m.Config.Profiles, name)
+	for pool, members := range m.Config.Pools {
+		kept := members[:0]
+		for _, member := range members {
+			if member.Profile != name {
+				kept = append(kept, member)
+			}
+		}
+		m.Config.Pools[pool] = kept
+	}
 	return m.Config.Save()
 }
 
@@ -320,6 +314,54 @@ func (m *Manager) List() []config.ProfileEntry {
 	return profiles
 }
 
+// Tag adds and removes tags of a profile and saves the config. Tags are
+// kept sorted and follow the same naming rules as profiles.
+func (m *Manager) Tag(name string, add, remove []string) error {
+	entry, ok := m.Config.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	tags := make(map[string]bool, len(entry.Tags)+len(add))
+	for _, t := range entry.Tags {
+		tags[t] = true
+	}
+	for _, t := range add {
+		if err := validateProfileName(t); err != nil {
+			return fmt.Errorf("invalid tag %q: use only letters, numbers, hyphens, underscores", t)
+		}
+		tags[t] = true
+	}
+	for _, t := range remove {
+		delete(tags, t)
+	}
+
+	entry.Tags = nil
+	for t := range tags {
+		entry.Tags = append(entry.Tags, t)
+	}
+	sort.Strings(entry.Tags)
+
+	prev := m.Config.Profiles[name]
+	m.Config.Profiles[name] = entry
+	if err := m.Config.Save(); err != nil {
+		m.Config.Profiles[name] = prev
+		return err
+	}
+	return nil
+}
+
+// WithTag returns the profiles carrying tag, sorted by name.
+func (m *Manager) WithTag(tag string) []config.ProfileEntry {
+	var tagged []config.ProfileEntry
+	for _, p := range m.List() {
+		if p.HasTag(tag) {
+			tagged = append(tagged, p)
+		}
+	}
+	return tagged
+}
+
 // Current returns the active profile entry, or an error if none is set.
 func (m *Manager) Current() (*config.ProfileEntry, error) {
 	if m.Config.ActiveProfile == "" {
@@ -372,7 +414,7 @@ func (m *Manager) createBackup() error {
 				continue
 			}
 			dst := filepath.Join(backupDir, fname)
-			if err := CopyFile(src, dst); err != nil {
+			if err := SealFile(src, dst); err != nil {
 				continue
 			}
 			copied = true
@@ -381,15 +423,7 @@ func (m *Manager) createBackup() error {
 
 	home, err := os.UserHomeDir()
 	if err == nil {
-		for _, fname := range HomeCredentialFiles {
-			src := filepath.Join(home, fname)
-			if !FileExists(src) {
-				continue
-			}
-			dst := filepath.Join(backupDir, "home_"+fname)
-			if err := CopyFile(src, dst); err != nil {
-				continue
-			}
+		if saved, _ := SaveHomeFiles(home, backupDir, m.Config.Settings.AccountKeys()); saved > 0 {
 			copied = true
 		}
 	}
@@ -403,6 +437,42 @@ func (m *Manager) createBackup() error {
 	return nil
 }
 
+// persistLiveCredentials writes the live credentials back into the active
+// profile so refreshed tokens are not lost on the next switch. Nothing is
+// written unless the live session belongs to the same account as the profile.
+func (m *Manager) persistLiveCredentials() error {
+	active := m.Config.ActiveProfile
+	if active == "" {
+		return nil
+	}
+	if _, ok := m.Config.Profiles[active]; !ok {
+		return nil
+	}
+
+	profileDir, err := m.profileDir(active)
+	if err != nil || !DirExists(profileDir) {
+		return nil
+	}
+
+	live, err := ReadCurrentCredentials()
+	if err != nil || len(live) == 0 {
+		return nil
+	}
+
+	if !liveIdentity(live).Matches(profileIdentity(profileDir)) {
+		return nil
+	}
+
+	dst := filepath.Join(profileDir, ".credentials.json")
+	if stored, err := ReadSealed(dst); err == nil && bytes.Equal(stored, live) {
+		return nil
+	}
+	if err := WriteSealed(dst, live); err != nil {
+		return fmt.Errorf("cannot save refreshed credentials for %q: %w", active, err)
+	}
+	return nil
+}
+
 // pruneBackups keeps only the most recent N backups.
 func (m *Manager) pruneBackups(backupsDir string) {
 	entries, err := os.ReadDir(backupsDir)
@@ -439,26 +509,32 @@ func validateProfileName(name string) error {
 	return nil
 }
 
-// extractEmailFromCredentials tries to read an email from the credentials JSON.
-func extractEmailFromCredentials(path string) string {
-	// We do a best-effort extraction without pulling in a full JSON parser
-	// for the credential structure. If the credentials contain an email field,
-	// we'll find it.
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return ""
+// register records a freshly imported profile in the config, filling in
+// account metadata from the stored credentials. The first profile becomes active.
+func (m *Manager) register(name, description, profileDir string) error {
+	entry := config.ProfileEntry{
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now().UTC(),
 	}
-
-	// Simple JSON extraction: look for "email":"value"
-	type credShape struct {
-		Email string `json:"email"`
+	if creds, err := LoadProfileCredentials(profileDir); err == nil {
+		entry.Email = creds.Email
+		entry.Subscription = creds.PlanName()
+		entry.OrgName = creds.OrgName
 	}
 
-	// Try unmarshaling as a simple object
-	var cred credShape
-	if err := jsonUnmarshal(data, &cred); err == nil && cred.Email != "" {
-		return cred.Email
+	isFirst := len(m.Config.Profiles) == 0
+	entry.IsActive = isFirst
+	m.Config.Profiles[name] = entry
+	if isFirst {
+		m.Config.ActiveProfile = name
 	}
 
-	return ""
+	if err := m.Config.Save(); err != nil {
+		return err
+	}
+	if isFirst {
+		_ = RecordSwitch("", name)
+	}
+	return nil
 }
@@ -160,6 +160,56 @@ func TestUseProfile(t *testing.T) {
 	}
 }
 
+func TestUseWritesBackRefreshedCredentials(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+
+	if err := mgr.Import("first", ""); err != nil {
+		t.Fatalf("Import first failed: %v", err)
+	}
+
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	livePath := filepath.Join(claudeDir, ".credentials.json")
+	if err := os.WriteFile(livePath,
+		[]byte(`{"email": "other@example.com", "token": "other-token"}`), 0600); err != nil {
+		t.Fatalf("cannot write credentials: %v", err)
+	}
+	if err := mgr.Import("second", ""); err != nil {
+		t.Fatalf("Import second failed: %v", err)
+	}
+
+	// Live session belongs to "second" while "first" is active: no write-back.
+	if err := mgr.Use("second"); err != nil {
+		t.Fatalf("Use second failed: %v", err)
+	}
+	profilesDir, _ := config.ProfilesDir()
+	data, _ := os.ReadFile(filepath.Join(profilesDir, "first", ".credentials.json"))
+	if string(data) != `{"email": "test@example.com", "token": "mock-token-123"}` {
+		t.Errorf("profile 'first' should not receive another account's credentials, got %s", data)
+	}
+
+	// Simulate Claude Code refreshing the token for the active account.
+	refreshed := `{"email": "other@example.com", "token": "refreshed-token"}`
+	if err := os.WriteFile(livePath, []byte(refreshed), 0600); err != nil {
+		t.Fatalf("cannot write credentials: %v", err)
+	}
+
+	if err := mgr.Use("first"); err != nil {
+		t.Fatalf("Use first failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(profilesDir, "second", ".credentials.json"))
+	if err != nil {
+		t.Fatalf("cannot read profile credentials: %v", err)
+	}
+	if string(data) != refreshed {
+		t.Errorf("expected refreshed credentials in profile 'second', got %s", data)
+	}
+}
+
 func TestUseNonExistent(t *testing.T) {
 	_, cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -7,34 +7,28 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/caeser1996/claude-switch/internal/claude"
 	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
 )
 
-// SharedDirs are directories from the Claude config that should be symlinked
-// (not copied) during parallel execution, so commands/skills are shared.
-var SharedDirs = []string{
-	"commands",
-	"todos",
-}
-
-// SharedFiles are individual files to symlink during parallel exec.
-var SharedFiles = []string{
-	"settings.json",
-	"settings.local.json",
-}
-
 // IsolatedEnv represents a temporary isolated environment for running
 // claude with a specific profile's credentials.
 type IsolatedEnv struct {
 	ProfileName string
 	TempDir     string
 	OrigEnv     map[string]string
+	// Vars are the variables of an environment profile, injected by Env.
+	Vars map[string]string
+	// Persistent marks a session directory that Cleanup must keep.
+	Persistent bool
 }
 
 // SetupIsolatedEnv creates a temporary directory with the profile's credentials
 // and symlinks to shared resources (commands, skills, settings).
 func SetupIsolatedEnv(profileName string, cfg *config.Config) (*IsolatedEnv, error) {
-	if _, ok := cfg.Profiles[profileName]; !ok {
+	entry, ok := cfg.Profiles[profileName]
+	if !ok {
 		return nil, fmt.Errorf("profile %q not found", profileName)
 	}
 
@@ -48,74 +42,149 @@ func SetupIsolatedEnv(profileName string, cfg *config.Config) (*IsolatedEnv, err
 		return nil, fmt.Errorf("profile directory for %q is missing", profileName)
 	}
 
-	// Create temp dir
-	suffix := fmt.Sprintf("cs-%s-%08x", profileName, rand.Int31())
-	tmpDir, err := os.MkdirTemp("", suffix)
+	var vars map[string]string
+	if entry.IsEnv() {
+		if vars, err = LoadEnvVars(profileDir); err != nil {
+			return nil, fmt.Errorf("cannot load profile %q: %w", profileName, err)
+		}
+	}
+
+	// Create temp dir, marked so 'cs gc' can tell when it was left behind
+	tmpDir, err := CreateTempDir(fmt.Sprintf("cs-%s-%08x", profileName, rand.Int31()), profileName)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create temp directory: %w", err)
+		return nil, err
+	}
+
+	if err := populateConfigDir(tmpDir, profileDir, profileName, entry, cfg, false); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+
+	// A shell session may hold a token refreshed after the stored one; the
+	// run's write-back then saves it into the profile.
+	if creds := sessionCredentials(profileName, profileDir); creds != nil {
+		if err := fsutil.WriteFile(filepath.Join(tmpDir, ".credentials.json"), creds, 0600); err != nil {
+			os.RemoveAll(tmpDir)
+			return nil, fmt.Errorf("cannot copy session credentials: %w", err)
+		}
+	}
+
+	env := &IsolatedEnv{
+		ProfileName: profileName,
+		TempDir:     tmpDir,
+		OrigEnv:     make(map[string]string),
+		Vars:        vars,
 	}
 
-	// Copy credential files from profile to temp dir
+	return env, nil
+}
+
+// populateConfigDir fills dir with the profile's credentials, a .claude.json
+// carrying the profile's identity, and the resources of its resource policy.
+// With keepExisting (the persistent session directory), files already in
+// dir are kept, except credentials that are older than the stored ones.
+func populateConfigDir(dir, profileDir, profileName string, entry config.ProfileEntry, cfg *config.Config, keepExisting bool) error {
+	// Copy credential files from profile to dir
 	for _, fname := range CredentialFiles {
 		src := filepath.Join(profileDir, fname)
 		if !FileExists(src) {
 			continue
 		}
-		dst := filepath.Join(tmpDir, fname)
-		if err := CopyFile(src, dst); err != nil {
-			os.RemoveAll(tmpDir)
-			return nil, fmt.Errorf("cannot copy %s: %w", fname, err)
+		dst := filepath.Join(dir, fname)
+		if keepExisting && FileExists(dst) && !storedIsNewer(src, dst) {
+			continue
+		}
+		if err := UnsealFile(src, dst); err != nil {
+			return fmt.Errorf("cannot copy %s: %w", fname, err)
 		}
 	}
 
-	// Symlink shared directories from the real Claude config
-	claudeDir, err := config.ClaudeConfigDir()
-	if err == nil {
-		for _, dirName := range SharedDirs {
-			src := filepath.Join(claudeDir, dirName)
-			if DirExists(src) {
-				dst := filepath.Join(tmpDir, dirName)
-				_ = os.Symlink(src, dst) // best-effort
+	// Seed .claude.json from the real one (MCP servers, onboarding state)
+	// and overlay the profile's account-identity keys.
+	if home, err := os.UserHomeDir(); err == nil {
+		for _, fname := range HomeCredentialFiles {
+			src := filepath.Join(home, fname)
+			dst := filepath.Join(dir, fname)
+			if FileExists(src) && !(keepExisting && FileExists(dst)) {
+				if err := CopyFile(src, dst); err != nil {
+					return fmt.Errorf("cannot copy %s: %w", fname, err)
+				}
 			}
 		}
-
-		for _, fileName := range SharedFiles {
-			src := filepath.Join(claudeDir, fileName)
-			if FileExists(src) {
-				dst := filepath.Join(tmpDir, fileName)
-				_ = os.Symlink(src, dst) // best-effort
-			}
+	}
+	if entry.IsEnv() {
+		// No account login: drop the live account's identity so Claude Code
+		// authenticates with the profile's variables instead.
+		if err := stripAccountKeys(dir, cfg.Settings.AccountKeys()); err != nil {
+			return err
 		}
+	} else if _, err := RestoreHomeFiles(profileDir, dir, cfg.Settings.AccountKeys()); err != nil {
+		return err
 	}
 
-	env := &IsolatedEnv{
-		ProfileName: profileName,
-		TempDir:     tmpDir,
-		OrigEnv:     make(map[string]string),
-	}
+	// Share, copy or persist the rest of the Claude config per the
+	// resource policy.
+	return applyResources(dir, profileName, cfg, keepExisting)
+}
 
-	return env, nil
+// storedIsNewer reports whether the stored credentials at src should replace
+// the copy at dst: dst is unreadable, or src's token expires later.
+func storedIsNewer(src, dst string) bool {
+	if filepath.Base(src) != ".credentials.json" {
+		return false
+	}
+	current, err := os.ReadFile(dst)
+	if err != nil {
+		return true
+	}
+	stored, err := ReadSealed(src)
+	if err != nil {
+		return false
+	}
+	cur, err := claude.ParseCredentials(current, nil)
+	if err != nil || cur.ExpiresAt == nil {
+		return true
+	}
+	st, err := claude.ParseCredentials(stored, nil)
+	if err != nil || st.ExpiresAt == nil {
+		return false
+	}
+	return st.ExpiresAt.After(*cur.ExpiresAt)
 }
 
-// Env returns the environment variables to set for isolated execution.
+// Env returns the environment variables to set for isolated execution:
+// the current environment with CLAUDE_CONFIG_DIR pointing at the temp dir
+// and, for environment profiles, the profile's variables.
 func (e *IsolatedEnv) Env() []string {
 	result := os.Environ()
 
-	// Filter out existing CLAUDE_CONFIG_DIR if present, then add ours
-	filtered := make([]string, 0, len(result)+1)
+	// Filter out variables we set, then add ours
+	filtered := make([]string, 0, len(result)+len(e.Vars)+1)
 	for _, env := range result {
-		if !strings.HasPrefix(env, "CLAUDE_CONFIG_DIR=") {
-			filtered = append(filtered, env)
+		key, _, _ := strings.Cut(env, "=")
+		if _, overridden := e.Vars[key]; overridden || key == "CLAUDE_CONFIG_DIR" {
+			continue
 		}
+		filtered = append(filtered, env)
+	}
+	for _, key := range sortedKeys(e.Vars) {
+		filtered = append(filtered, key+"="+e.Vars[key])
 	}
 	filtered = append(filtered, "CLAUDE_CONFIG_DIR="+e.TempDir)
 
 	return filtered
 }
 
-// Cleanup removes the temporary directory.
+// UsageSource points claude.GetUsageInfo at the environment, whose
+// credentials may have been refreshed since it was set up.
+func (e *IsolatedEnv) UsageSource() claude.UsageSource {
+	return claude.DirUsageSource(e.ProfileName, e.TempDir)
+}
+
+// Cleanup removes the temporary directory. Persistent session directories
+// are left in place.
 func (e *IsolatedEnv) Cleanup() error {
-	if e.TempDir == "" {
+	if e.TempDir == "" || e.Persistent {
 		return nil
 	}
 	return os.RemoveAll(e.TempDir)
@@ -188,3 +188,83 @@ func TestIsolatedEnvSharedDirs(t *testing.T) {
 		t.Error("settings.json is not a symlink")
 	}
 }
+
+func TestIsolatedEnvResourcePolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+	tmpHome, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	claudeDir := filepath.Join(tmpHome, ".claude")
+	if err := os.MkdirAll(filepath.Join(claudeDir, "agents"), 0700); err != nil {
+		t.Fatalf("cannot create agents dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(claudeDir, "agents", "a.md"), []byte("agent"), 0600); err != nil {
+		t.Fatalf("cannot write a.md: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("policy", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	entry := cfg.Profiles["policy"]
+	entry.Resources = &config.ResourcePolicy{Copied: []string{"agents"}}
+	cfg.Profiles["policy"] = entry
+
+	env, err := SetupIsolatedEnv("policy", cfg)
+	if err != nil {
+		t.Fatalf("SetupIsolatedEnv failed: %v", err)
+	}
+
+	// Copied: a real directory with the content of ~/.claude/agents.
+	info, err := os.Lstat(filepath.Join(env.TempDir, "agents"))
+	if err != nil || info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		t.Fatalf("agents should be a copied directory (err %v)", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(env.TempDir, "agents", "a.md")); string(data) != "agent" {
+		t.Errorf("copied agents/a.md = %q", data)
+	}
+
+	// Persistent: projects outlives the environment.
+	transcript := filepath.Join(env.TempDir, "projects", "p", "t.jsonl")
+	if err := os.MkdirAll(filepath.Dir(transcript), 0700); err != nil {
+		t.Fatalf("cannot write into projects: %v", err)
+	}
+	if err := os.WriteFile(transcript, []byte("{}"), 0600); err != nil {
+		t.Fatalf("cannot write transcript: %v", err)
+	}
+	if err := env.Cleanup(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+
+	env, err = SetupIsolatedEnv("policy", cfg)
+	if err != nil {
+		t.Fatalf("SetupIsolatedEnv failed: %v", err)
+	}
+	defer func() { _ = env.Cleanup() }()
+	if !FileExists(filepath.Join(env.TempDir, "projects", "p", "t.jsonl")) {
+		t.Error("transcript in projects did not persist across environments")
+	}
+}
+
+func TestResourcePolicyRejectsManagedPaths(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("bad", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	for _, path := range []string{".credentials.json", "../escape", "/etc"} {
+		cfg.Settings.Resources = &config.ResourcePolicy{Shared: []string{path}}
+		env, err := SetupIsolatedEnv("bad", cfg)
+		if err == nil {
+			_ = env.Cleanup()
+			t.Errorf("resource path %q should be rejected", path)
+		}
+	}
+}
@@ -20,6 +20,14 @@ func DetectProjectProfile() string {
 
 // detectProjectProfileFrom walks up from the given directory.
 func detectProjectProfileFrom(startDir string) string {
+	name, _ := FindProjectProfile(startDir)
+	return name
+}
+
+// FindProjectProfile walks up from startDir and returns the profile named by
+// the nearest non-empty .claude-profile file, and that file's path. Both are
+// empty if there is none.
+func FindProjectProfile(startDir string) (name, path string) {
 	dir := startDir
 	for {
 		candidate := filepath.Join(dir, ProjectProfileFile)
@@ -28,7 +36,7 @@ func detectProjectProfileFrom(startDir string) string {
 			if err == nil {
 				name := strings.TrimSpace(string(data))
 				if name != "" {
-					return name
+					return name, candidate
 				}
 			}
 		}
@@ -39,7 +47,7 @@ func detectProjectProfileFrom(startDir string) string {
 		}
 		dir = parent
 	}
-	return ""
+	return "", ""
 }
 
 // WriteProjectProfile creates a .claude-profile file in the given directory.
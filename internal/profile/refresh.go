@@ -0,0 +1,102 @@
+package profile
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// RefreshResult describes the outcome of refreshing one profile's OAuth token.
+type RefreshResult struct {
+	ProfileName string
+	ExpiresAt   *time.Time
+	WroteLive   bool
+}
+
+// Refresh renews a profile's OAuth access token using its stored refresh
+// token (or its shell session's, when that one is newer), saves the new
+// credentials into the profile, and also updates the live credential store
+// when the profile is active.
+func (m *Manager) Refresh(name string) (RefreshResult, error) {
+	result := RefreshResult{ProfileName: name}
+
+	entry, ok := m.Config.Profiles[name]
+	if !ok {
+		return result, fmt.Errorf("profile %q not found", name)
+	}
+	if entry.IsEnv() {
+		return result, fmt.Errorf("profile %q holds environment variables; there is no token to refresh", name)
+	}
+
+	profileDir, err := m.profileDir(name)
+	if err != nil {
+		return result, err
+	}
+
+	active := m.Config.ActiveProfile == name
+	if active {
+		// Claude Code may have rotated the refresh token already; start from
+		// the freshest copy so we don't present a revoked one.
+		if err := m.persistLiveCredentials(); err != nil {
+			return result, err
+		}
+	}
+
+	credPath := filepath.Join(profileDir, ".credentials.json")
+	data, err := ReadSealed(credPath)
+	if err != nil {
+		return result, fmt.Errorf("cannot read profile credentials: %w", err)
+	}
+	// Likewise a shell session may hold a newer token than the profile.
+	fromSession := false
+	if creds := sessionCredentials(name, profileDir); creds != nil {
+		data, fromSession = creds, true
+	}
+
+	creds, err := claude.ParseCredentials(data, nil)
+	if err != nil {
+		return result, err
+	}
+	if creds.Kind != claude.KindOAuth || creds.RefreshToken == "" {
+		return result, fmt.Errorf("profile %q has no OAuth refresh token — use 'cs login %s'", name, name)
+	}
+
+	tok, err := claude.RefreshOAuthToken(m.Config.Settings.TokenURL(), m.Config.Settings.ClientID(), creds.RefreshToken)
+	if err != nil {
+		return result, err
+	}
+
+	updated, err := claude.ApplyTokenResponse(data, tok, time.Now())
+	if err != nil {
+		return result, err
+	}
+
+	if err := WriteSealed(credPath, updated); err != nil {
+		return result, fmt.Errorf("cannot save refreshed credentials: %w", err)
+	}
+
+	if fromSession {
+		// The session's refresh token was just rotated; keep the session
+		// working with the new one.
+		if dir, err := SessionDir(name); err == nil {
+			if err := fsutil.WriteFile(filepath.Join(dir, ".credentials.json"), updated, 0600); err != nil {
+				return result, fmt.Errorf("profile saved, but cannot update the shell session: %w", err)
+			}
+		}
+	}
+
+	if active {
+		if err := WriteCurrentCredentials(updated); err != nil {
+			return result, fmt.Errorf("profile saved, but cannot update live credentials: %w", err)
+		}
+		result.WroteLive = true
+	}
+
+	if refreshed, err := claude.ParseCredentials(updated, nil); err == nil {
+		result.ExpiresAt = refreshed.ExpiresAt
+	}
+	return result, nil
+}
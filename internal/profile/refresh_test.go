@@ -0,0 +1,91 @@
+package profile
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+func TestRefreshProfile(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	var gotRefresh string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotRefresh = req["refresh_token"]
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "new-access",
+			"refresh_token": "new-refresh",
+			"expires_in":    3600,
+		})
+	}))
+	defer srv.Close()
+
+	livePath := filepath.Join(tmpDir, ".claude", ".credentials.json")
+	old := `{"claudeAiOauth": {"accessToken": "old-access", "refreshToken": "old-refresh", "expiresAt": 1000, "subscriptionType": "pro"}}`
+	if err := os.WriteFile(livePath, []byte(old), 0600); err != nil {
+		t.Fatalf("cannot write credentials: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	cfg.Settings.OAuthTokenURL = srv.URL
+	mgr := NewManager(cfg)
+	if err := mgr.Import("work", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if !NeedsRefresh(CheckTokenStatus("work"), 0) {
+		t.Fatal("expired profile should need refresh")
+	}
+
+	result, err := mgr.Refresh("work")
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if gotRefresh != "old-refresh" {
+		t.Errorf("expected server to receive 'old-refresh', got %q", gotRefresh)
+	}
+	if !result.WroteLive {
+		t.Error("active profile refresh should update the live store")
+	}
+
+	profilesDir, _ := config.ProfilesDir()
+	for _, path := range []string{filepath.Join(profilesDir, "work", ".credentials.json"), livePath} {
+		creds, err := claude.LoadCredentials(path, "")
+		if err != nil {
+			t.Fatalf("cannot load %s: %v", path, err)
+		}
+		if creds.AccessToken != "new-access" || creds.RefreshToken != "new-refresh" {
+			t.Errorf("%s not updated: %+v", path, creds)
+		}
+		if creds.SubscriptionType != "pro" {
+			t.Errorf("%s lost unrelated fields: %+v", path, creds)
+		}
+		if creds.IsExpired() {
+			t.Errorf("%s should hold an unexpired token", path)
+		}
+	}
+}
+
+func TestRefreshProfileWithoutRefreshToken(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("legacy", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if _, err := mgr.Refresh("legacy"); err == nil {
+		t.Error("expected error for profile without an OAuth refresh token")
+	}
+}
@@ -0,0 +1,146 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+// applyResources makes the paths of ~/.claude named by the profile's
+// resource policy available in dir: shared paths as symlinks, copied paths
+// as copies, and persistent paths as symlinks into the profile's session
+// directory. When dir is the session directory itself (inSession),
+// persistent paths simply stay where they are.
+func applyResources(dir, profileName string, cfg *config.Config, inSession bool) error {
+	claudeDir, err := config.ClaudeConfigDir()
+	if err != nil {
+		return err
+	}
+	sessionDir, err := SessionDir(profileName)
+	if err != nil {
+		return err
+	}
+
+	modes := cfg.ResourceModes(profileName)
+	paths := make([]string, 0, len(modes))
+	for path := range modes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := validateResourcePath(path); err != nil {
+			return err
+		}
+		mode := modes[path]
+		src := filepath.Join(claudeDir, path)
+		dst := filepath.Join(dir, path)
+
+		if info, err := os.Lstat(dst); err == nil {
+			// A session directory keeps what it has, except a symlink left
+			// from when the path was shared.
+			if mode == config.ResourceShared || info.Mode()&os.ModeSymlink == 0 {
+				continue
+			}
+			if err := os.Remove(dst); err != nil {
+				return fmt.Errorf("cannot replace %s: %w", dst, err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return err
+		}
+
+		switch mode {
+		case config.ResourceShared:
+			if pathExists(src) {
+				_ = os.Symlink(src, dst) // best-effort
+			}
+		case config.ResourceCopied:
+			if pathExists(src) {
+				if err := copyTree(src, dst); err != nil {
+					return fmt.Errorf("cannot copy %s: %w", path, err)
+				}
+			}
+		case config.ResourcePersistent:
+			if inSession {
+				continue
+			}
+			target := filepath.Join(sessionDir, path)
+			// Paths without an extension are taken to be directories; a
+			// link to a missing file is fine, Claude Code creates it.
+			mkdir := target
+			if filepath.Ext(path) != "" {
+				mkdir = filepath.Dir(target)
+			}
+			if err := os.MkdirAll(mkdir, 0700); err != nil {
+				return fmt.Errorf("cannot create %s: %w", mkdir, err)
+			}
+			_ = os.Symlink(target, dst) // best-effort
+		}
+	}
+	return nil
+}
+
+// validateResourcePath rejects policy paths that escape the config
+// directory or would replace the files cs manages itself.
+func validateResourcePath(path string) error {
+	clean := filepath.Clean(path)
+	if path == "" || filepath.IsAbs(path) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid resource path %q in config: must be relative to ~/.claude", path)
+	}
+	for _, managed := range append(append([]string{OwnerFile}, CredentialFiles...), HomeCredentialFiles...) {
+		if clean == managed {
+			return fmt.Errorf("invalid resource path %q in config: managed by cs", path)
+		}
+	}
+	return nil
+}
+
+// copyTree copies a file, or a directory recursively, keeping permissions.
+// Symlinks are recreated rather than followed.
+func copyTree(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()|0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := copyTree(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	case info.Mode().IsRegular():
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, info.Mode().Perm())
+	default:
+		return nil // sockets, devices: skip
+	}
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
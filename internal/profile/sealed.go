@@ -0,0 +1,242 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+	"github.com/caeser1996/claude-switch/internal/vault"
+)
+
+var (
+	vaultMu       sync.Mutex
+	vaultSettings config.VaultSettings
+)
+
+// ConfigureVault records the vault settings used to seal files written to
+// the profile store. It should be called once the config is loaded.
+func ConfigureVault(settings config.VaultSettings) {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	vaultSettings = settings
+}
+
+func currentVault() config.VaultSettings {
+	vaultMu.Lock()
+	defer vaultMu.Unlock()
+	return vaultSettings
+}
+
+// ReadSealed reads a file from the profile store, decrypting it if it was
+// sealed by the vault. Plaintext files are returned unchanged.
+func ReadSealed(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !vault.IsSealed(data) {
+		return data, nil
+	}
+
+	key, err := vault.Key(currentVault())
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s: %w", filepath.Base(path), err)
+	}
+	plain, err := vault.Open(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s: %w", filepath.Base(path), err)
+	}
+	return plain, nil
+}
+
+// WriteSealed writes a file into the profile store, encrypting it when the
+// vault is enabled.
+func WriteSealed(path string, data []byte) error {
+	sealed, err := sealData(path, data)
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(path, sealed, 0600)
+}
+
+// sealData encrypts data destined for path when the vault is enabled.
+func sealData(path string, data []byte) ([]byte, error) {
+	settings := currentVault()
+	if !settings.Enabled {
+		return data, nil
+	}
+	key, err := vault.Key(settings)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encrypt %s: %w", filepath.Base(path), err)
+	}
+	sealed, err := vault.Seal(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encrypt %s: %w", filepath.Base(path), err)
+	}
+	return sealed, nil
+}
+
+// IsSealedFile reports whether the file at path is encrypted by the vault.
+func IsSealedFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && vault.IsSealed(data)
+}
+
+// SealFile copies a live file into the profile store, sealing it if the
+// vault is enabled.
+func SealFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", src, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return fmt.Errorf("cannot create directory for %s: %w", dst, err)
+	}
+	return WriteSealed(dst, data)
+}
+
+// UnsealFile copies a file out of the profile store, decrypting it if needed.
+func UnsealFile(src, dst string) error {
+	data, err := ReadSealed(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return fmt.Errorf("cannot create directory for %s: %w", dst, err)
+	}
+	return fsutil.WriteFile(dst, data, 0600)
+}
+
+// StoredFiles returns every credential file kept in profile and backup
+// directories.
+func StoredFiles() ([]string, error) {
+	var files []string
+	for _, dirFn := range []func() (string, error){config.ProfilesDir, config.BackupsDir} {
+		base, err := dirFn()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := os.ReadDir(base)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			dir := filepath.Join(base, e.Name())
+			for _, fname := range storedFileNames() {
+				if path := filepath.Join(dir, fname); FileExists(path) {
+					files = append(files, path)
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// CountSealed reports how many stored credential files are sealed and how
+// many are plaintext.
+func CountSealed() (sealed, plain int, err error) {
+	files, err := StoredFiles()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return sealed, plain, err
+		}
+		if vault.IsSealed(data) {
+			sealed++
+		} else {
+			plain++
+		}
+	}
+	return sealed, plain, nil
+}
+
+// ResealStore re-encrypts every stored credential file. Sealed files are
+// opened with oldKey; each file is then sealed with newKey, or written as
+// plaintext when newKey is nil. It returns the number of files rewritten.
+// On error the files already rewritten are restored, so the whole store
+// stays readable with oldKey.
+func ResealStore(oldKey, newKey []byte) (int, error) {
+	files, err := StoredFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	type rewrite struct {
+		path string
+		prev []byte
+	}
+	var done []rewrite
+	fail := func(cause error) (int, error) {
+		for i := len(done) - 1; i >= 0; i-- {
+			if err := fsutil.WriteFile(done[i].path, done[i].prev, 0600); err != nil {
+				return len(done), fmt.Errorf("%w; rollback was incomplete, cannot restore %s: %v", cause, done[i].path, err)
+			}
+		}
+		return 0, fmt.Errorf("%w (no file was changed)", cause)
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fail(fmt.Errorf("cannot read %s: %w", path, err))
+		}
+
+		plain := data
+		if vault.IsSealed(data) {
+			if oldKey == nil {
+				return fail(fmt.Errorf("%s is encrypted but no vault key was given", path))
+			}
+			if plain, err = vault.Open(data, oldKey); err != nil {
+				return fail(fmt.Errorf("cannot decrypt %s: %w", path, err))
+			}
+		}
+
+		out := plain
+		if newKey != nil {
+			if out, err = vault.Seal(plain, newKey); err != nil {
+				return fail(fmt.Errorf("cannot encrypt %s: %w", path, err))
+			}
+		}
+		if bytes.Equal(out, data) {
+			continue
+		}
+
+		if err := fsutil.WriteFile(path, out, 0600); err != nil {
+			return fail(err)
+		}
+		done = append(done, rewrite{path, data})
+	}
+	return len(done), nil
+}
+
+// storedFileNames lists the file names kept inside a profile directory.
+func storedFileNames() []string {
+	names := append([]string{}, CredentialFiles...)
+	for _, f := range HomeCredentialFiles {
+		names = append(names, "home_"+f)
+	}
+	return append(names, EnvFile)
+}
+
+// isStoredFileName reports whether name is one of storedFileNames.
+func isStoredFileName(name string) bool {
+	for _, n := range storedFileNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/vault"
+)
+
+// enableTestVault turns on the vault with a passphrase supplied via the environment.
+func enableTestVault(t *testing.T, cfg *config.Config) []byte {
+	t.Helper()
+	t.Setenv(vault.PassphraseEnvVar, "test-vault-pass")
+
+	settings, key, err := vault.Create("test-vault-pass")
+	if err != nil {
+		t.Fatalf("vault.Create() error: %v", err)
+	}
+	cfg.Settings.Vault = settings
+	ConfigureVault(settings)
+	t.Cleanup(func() {
+		ConfigureVault(config.VaultSettings{})
+		vault.Forget()
+	})
+	return key
+}
+
+func TestVaultSealsImportAndUnsealsOnUse(t *testing.T) {
+	home, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	enableTestVault(t, cfg)
+	mgr := NewManager(cfg)
+
+	if err := mgr.Import("work", ""); err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+
+	profilesDir, _ := config.ProfilesDir()
+	stored, err := os.ReadFile(filepath.Join(profilesDir, "work", ".credentials.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vault.IsSealed(stored) || strings.Contains(string(stored), "mock-token-123") {
+		t.Fatal("stored credentials should be encrypted")
+	}
+	if cfg.Profiles["work"].Email != "test@example.com" {
+		t.Errorf("email = %q, want it read through the vault", cfg.Profiles["work"].Email)
+	}
+
+	// Replace the live session, then switch back to the sealed profile.
+	credPath := filepath.Join(home, ".claude", ".credentials.json")
+	if err := os.WriteFile(credPath, []byte(`{"email": "other@example.com", "token": "other"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("Use() error: %v", err)
+	}
+
+	live, _ := os.ReadFile(credPath)
+	if !strings.Contains(string(live), "mock-token-123") {
+		t.Errorf("live credentials = %s, want decrypted profile", live)
+	}
+}
+
+func TestResealStore(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("plain", ""); err != nil {
+		t.Fatalf("Import() error: %v", err)
+	}
+
+	key := enableTestVault(t, cfg)
+
+	n, err := ResealStore(nil, key)
+	if err != nil {
+		t.Fatalf("ResealStore(seal) error: %v", err)
+	}
+	if n == 0 {
+		t.Fatal("ResealStore(seal) rewrote no files")
+	}
+	if sealed, plain, _ := CountSealed(); sealed != n || plain != 0 {
+		t.Errorf("CountSealed() = %d sealed, %d plain; want %d, 0", sealed, plain, n)
+	}
+
+	creds, err := LoadProfileCredentials(filepath.Join(mustProfilesDir(t), "plain"))
+	if err != nil || creds.Email != "test@example.com" {
+		t.Fatalf("LoadProfileCredentials() = %+v, %v", creds, err)
+	}
+
+	if _, err := ResealStore(nil, nil); err == nil {
+		t.Error("ResealStore() without the old key should fail on sealed files")
+	}
+
+	if _, err := ResealStore(key, nil); err != nil {
+		t.Fatalf("ResealStore(unseal) error: %v", err)
+	}
+	if sealed, _, _ := CountSealed(); sealed != 0 {
+		t.Errorf("%d file(s) still sealed after unsealing", sealed)
+	}
+}
+
+func TestResealStoreRollsBackOnFailure(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	for _, name := range []string{"a", "b", "c"} {
+		if err := mgr.Import(name, ""); err != nil {
+			t.Fatalf("Import(%s) error: %v", name, err)
+		}
+	}
+	oldKey := enableTestVault(t, cfg)
+	if _, err := ResealStore(nil, oldKey); err != nil {
+		t.Fatalf("ResealStore(seal) error: %v", err)
+	}
+
+	// Seal b's credentials under a foreign key: rekeying fails there, after
+	// the files of a have been rewritten.
+	_, foreignKey, err := vault.Create("foreign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken, err := vault.Seal([]byte(`{"email":"b@example.com"}`), foreignKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mustProfilesDir(t), "b", ".credentials.json"), broken, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := StoredFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := map[string]string{}
+	for _, path := range files {
+		data, _ := os.ReadFile(path)
+		before[path] = string(data)
+	}
+
+	_, newKey, err := vault.Create("new-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := ResealStore(oldKey, newKey)
+	if err == nil {
+		t.Fatal("ResealStore() should fail on a file sealed under another key")
+	}
+	if n != 0 {
+		t.Errorf("ResealStore() = %d after rollback, want 0", n)
+	}
+	for path, want := range before {
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("%s was left rewritten", path)
+		}
+	}
+	if _, err := LoadProfileCredentials(filepath.Join(mustProfilesDir(t), "a")); err != nil {
+		t.Errorf("profile a is unreadable with the old key: %v", err)
+	}
+}
+
+func mustProfilesDir(t *testing.T) string {
+	t.Helper()
+	dir, err := config.ProfilesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
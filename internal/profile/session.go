@@ -0,0 +1,98 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+// SessionDir returns the persistent config directory for shell sessions of
+// the named profile.
+func SessionDir(name string) (string, error) {
+	sessionsDir, err := config.SessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(sessionsDir, name), nil
+}
+
+// SetupSessionEnv prepares the persistent config directory of a profile for
+// use as CLAUDE_CONFIG_DIR in a shell. Unlike SetupIsolatedEnv the directory
+// survives between runs, so Claude Code's history and refreshed tokens for
+// the session are kept; stored credentials only replace the session's copy
+// when they are newer.
+func SetupSessionEnv(profileName string, cfg *config.Config) (*IsolatedEnv, error) {
+	entry, ok := cfg.Profiles[profileName]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found", profileName)
+	}
+
+	profilesDir, err := config.ProfilesDir()
+	if err != nil {
+		return nil, err
+	}
+	profileDir := filepath.Join(profilesDir, profileName)
+
+	if !DirExists(profileDir) {
+		return nil, fmt.Errorf("profile directory for %q is missing", profileName)
+	}
+
+	var vars map[string]string
+	if entry.IsEnv() {
+		if vars, err = LoadEnvVars(profileDir); err != nil {
+			return nil, fmt.Errorf("cannot load profile %q: %w", profileName, err)
+		}
+	}
+
+	dir, err := SessionDir(profileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create session directory: %w", err)
+	}
+
+	if err := populateConfigDir(dir, profileDir, profileName, entry, cfg, true); err != nil {
+		return nil, err
+	}
+
+	return &IsolatedEnv{
+		ProfileName: profileName,
+		TempDir:     dir,
+		OrigEnv:     make(map[string]string),
+		Vars:        vars,
+		Persistent:  true,
+	}, nil
+}
+
+// sessionCredentials returns the credentials in the profile's shell session
+// when Claude Code refreshed them there after they were stored, and nil
+// otherwise. The refresh rotated the stored refresh token, so whatever
+// starts from the profile next must take the session's copy instead.
+func sessionCredentials(profileName, profileDir string) []byte {
+	dir, err := SessionDir(profileName)
+	if err != nil {
+		return nil
+	}
+	sessionPath := filepath.Join(dir, ".credentials.json")
+	storedPath := filepath.Join(profileDir, ".credentials.json")
+
+	data, err := os.ReadFile(sessionPath)
+	if err != nil || len(data) == 0 || storedIsNewer(storedPath, sessionPath) {
+		return nil
+	}
+	if stored, err := ReadSealed(storedPath); err != nil || bytes.Equal(stored, data) {
+		return nil
+	}
+
+	home, _ := os.ReadFile(filepath.Join(dir, ".claude.json"))
+	sessionID := accountIdentity(data, home)
+	storedID := profileIdentity(profileDir)
+	if !sessionID.IsZero() && !storedID.IsZero() && !sessionID.Matches(storedID) {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,161 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+func TestSetupSessionEnvIsPersistent(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("work", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	env, err := SetupSessionEnv("work", cfg)
+	if err != nil {
+		t.Fatalf("SetupSessionEnv failed: %v", err)
+	}
+	want, _ := SessionDir("work")
+	if env.TempDir != want {
+		t.Errorf("session dir = %s, want %s", env.TempDir, want)
+	}
+
+	// Claude Code refreshes the session's token: a later expiry must survive
+	// the next setup, and Cleanup must not remove the directory.
+	credPath := filepath.Join(env.TempDir, ".credentials.json")
+	refreshed := `{"claudeAiOauth":{"accessToken":"new","expiresAt":4102444800000}}`
+	if err := os.WriteFile(credPath, []byte(refreshed), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Cleanup(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+
+	if _, err := SetupSessionEnv("work", cfg); err != nil {
+		t.Fatalf("second SetupSessionEnv failed: %v", err)
+	}
+	data, err := os.ReadFile(credPath)
+	if err != nil {
+		t.Fatalf("session credentials missing: %v", err)
+	}
+	if !strings.Contains(string(data), `"new"`) {
+		t.Errorf("newer session credentials were overwritten: %s", data)
+	}
+}
+
+func TestRemoveDeletesSessionDir(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	for _, name := range []string{"a", "b"} {
+		if err := mgr.Import(name, ""); err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+	}
+	env, err := SetupSessionEnv("b", cfg)
+	if err != nil {
+		t.Fatalf("SetupSessionEnv failed: %v", err)
+	}
+
+	if err := mgr.Remove("b"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if DirExists(env.TempDir) {
+		t.Error("session directory should be removed with the profile")
+	}
+}
+
+func TestSessionRefreshedCredentialsAreTakenOver(t *testing.T) {
+	home, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	livePath := filepath.Join(home, ".claude", ".credentials.json")
+	writeLive := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(livePath, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	writeLive(oauthCreds("old", "a@example.com", 2000000000000))
+	if err := mgr.Import("work", ""); err != nil {
+		t.Fatalf("Import work failed: %v", err)
+	}
+	writeLive(oauthCreds("other", "b@example.com", 2000000000000))
+	if err := mgr.Import("other", ""); err != nil {
+		t.Fatalf("Import other failed: %v", err)
+	}
+	if err := mgr.Use("other"); err != nil {
+		t.Fatalf("Use other failed: %v", err)
+	}
+
+	// Claude Code rotates the token inside a 'cs shell' session.
+	env, err := SetupSessionEnv("work", cfg)
+	if err != nil {
+		t.Fatalf("SetupSessionEnv failed: %v", err)
+	}
+	rotated := oauthCreds("rotated", "a@example.com", 2100000000000)
+	if err := os.WriteFile(filepath.Join(env.TempDir, ".credentials.json"), []byte(rotated), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	isolated, err := SetupIsolatedEnv("work", cfg)
+	if err != nil {
+		t.Fatalf("SetupIsolatedEnv failed: %v", err)
+	}
+	defer isolated.Cleanup()
+	if data, _ := os.ReadFile(filepath.Join(isolated.TempDir, ".credentials.json")); string(data) != rotated {
+		t.Errorf("isolated run got %s, want the session's rotated token", data)
+	}
+
+	if err := mgr.Use("work"); err != nil {
+		t.Fatalf("Use work failed: %v", err)
+	}
+	if data, _ := os.ReadFile(livePath); string(data) != rotated {
+		t.Errorf("live credentials = %s, want the session's rotated token", data)
+	}
+	profilesDir, _ := config.ProfilesDir()
+	if data, _ := ReadSealed(filepath.Join(profilesDir, "work", ".credentials.json")); string(data) != rotated {
+		t.Errorf("stored credentials = %s, want the session's rotated token", data)
+	}
+}
+
+func TestSessionCredentialsOfAnotherAccountAreIgnored(t *testing.T) {
+	home, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	live := oauthCreds("old", "a@example.com", 2000000000000)
+	if err := os.WriteFile(filepath.Join(home, ".claude", ".credentials.json"), []byte(live), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.NewConfig()
+	if err := NewManager(cfg).Import("work", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	env, err := SetupSessionEnv("work", cfg)
+	if err != nil {
+		t.Fatalf("SetupSessionEnv failed: %v", err)
+	}
+	// The session was logged into a different account.
+	foreign := oauthCreds("foreign", "b@example.com", 2100000000000)
+	if err := os.WriteFile(filepath.Join(env.TempDir, ".credentials.json"), []byte(foreign), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	profilesDir, _ := config.ProfilesDir()
+	if creds := sessionCredentials("work", filepath.Join(profilesDir, "work")); creds != nil {
+		t.Errorf("took another account's session credentials: %s", creds)
+	}
+}
@@ -0,0 +1,256 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// StoreEnvVar overrides the configured credential store backend.
+const StoreEnvVar = "CS_CREDENTIAL_STORE"
+
+// CredentialStore is where Claude Code keeps its live credentials.
+type CredentialStore interface {
+	// Read returns the stored credentials.
+	Read() ([]byte, error)
+	// Write replaces the stored credentials.
+	Write(data []byte) error
+	// Delete removes the stored credentials. Deleting missing credentials is not an error.
+	Delete() error
+	// Describe returns a short human-readable description of the backend.
+	Describe() string
+}
+
+// StoreFactory creates a credential store from the application settings.
+type StoreFactory func(settings config.Settings) (CredentialStore, error)
+
+var (
+	storeMu        sync.Mutex
+	storeFactories = map[string]StoreFactory{}
+	storeSettings  = config.DefaultSettings()
+	storeOverride  CredentialStore
+)
+
+func init() {
+	RegisterStore("file", func(config.Settings) (CredentialStore, error) { return fileStore{}, nil })
+	RegisterStore("keychain", newKeychainStore)
+	RegisterStore("pass", newPassStore)
+	RegisterStore("helper", newHelperStore)
+}
+
+// RegisterStore makes a credential store backend available under name.
+func RegisterStore(name string, factory StoreFactory) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	storeFactories[name] = factory
+}
+
+// StoreNames returns the names of all registered backends, sorted.
+func StoreNames() []string {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	names := make([]string, 0, len(storeFactories))
+	for name := range storeFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConfigureCredentialStore records the settings used to select and build
+// the credential store. It should be called once the config is loaded.
+func ConfigureCredentialStore(settings config.Settings) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	storeSettings = settings
+}
+
+// SetCredentialStore forces a specific store instance, bypassing selection.
+// Passing nil restores normal selection. Intended for tests.
+func SetCredentialStore(s CredentialStore) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	storeOverride = s
+}
+
+// CurrentStore returns the credential store selected by CS_CREDENTIAL_STORE,
+// settings.credential_store, or the platform default (Keychain on macOS,
+// file elsewhere).
+func CurrentStore() (CredentialStore, error) {
+	storeMu.Lock()
+	override, settings := storeOverride, storeSettings
+	storeMu.Unlock()
+
+	if override != nil {
+		return override, nil
+	}
+	return OpenStore(SelectedStoreName(settings), settings)
+}
+
+// SelectedStoreName returns the backend name that CurrentStore would use.
+func SelectedStoreName(settings config.Settings) string {
+	name := os.Getenv(StoreEnvVar)
+	if name == "" {
+		name = settings.CredentialStore
+	}
+	if name == "" || name == "auto" {
+		if runtime.GOOS == "darwin" {
+			return "keychain"
+		}
+		return "file"
+	}
+	return name
+}
+
+// OpenStore builds the named credential store backend.
+func OpenStore(name string, settings config.Settings) (CredentialStore, error) {
+	storeMu.Lock()
+	factory, ok := storeFactories[name]
+	storeMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown credential store %q (available: %v)", name, StoreNames())
+	}
+	return factory(settings)
+}
+
+// ReadCurrentCredentials reads the active Claude Code credentials from the
+// selected credential store.
+func ReadCurrentCredentials() ([]byte, error) {
+	store, err := CurrentStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Read()
+}
+
+// WriteCurrentCredentials writes credentials to the selected credential store.
+func WriteCurrentCredentials(data []byte) error {
+	store, err := CurrentStore()
+	if err != nil {
+		return err
+	}
+	return store.Write(data)
+}
+
+// SaveCredentialsToProfile reads the current credentials and saves them
+// to the given profile directory as .credentials.json, sealed by the vault
+// when it is enabled.
+func SaveCredentialsToProfile(profileDir string) error {
+	data, err := ReadCurrentCredentials()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("credentials are empty")
+	}
+	return WriteSealed(filepath.Join(profileDir, ".credentials.json"), data)
+}
+
+// RestoreCredentialsFromProfile reads .credentials.json from a profile
+// directory and writes it to the active credential store.
+func RestoreCredentialsFromProfile(profileDir string) error {
+	src := filepath.Join(profileDir, ".credentials.json")
+	data, err := ReadSealed(src)
+	if err != nil {
+		return fmt.Errorf("cannot read profile credentials: %w", err)
+	}
+	return WriteCurrentCredentials(data)
+}
+
+// fileStore keeps credentials in ~/.claude/.credentials.json.
+type fileStore struct{}
+
+func (fileStore) path() (string, error) {
+	return config.ClaudeCredentialsPath()
+}
+
+func (s fileStore) Read() ([]byte, error) {
+	path, err := s.path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+func (s fileStore) Write(data []byte) error {
+	path, err := s.path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("cannot create %s: %w", filepath.Dir(path), err)
+	}
+	if err := fsutil.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s fileStore) Delete() error {
+	path, err := s.path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove %s: %w", path, err)
+	}
+	return nil
+}
+
+func (s fileStore) Describe() string {
+	path, err := s.path()
+	if err != nil {
+		return "file"
+	}
+	return "file (" + path + ")"
+}
+
+// MemoryStore is an in-process credential store for tests, installed with
+// SetCredentialStore. It is not a selectable backend: nothing it holds
+// outlives the process.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+// NewMemoryStore returns a MemoryStore holding the given credentials.
+func NewMemoryStore(initial []byte) *MemoryStore {
+	return &MemoryStore{data: append([]byte(nil), initial...)}
+}
+
+func (s *MemoryStore) Read() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.data) == 0 {
+		return nil, fmt.Errorf("no credentials in memory store")
+	}
+	return append([]byte(nil), s.data...), nil
+}
+
+func (s *MemoryStore) Write(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStore) Delete() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = nil
+	return nil
+}
+
+func (s *MemoryStore) Describe() string {
+	return "memory"
+}
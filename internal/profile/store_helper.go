@@ -0,0 +1,73 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+// helperStore delegates to an external program, in the style of git
+// credential helpers. The program is invoked with one extra argument:
+//
+//	get    print the credentials on stdout (non-zero exit if none)
+//	store  read the credentials from stdin
+//	erase  remove the credentials
+type helperStore struct {
+	argv []string
+}
+
+func newHelperStore(settings config.Settings) (CredentialStore, error) {
+	argv := strings.Fields(settings.CredentialHelper)
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("helper credential store requires settings.credential_helper")
+	}
+	return helperStore{argv: argv}, nil
+}
+
+func (s helperStore) run(op string, stdin []byte) ([]byte, error) {
+	args := append(append([]string{}, s.argv[1:]...), op)
+	cmd := exec.Command(s.argv[0], args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("credential helper %q %s failed: %s", s.argv[0], op, msg)
+	}
+	return out, nil
+}
+
+func (s helperStore) Read() ([]byte, error) {
+	out, err := s.run("get", nil)
+	if err != nil {
+		return nil, err
+	}
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("credential helper %q returned no credentials", s.argv[0])
+	}
+	return out, nil
+}
+
+func (s helperStore) Write(data []byte) error {
+	_, err := s.run("store", data)
+	return err
+}
+
+func (s helperStore) Delete() error {
+	_, err := s.run("erase", nil)
+	return err
+}
+
+func (s helperStore) Describe() string {
+	return "helper (" + strings.Join(s.argv, " ") + ")"
+}
@@ -0,0 +1,57 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+// defaultPassEntry is the password-store entry used when none is configured.
+const defaultPassEntry = "claude-code/credentials"
+
+// passStore keeps credentials in a gpg-encrypted pass(1) password store.
+type passStore struct {
+	entry string
+}
+
+func newPassStore(settings config.Settings) (CredentialStore, error) {
+	entry := settings.PassEntry
+	if entry == "" {
+		entry = defaultPassEntry
+	}
+	return passStore{entry: entry}, nil
+}
+
+func (s passStore) Read() ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("pass", "show", s.entry)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("no credentials found in pass entry %q: %s", s.entry, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func (s passStore) Write(data []byte) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("pass", "insert", "--multiline", "--force", s.entry)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cannot write pass entry %q: %s", s.entry, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (s passStore) Delete() error {
+	_ = exec.Command("pass", "rm", "--force", s.entry).Run()
+	return nil
+}
+
+func (s passStore) Describe() string {
+	return "pass (" + s.entry + ")"
+}
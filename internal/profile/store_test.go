@@ -0,0 +1,138 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+func TestSelectedStoreName(t *testing.T) {
+	settings := config.DefaultSettings()
+
+	t.Setenv(StoreEnvVar, "")
+	settings.CredentialStore = "pass"
+	if got := SelectedStoreName(settings); got != "pass" {
+		t.Errorf("expected configured store 'pass', got %q", got)
+	}
+
+	t.Setenv(StoreEnvVar, "helper")
+	if got := SelectedStoreName(settings); got != "helper" {
+		t.Errorf("expected env override 'helper', got %q", got)
+	}
+
+	t.Setenv(StoreEnvVar, "")
+	settings.CredentialStore = ""
+	want := "file"
+	if runtime.GOOS == "darwin" {
+		want = "keychain"
+	}
+	if got := SelectedStoreName(settings); got != want {
+		t.Errorf("expected platform default %q, got %q", want, got)
+	}
+}
+
+func TestOpenStoreUnknown(t *testing.T) {
+	if _, err := OpenStore("nope", config.DefaultSettings()); err == nil {
+		t.Error("expected error for unknown store")
+	}
+	// The memory store keeps nothing across processes, so it can only be
+	// installed by tests.
+	if _, err := OpenStore("memory", config.DefaultSettings()); err == nil {
+		t.Error("memory should not be a selectable store")
+	}
+}
+
+func TestMemoryStoreRoundTrip(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	store := NewMemoryStore([]byte(`{"email": "mem@example.com", "token": "m1"}`))
+	SetCredentialStore(store)
+	defer SetCredentialStore(nil)
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("mem", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if cfg.Profiles["mem"].Email != "mem@example.com" {
+		t.Errorf("expected email from memory store, got %q", cfg.Profiles["mem"].Email)
+	}
+
+	if err := store.Write([]byte(`{"email": "other@example.com", "token": "o1"}`)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := mgr.Import("other", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if err := mgr.Use("mem"); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	data, err := store.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != `{"email": "mem@example.com", "token": "m1"}` {
+		t.Errorf("Use did not write through the store, got %s", data)
+	}
+
+	if err := store.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Read(); err == nil {
+		t.Error("expected error reading deleted credentials")
+	}
+}
+
+func TestHelperStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script uses sh")
+	}
+
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "creds")
+	script := filepath.Join(dir, "helper.sh")
+	body := "#!/bin/sh\n" +
+		"case \"$1\" in\n" +
+		"get) cat " + dataFile + " ;;\n" +
+		"store) cat > " + dataFile + " ;;\n" +
+		"erase) rm -f " + dataFile + " ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatalf("cannot write helper: %v", err)
+	}
+
+	settings := config.DefaultSettings()
+	settings.CredentialHelper = script
+	store, err := OpenStore("helper", settings)
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+
+	if err := store.Write([]byte(`{"token": "h1"}`)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data, err := store.Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(data) != `{"token": "h1"}` {
+		t.Errorf("unexpected helper data %s", data)
+	}
+	if err := store.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Read(); err == nil {
+		t.Error("expected error after erase")
+	}
+}
+
+func TestHelperStoreRequiresCommand(t *testing.T) {
+	if _, err := OpenStore("helper", config.DefaultSettings()); err == nil {
+		t.Error("expected error when credential_helper is not set")
+	}
+}
@@ -0,0 +1,137 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// OwnerFile is the marker written into every temporary directory cs
+// creates, naming the process that owns it.
+const OwnerFile = ".cs-owner.json"
+
+// tempDirPrefix starts the name of every temporary directory cs creates.
+const tempDirPrefix = "cs-"
+
+// unmarkedGrace is how old a cs temp directory without an owner marker
+// (left by an older version, or by a crash right after creation) must be
+// before it counts as orphaned.
+const unmarkedGrace = time.Hour
+
+// TempOwner identifies the process that owns a temporary directory.
+type TempOwner struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Profile   string    `json:"profile,omitempty"`
+}
+
+// TempDir describes a temporary directory created by cs.
+type TempDir struct {
+	Path string
+	// Owner is nil if the directory has no readable owner marker.
+	Owner    *TempOwner
+	ModTime  time.Time
+	Orphaned bool
+}
+
+// CreateTempDir creates a temporary directory named after pattern (as for
+// os.MkdirTemp, starting with "cs-") and marks it as owned by this process.
+func CreateTempDir(pattern, profileName string) (string, error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("cannot create temp directory: %w", err)
+	}
+
+	owner := TempOwner{PID: os.Getpid(), StartedAt: time.Now().UTC(), Profile: profileName}
+	data, err := json.Marshal(owner)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, OwnerFile), data, 0600)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("cannot mark temp directory: %w", err)
+	}
+	return dir, nil
+}
+
+// ListTempDirs returns the cs temporary directories in os.TempDir(), oldest
+// first. A directory is orphaned when its owning process has exited, or when
+// it has no marker, holds credentials and is older than an hour.
+func ListTempDirs() ([]TempDir, error) {
+	base := os.TempDir()
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", base, err)
+	}
+
+	var dirs []TempDir
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), tempDirPrefix) {
+			continue
+		}
+		path := filepath.Join(base, e.Name())
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		d := TempDir{Path: path, ModTime: info.ModTime()}
+
+		if data, err := os.ReadFile(filepath.Join(path, OwnerFile)); err == nil {
+			var owner TempOwner
+			if json.Unmarshal(data, &owner) == nil {
+				d.Owner = &owner
+			}
+		}
+
+		switch {
+		case d.Owner != nil:
+			d.Orphaned = !fsutil.ProcessAlive(d.Owner.PID)
+		case holdsCredentials(path):
+			d.Orphaned = time.Since(d.ModTime) > unmarkedGrace
+		default:
+			continue // not recognisably ours
+		}
+		dirs = append(dirs, d)
+	}
+
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].ModTime.Before(dirs[j].ModTime) })
+	return dirs, nil
+}
+
+// RemoveOrphanedTempDirs deletes the orphaned directories reported by
+// ListTempDirs and returns those it removed.
+func RemoveOrphanedTempDirs() ([]TempDir, error) {
+	dirs, err := ListTempDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []TempDir
+	for _, d := range dirs {
+		if !d.Orphaned {
+			continue
+		}
+		if err := os.RemoveAll(d.Path); err != nil {
+			return removed, fmt.Errorf("cannot remove %s: %w", d.Path, err)
+		}
+		removed = append(removed, d)
+	}
+	return removed, nil
+}
+
+// holdsCredentials reports whether dir contains a copy of Claude's
+// credential or identity files.
+func holdsCredentials(dir string) bool {
+	for _, fname := range append(append([]string{}, CredentialFiles...), HomeCredentialFiles...) {
+		if FileExists(filepath.Join(dir, fname)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package profile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTempDirOwnership(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	live, err := CreateTempDir("cs-live-*", "work")
+	if err != nil {
+		t.Fatalf("CreateTempDir failed: %v", err)
+	}
+
+	// A directory whose owner has exited.
+	dead, err := CreateTempDir("cs-dead-*", "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(TempOwner{PID: 1 << 30, StartedAt: time.Now()})
+	if err := os.WriteFile(filepath.Join(dead, OwnerFile), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// An old unmarked directory with credentials, e.g. from an older version.
+	legacy := filepath.Join(os.TempDir(), "cs-legacy-1")
+	if err := os.MkdirAll(legacy, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, ".credentials.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(legacy, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unrelated directories are never touched.
+	other := filepath.Join(os.TempDir(), "cs-something-else")
+	if err := os.MkdirAll(other, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := ListTempDirs()
+	if err != nil {
+		t.Fatalf("ListTempDirs failed: %v", err)
+	}
+	if len(dirs) != 3 {
+		t.Fatalf("expected 3 cs temp dirs, got %d: %+v", len(dirs), dirs)
+	}
+
+	removed, err := RemoveOrphanedTempDirs()
+	if err != nil {
+		t.Fatalf("RemoveOrphanedTempDirs failed: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected 2 removed dirs, got %+v", removed)
+	}
+	if !DirExists(live) || !DirExists(other) {
+		t.Error("in-use and unrelated directories must be kept")
+	}
+	if DirExists(dead) || DirExists(legacy) {
+		t.Error("orphaned directories should be removed")
+	}
+}
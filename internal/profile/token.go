@@ -1,23 +1,26 @@
 package profile
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/caeser1996/claude-switch/internal/claude"
 	"github.com/caeser1996/claude-switch/internal/config"
 )
 
 // TokenStatus represents the health of a profile's credentials.
 type TokenStatus struct {
-	ProfileName string
-	HasCreds    bool
-	Email       string
-	ExpiresAt   *time.Time
-	IsExpired   bool
-	ExpiresIn   string
+	ProfileName      string
+	HasCreds         bool
+	Kind             string
+	Provider         string
+	Email            string
+	SubscriptionType string
+	ExpiresAt        *time.Time
+	IsExpired        bool
+	ExpiresIn        string
 }
 
 // CheckTokenStatus inspects a profile's credentials and returns status info.
@@ -28,54 +31,95 @@ func CheckTokenStatus(name string) TokenStatus {
 	if err != nil {
 		return status
 	}
+	profileDir := filepath.Join(profilesDir, name)
 
-	credPath := filepath.Join(profilesDir, name, ".credentials.json")
+	credPath := filepath.Join(profileDir, ".credentials.json")
 	if !FileExists(credPath) {
+		// Environment profiles carry no token; report the provider instead.
+		if vars, err := LoadEnvVars(profileDir); err == nil {
+			status.HasCreds = true
+			status.Kind = config.ProfileKindEnv
+			status.Provider = ProviderKind(vars)
+		}
 		return status
 	}
 	status.HasCreds = true
 
-	data, err := os.ReadFile(credPath)
+	creds, err := LoadProfileCredentials(profileDir)
 	if err != nil {
 		return status
 	}
 
-	var creds map[string]interface{}
-	if err := json.Unmarshal(data, &creds); err != nil {
-		return status
+	status.Kind = creds.Kind
+	status.Email = creds.Email
+	status.SubscriptionType = creds.SubscriptionType
+
+	if creds.ExpiresAt != nil {
+		status.ExpiresAt = creds.ExpiresAt
+		status.IsExpired = creds.IsExpired()
+		if !status.IsExpired {
+			status.ExpiresIn = formatDuration(time.Until(*creds.ExpiresAt))
+		}
 	}
 
-	if email, ok := creds["email"].(string); ok {
-		status.Email = email
+	return status
+}
+
+// LoadProfileCredentials parses the credentials and account identity stored
+// in a profile (or backup) directory.
+func LoadProfileCredentials(profileDir string) (*claude.Credentials, error) {
+	credData, err := ReadSealed(filepath.Join(profileDir, ".credentials.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
 	}
+	homeData, err := ReadSealed(filepath.Join(profileDir, "home_.claude.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return claude.ParseCredentials(credData, homeData)
+}
 
-	// Check for expiry fields (expiresAt, expires_at, exp)
-	for _, key := range []string{"expiresAt", "expires_at", "exp"} {
-		if v, ok := creds[key]; ok {
-			if expStr, ok := v.(string); ok {
-				if t, err := time.Parse(time.RFC3339, expStr); err == nil {
-					status.ExpiresAt = &t
-					status.IsExpired = time.Now().After(t)
-					if !status.IsExpired {
-						status.ExpiresIn = formatDuration(time.Until(t))
-					}
-					break
-				}
+// CurrentCredentials parses the credentials a profile runs with: the live
+// ones for the active profile, whose stored copy may be behind, and the
+// stored ones for the others.
+func CurrentCredentials(cfg *config.Config, name string) (*claude.Credentials, error) {
+	if _, ok := cfg.Profiles[name]; !ok {
+		return nil, fmt.Errorf("profile %q not found", name)
+	}
+	if name == cfg.ActiveProfile {
+		credData, err := ReadCurrentCredentials()
+		if err == nil {
+			var homeData []byte
+			if homePath, err := config.ClaudeAuthJSONPath(); err == nil {
+				homeData, _ = os.ReadFile(homePath)
 			}
-			// Could be a numeric timestamp
-			if expNum, ok := v.(float64); ok {
-				t := time.Unix(int64(expNum), 0)
-				status.ExpiresAt = &t
-				status.IsExpired = time.Now().After(t)
-				if !status.IsExpired {
-					status.ExpiresIn = formatDuration(time.Until(t))
-				}
-				break
+			if creds, err := claude.ParseCredentials(credData, homeData); err == nil {
+				return creds, nil
 			}
 		}
 	}
+	profilesDir, err := config.ProfilesDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadProfileCredentials(filepath.Join(profilesDir, name))
+}
 
-	return status
+// StoredUsageSource points claude.GetUsageInfo at a profile's stored
+// files, decrypting them if the store is sealed.
+func StoredUsageSource(name string) (claude.UsageSource, error) {
+	profilesDir, err := config.ProfilesDir()
+	if err != nil {
+		return claude.UsageSource{}, err
+	}
+	profileDir := filepath.Join(profilesDir, name)
+	return claude.UsageSource{
+		Profile:         name,
+		CredentialsPath: filepath.Join(profileDir, ".credentials.json"),
+		HomePath:        filepath.Join(profileDir, "home_.claude.json"),
+		StatsigPath:     filepath.Join(profileDir, "statsig_metadata"),
+		ReadFile:        ReadSealed,
+	}, nil
 }
 
 // NeedsRefresh returns true if the token is expired or will expire within the threshold.
@@ -93,6 +93,60 @@ func TestCheckTokenStatusExpired(t *testing.T) {
 	}
 }
 
+func TestCheckTokenStatusNestedOAuth(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	expires := time.Now().Add(6 * time.Hour).UnixMilli()
+	creds := map[string]interface{}{
+		"claudeAiOauth": map[string]interface{}{
+			"accessToken":      "sk-ant-oat01-test",
+			"refreshToken":     "sk-ant-ort01-test",
+			"expiresAt":        expires,
+			"scopes":           []string{"user:inference", "user:profile"},
+			"subscriptionType": "max",
+		},
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, ".claude", ".credentials.json"), data, 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	home := `{"oauthAccount": {"accountUuid": "acc-1", "emailAddress": "nested@example.com"}}`
+	if err := os.WriteFile(filepath.Join(tmpDir, ".claude.json"), []byte(home), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	if err := mgr.Import("nested", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if cfg.Profiles["nested"].Email != "nested@example.com" {
+		t.Errorf("expected profile email 'nested@example.com', got %q", cfg.Profiles["nested"].Email)
+	}
+	if cfg.Profiles["nested"].Subscription != "Max" {
+		t.Errorf("expected profile subscription 'Max', got %q", cfg.Profiles["nested"].Subscription)
+	}
+
+	status := CheckTokenStatus("nested")
+	if status.Email != "nested@example.com" {
+		t.Errorf("expected email 'nested@example.com', got %q", status.Email)
+	}
+	if status.SubscriptionType != "max" {
+		t.Errorf("expected subscription 'max', got %q", status.SubscriptionType)
+	}
+	if status.ExpiresAt == nil || status.ExpiresAt.UnixMilli() != expires {
+		t.Errorf("expected millisecond expiry %d, got %v", expires, status.ExpiresAt)
+	}
+	if status.IsExpired {
+		t.Error("token should not be expired")
+	}
+}
+
 func TestCheckTokenStatusNoCreds(t *testing.T) {
 	_, cleanup := setupTestEnv(t)
 	defer cleanup()
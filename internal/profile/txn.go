@@ -0,0 +1,277 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// Txn applies a group of credential changes as a unit. Each step records
+// how to undo itself before moving on; if a later step fails, Fail undoes
+// the completed steps in reverse order so nothing is left half-applied.
+type Txn struct {
+	steps []txnStep
+}
+
+type txnStep struct {
+	desc string
+	undo func() error
+}
+
+// NewTxn starts an empty transaction.
+func NewTxn() *Txn {
+	return &Txn{}
+}
+
+func (t *Txn) record(desc string, undo func() error) {
+	t.steps = append(t.steps, txnStep{desc: desc, undo: undo})
+}
+
+// WriteFile atomically replaces path with data (mode 0600), remembering the
+// previous contents so the write can be undone.
+func (t *Txn) WriteFile(path string, data []byte) error {
+	prev, err := os.ReadFile(path)
+	existed := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read %s: %w", path, err)
+	}
+
+	if err := t.MkdirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := fsutil.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	t.record(displayPath(path), func() error {
+		if existed {
+			return fsutil.WriteFile(path, prev, 0600)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	})
+	return nil
+}
+
+// WriteSealed is WriteFile for files kept in the profile store: data is
+// sealed first when the vault is enabled.
+func (t *Txn) WriteSealed(path string, data []byte) error {
+	sealed, err := sealData(path, data)
+	if err != nil {
+		return err
+	}
+	return t.WriteFile(path, sealed)
+}
+
+// MkdirAll creates dir (mode 0700) if it is missing; undoing removes it again.
+func (t *Txn) MkdirAll(dir string) error {
+	if DirExists(dir) {
+		return nil
+	}
+	if err := t.MkdirAll(filepath.Dir(dir)); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("cannot create %s: %w", dir, err)
+	}
+	t.record("", func() error {
+		// Only succeeds once the files written into it have been undone.
+		if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	})
+	return nil
+}
+
+// WriteCredentials replaces the contents of the live credential store.
+func (t *Txn) WriteCredentials(data []byte) error {
+	store, err := CurrentStore()
+	if err != nil {
+		return err
+	}
+	prev, readErr := store.Read()
+
+	if err := store.Write(data); err != nil {
+		return err
+	}
+
+	t.record(store.Describe(), func() error {
+		if readErr == nil && len(prev) > 0 {
+			return store.Write(prev)
+		}
+		return store.Delete()
+	})
+	return nil
+}
+
+// Fail undoes every completed step and returns an error describing cause,
+// what was rolled back, and any step that could not be undone. action
+// names the operation, e.g. `switch to "work"`.
+func (t *Txn) Fail(action string, cause error) error {
+	var restored []string
+	var failures []error
+	for i := len(t.steps) - 1; i >= 0; i-- {
+		s := t.steps[i]
+		if err := s.undo(); err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", s.desc, err))
+			continue
+		}
+		if s.desc != "" {
+			restored = append(restored, s.desc)
+		}
+	}
+	t.steps = nil
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%s failed: %w; rollback was incomplete (%v) — check 'cs backup list'",
+			action, cause, errors.Join(failures...))
+	}
+	if len(restored) == 0 {
+		return fmt.Errorf("%s failed: %w (nothing had been changed)", action, cause)
+	}
+	return fmt.Errorf("%s failed and was rolled back (restored %s): %w",
+		action, strings.Join(restored, ", "), cause)
+}
+
+// stagedFiles is the full content of a profile or backup directory, read
+// (and decrypted) up front so a switch cannot fail halfway on a bad source.
+type stagedFiles struct {
+	creds []byte            // .credentials.json, for the credential store
+	files map[string][]byte // other CredentialFiles, for ~/.claude
+	home  map[string][]byte // identity subsets of HomeCredentialFiles
+}
+
+// stageDir reads every stored file in dir. With requireCreds, a missing
+// .credentials.json is an error: applying the rest would leave the live
+// token of one account under the identity of another.
+func stageDir(dir string, requireCreds bool) (*stagedFiles, error) {
+	s := &stagedFiles{files: map[string][]byte{}, home: map[string][]byte{}}
+
+	for _, fname := range CredentialFiles {
+		data, err := ReadSealed(filepath.Join(dir, fname))
+		if os.IsNotExist(err) {
+			if fname == ".credentials.json" && requireCreds {
+				return nil, fmt.Errorf("no stored credentials (%s is missing)", fname)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %w", fname, err)
+		}
+		if fname == ".credentials.json" {
+			s.creds = data
+		} else {
+			s.files[fname] = data
+		}
+	}
+
+	for _, fname := range HomeCredentialFiles {
+		data, err := ReadSealed(filepath.Join(dir, "home_"+fname))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read stored %s: %w", fname, err)
+		}
+		s.home[fname] = data
+	}
+
+	return s, nil
+}
+
+// count returns the number of staged files.
+func (s *stagedFiles) count() int {
+	n := len(s.files) + len(s.home)
+	if s.creds != nil {
+		n++
+	}
+	return n
+}
+
+// applyToLive writes the staged files into the live Claude session within t:
+// credentials go to the credential store, the rest to ~/.claude, and the
+// identity keys are merged into the home-level files.
+func (s *stagedFiles) applyToLive(t *Txn, keys []string) error {
+	if s.creds != nil {
+		if err := t.WriteCredentials(s.creds); err != nil {
+			return fmt.Errorf("cannot restore credentials: %w", err)
+		}
+	}
+
+	claudeDir, err := config.ClaudeConfigDir()
+	if err != nil {
+		return err
+	}
+	for _, fname := range CredentialFiles {
+		data, ok := s.files[fname]
+		if !ok {
+			continue
+		}
+		if err := t.WriteFile(filepath.Join(claudeDir, fname), data); err != nil {
+			return fmt.Errorf("cannot restore %s: %w", fname, err)
+		}
+	}
+
+	if len(s.home) == 0 {
+		return nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	for _, fname := range HomeCredentialFiles {
+		stored, ok := s.home[fname]
+		if !ok {
+			continue
+		}
+		dst := filepath.Join(home, fname)
+		live, err := os.ReadFile(dst)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot read %s: %w", dst, err)
+		}
+		merged, err := MergeKeys(live, stored, keys)
+		if err != nil {
+			return fmt.Errorf("cannot merge %s: %w", fname, err)
+		}
+		if err := t.WriteFile(dst, merged); err != nil {
+			return fmt.Errorf("cannot restore %s: %w", fname, err)
+		}
+	}
+	return nil
+}
+
+// RestoreBackup replaces the live Claude session with the files saved in
+// backupDir as one transaction, returning the number of files restored.
+func RestoreBackup(backupDir string, keys []string) (int, error) {
+	staged, err := stageDir(backupDir, false)
+	if err != nil {
+		return 0, err
+	}
+	if staged.count() == 0 {
+		return 0, nil
+	}
+
+	txn := NewTxn()
+	if err := staged.applyToLive(txn, keys); err != nil {
+		return 0, txn.Fail("restore of backup "+filepath.Base(backupDir), err)
+	}
+	return staged.count(), nil
+}
+
+// displayPath shortens path for messages by replacing the home directory with ~.
+func displayPath(path string) string {
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join("~", rel)
+		}
+	}
+	return path
+}
@@ -0,0 +1,192 @@
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/crypto"
+)
+
+func TestTxnFailUndoesWrites(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.json")
+	if err := os.WriteFile(existing, []byte("before"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	newDir := filepath.Join(dir, "sub")
+	created := filepath.Join(newDir, "created.json")
+
+	txn := NewTxn()
+	if err := txn.WriteFile(existing, []byte("after")); err != nil {
+		t.Fatalf("WriteFile(existing) error: %v", err)
+	}
+	if err := txn.WriteFile(created, []byte("new")); err != nil {
+		t.Fatalf("WriteFile(created) error: %v", err)
+	}
+
+	err := txn.Fail("test", errors.New("boom"))
+	if err == nil || !strings.Contains(err.Error(), "rolled back") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Fail() error = %v, want rollback message wrapping the cause", err)
+	}
+
+	if data, _ := os.ReadFile(existing); string(data) != "before" {
+		t.Errorf("existing file = %q, want %q", data, "before")
+	}
+	if FileExists(created) || DirExists(newDir) {
+		t.Error("file and directory created in the transaction were not removed")
+	}
+}
+
+func TestUseRollsBackOnFailure(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	claudeDir := filepath.Join(tmpDir, ".claude")
+
+	if err := mgr.Import("first", ""); err != nil {
+		t.Fatalf("Import first failed: %v", err)
+	}
+
+	// A second account, whose profile also carries a statsig file.
+	otherCreds := `{"email": "other@example.com", "token": "other-token"}`
+	if err := os.WriteFile(filepath.Join(claudeDir, ".credentials.json"), []byte(otherCreds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(claudeDir, "statsig"), []byte("other-statsig"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Import("second", ""); err != nil {
+		t.Fatalf("Import second failed: %v", err)
+	}
+
+	// Back on the first account, with statsig replaced by a directory so
+	// the switch fails after the credentials have been written.
+	firstCreds := `{"email": "test@example.com", "token": "mock-token-123"}`
+	if err := os.WriteFile(filepath.Join(claudeDir, ".credentials.json"), []byte(firstCreds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(claudeDir, "statsig"))
+	if err := os.MkdirAll(filepath.Join(claudeDir, "statsig", "blocker"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	err := mgr.Use("second")
+	if err == nil {
+		t.Fatal("Use() should fail when a file cannot be written")
+	}
+	if !strings.Contains(err.Error(), "rolled back") {
+		t.Errorf("error should explain the rollback, got: %v", err)
+	}
+
+	live, _ := os.ReadFile(filepath.Join(claudeDir, ".credentials.json"))
+	if string(live) != firstCreds {
+		t.Errorf("live credentials = %s, want the pre-switch ones", live)
+	}
+	if cfg.ActiveProfile != "first" {
+		t.Errorf("active profile = %q, want %q", cfg.ActiveProfile, "first")
+	}
+	if saved, _ := config.Load(); saved.ActiveProfile != "first" {
+		t.Errorf("saved active profile = %q, want %q", saved.ActiveProfile, "first")
+	}
+}
+
+func TestUseFailsWithoutStoredCredentials(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := config.NewConfig()
+	mgr := NewManager(cfg)
+	claudeDir := filepath.Join(tmpDir, ".claude")
+
+	if err := mgr.Import("first", ""); err != nil {
+		t.Fatalf("Import first failed: %v", err)
+	}
+	otherCreds := `{"email": "other@example.com", "token": "other-token"}`
+	if err := os.WriteFile(filepath.Join(claudeDir, ".credentials.json"), []byte(otherCreds), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Import("second", ""); err != nil {
+		t.Fatalf("Import second failed: %v", err)
+	}
+	if err := mgr.Use("first"); err != nil {
+		t.Fatalf("Use first failed: %v", err)
+	}
+
+	profilesDir, err := config.ProfilesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(profilesDir, "second", ".credentials.json")); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(filepath.Join(claudeDir, ".credentials.json"))
+
+	if err := mgr.Use("second"); err == nil || !strings.Contains(err.Error(), "no stored credentials") {
+		t.Fatalf("Use() of a profile without credentials: %v", err)
+	}
+	if live, _ := os.ReadFile(filepath.Join(claudeDir, ".credentials.json")); string(live) != string(before) {
+		t.Errorf("live credentials = %s, want the pre-switch %s", live, before)
+	}
+	if cfg.ActiveProfile != "first" {
+		t.Errorf("active profile = %q, want %q", cfg.ActiveProfile, "first")
+	}
+	if saved, _ := config.Load(); saved.ActiveProfile != "first" {
+		t.Errorf("saved active profile = %q, want %q", saved.ActiveProfile, "first")
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	backupDir := filepath.Join(t.TempDir(), "20260101-120000")
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	saved := `{"email": "backup@example.com", "token": "backup-token"}`
+	if err := os.WriteFile(filepath.Join(backupDir, ".credentials.json"), []byte(saved), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := RestoreBackup(backupDir, config.DefaultIdentityKeys)
+	if err != nil {
+		t.Fatalf("RestoreBackup() error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("restored %d file(s), want 1", n)
+	}
+	live, _ := os.ReadFile(filepath.Join(tmpDir, ".claude", ".credentials.json"))
+	if string(live) != saved {
+		t.Errorf("live credentials = %s, want backup contents", live)
+	}
+}
+
+func TestImportFromFileRejectsUnknownFiles(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	plaintext, _ := json.Marshal(ExportBundle{
+		Version:     1,
+		ProfileName: "evil",
+		Files:       map[string][]byte{"../../escape": []byte("x")},
+	})
+	data, err := crypto.Encrypt(plaintext, "test-passphrase-12345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.NewConfig()
+	if _, err := ImportFromFile(data, "test-passphrase-12345678", "", cfg); err == nil {
+		t.Fatal("ImportFromFile() should reject files outside the profile layout")
+	}
+	if _, ok := cfg.Profiles["evil"]; ok {
+		t.Error("rejected bundle was added to the config")
+	}
+}
@@ -0,0 +1,112 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+// WriteBack saves credential changes made inside the isolated environment,
+// typically a token refresh by Claude Code, into the stored profile so the
+// next run does not start from an already-rotated refresh token.
+//
+// A file is written only when it differs from the stored copy. Credentials
+// are skipped if they belong to a different account or the stored token
+// expires later (another run refreshed it since); a run that logged into a
+// different account is an error. The files are written as one
+// transaction. WriteBack returns the names of the files it wrote; take the
+// state lock before calling it.
+func (e *IsolatedEnv) WriteBack(cfg *config.Config) ([]string, error) {
+	entry, ok := cfg.Profiles[e.ProfileName]
+	if !ok || entry.IsEnv() || e.TempDir == "" {
+		return nil, nil
+	}
+
+	profilesDir, err := config.ProfilesDir()
+	if err != nil {
+		return nil, err
+	}
+	profileDir := filepath.Join(profilesDir, e.ProfileName)
+	if !DirExists(profileDir) {
+		return nil, nil
+	}
+
+	type change struct {
+		name string
+		data []byte
+	}
+	var changes []change
+
+	credPath := filepath.Join(e.TempDir, ".credentials.json")
+	if creds, err := os.ReadFile(credPath); err == nil && len(creds) > 0 {
+		home, _ := os.ReadFile(filepath.Join(e.TempDir, ".claude.json"))
+		runID := accountIdentity(creds, home)
+		storedID := profileIdentity(profileDir)
+		if !runID.IsZero() && !storedID.IsZero() && !runID.Matches(storedID) {
+			return nil, fmt.Errorf("the run is logged into a different account; its credentials were not saved to profile %q", e.ProfileName)
+		}
+	}
+
+	for _, fname := range CredentialFiles {
+		src := filepath.Join(e.TempDir, fname)
+		data, err := os.ReadFile(src)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		dst := filepath.Join(profileDir, fname)
+		if stored, err := ReadSealed(dst); err == nil && bytes.Equal(stored, data) {
+			continue
+		}
+		if FileExists(dst) && storedIsNewer(dst, src) {
+			continue
+		}
+		changes = append(changes, change{fname, data})
+	}
+
+	keys := cfg.Settings.AccountKeys()
+	for _, fname := range HomeCredentialFiles {
+		data, err := os.ReadFile(filepath.Join(e.TempDir, fname))
+		if err != nil {
+			continue
+		}
+		subset, err := ExtractKeys(data, keys)
+		if err != nil {
+			continue
+		}
+		stored, err := ReadSealed(filepath.Join(profileDir, "home_"+fname))
+		if err != nil || !sameJSONKeys(stored, subset, keys) {
+			changes = append(changes, change{"home_" + fname, subset})
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	action := fmt.Sprintf("saving credentials back to profile %q", e.ProfileName)
+	txn := NewTxn()
+	written := make([]string, 0, len(changes))
+	for _, c := range changes {
+		if err := txn.WriteSealed(filepath.Join(profileDir, c.name), c.data); err != nil {
+			return nil, txn.Fail(action, fmt.Errorf("cannot write %s: %w", c.name, err))
+		}
+		written = append(written, c.name)
+	}
+	return written, nil
+}
+
+// sameJSONKeys reports whether a and b hold the same values for keys.
+func sameJSONKeys(a, b []byte, keys []string) bool {
+	x, err := ExtractKeys(a, keys)
+	if err != nil {
+		return false
+	}
+	y, err := ExtractKeys(b, keys)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(x, y)
+}
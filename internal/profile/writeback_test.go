@@ -0,0 +1,98 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+)
+
+func oauthCreds(token, email string, expiresAtMs int64) string {
+	return fmt.Sprintf(`{"claudeAiOauth":{"accessToken":%q,"refreshToken":"r-%s","expiresAt":%d},"email":%q}`,
+		token, token, expiresAtMs, email)
+}
+
+// setupWriteBack imports a profile with OAuth credentials and returns an
+// isolated environment for it.
+func setupWriteBack(t *testing.T) (*IsolatedEnv, *config.Config, string) {
+	t.Helper()
+	home, cleanup := setupTestEnv(t)
+	t.Cleanup(cleanup)
+
+	live := filepath.Join(home, ".claude", ".credentials.json")
+	if err := os.WriteFile(live, []byte(oauthCreds("old", "a@example.com", 2000000000000)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.NewConfig()
+	if err := NewManager(cfg).Import("work", ""); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	env, err := SetupIsolatedEnv("work", cfg)
+	if err != nil {
+		t.Fatalf("SetupIsolatedEnv failed: %v", err)
+	}
+	t.Cleanup(func() { _ = env.Cleanup() })
+
+	profilesDir, _ := config.ProfilesDir()
+	return env, cfg, filepath.Join(profilesDir, "work", ".credentials.json")
+}
+
+func TestWriteBackSavesRefreshedCredentials(t *testing.T) {
+	env, cfg, stored := setupWriteBack(t)
+
+	refreshed := oauthCreds("new", "a@example.com", 2100000000000)
+	if err := os.WriteFile(filepath.Join(env.TempDir, ".credentials.json"), []byte(refreshed), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := env.WriteBack(cfg)
+	if err != nil {
+		t.Fatalf("WriteBack failed: %v", err)
+	}
+	if len(written) != 1 || written[0] != ".credentials.json" {
+		t.Errorf("written = %v, want [.credentials.json]", written)
+	}
+	data, _ := ReadSealed(stored)
+	if string(data) != refreshed {
+		t.Errorf("stored credentials not updated: %s", data)
+	}
+
+	// Nothing changed since: nothing to write.
+	if written, _ := env.WriteBack(cfg); len(written) != 0 {
+		t.Errorf("second WriteBack wrote %v", written)
+	}
+}
+
+func TestWriteBackKeepsNewerStoredCredentials(t *testing.T) {
+	env, cfg, stored := setupWriteBack(t)
+
+	older := oauthCreds("older", "a@example.com", 1900000000000)
+	if err := os.WriteFile(filepath.Join(env.TempDir, ".credentials.json"), []byte(older), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if written, err := env.WriteBack(cfg); err != nil || len(written) != 0 {
+		t.Errorf("WriteBack = %v, %v; want nothing written", written, err)
+	}
+	if data, _ := ReadSealed(stored); !strings.Contains(string(data), `"old"`) {
+		t.Errorf("stored credentials were replaced by older ones: %s", data)
+	}
+}
+
+func TestWriteBackRejectsOtherAccount(t *testing.T) {
+	env, cfg, stored := setupWriteBack(t)
+
+	other := oauthCreds("other", "b@example.com", 2100000000000)
+	if err := os.WriteFile(filepath.Join(env.TempDir, ".credentials.json"), []byte(other), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.WriteBack(cfg); err == nil {
+		t.Error("WriteBack should refuse credentials of another account")
+	}
+	if data, _ := ReadSealed(stored); strings.Contains(string(data), "b@example.com") {
+		t.Error("stored credentials were overwritten with another account")
+	}
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// controlSocket is the file name of the proxy's control socket in
+// AppDataDir.
+const controlSocket = "proxy.sock"
+
+// SocketPath returns the path of the control socket of a running proxy.
+func SocketPath() (string, error) {
+	base, err := config.AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, controlSocket), nil
+}
+
+// ListenControl opens the control socket at path (mode 0600). It fails if
+// another proxy is answering there, and replaces a stale socket otherwise.
+func ListenControl(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, 500*time.Millisecond); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a proxy is already running (control socket %s)", path)
+	}
+	return fsutil.ListenUnix(path)
+}
+
+// ServeControl answers control requests on ln until it is closed:
+//
+//	STATUS      → OK <profile>
+//	USE <name>  → OK <name>, or ERR <reason>
+func (p *Proxy) ServeControl(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go p.handleControlConn(conn)
+	}
+}
+
+func (p *Proxy) handleControlConn(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	switch command {
+	case "STATUS":
+		fmt.Fprintln(conn, "OK", p.Current())
+	case "USE":
+		name := strings.TrimSpace(arg)
+		if err := p.Use(name); err != nil {
+			fmt.Fprintln(conn, "ERR", err)
+			return
+		}
+		p.opts.Logf("Now using profile %q", name)
+		fmt.Fprintln(conn, "OK", name)
+	default:
+		fmt.Fprintln(conn, "ERR unknown command", command)
+	}
+}
+
+// Control sends one command to the proxy listening on the control socket
+// at path and returns its reply.
+func Control(path, command string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, 500*time.Millisecond)
+	if err != nil {
+		return "", fmt.Errorf("no proxy is running — start one with 'cs proxy --listen 127.0.0.1:PORT'")
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("no reply from proxy: %w", err)
+	}
+	status, rest, _ := strings.Cut(strings.TrimSpace(reply), " ")
+	if status != "OK" {
+		return "", fmt.Errorf("%s", rest)
+	}
+	return rest, nil
+}
@@ -0,0 +1,321 @@
+// Package proxy implements 'cs proxy': a local HTTP endpoint for
+// ANTHROPIC_BASE_URL that forwards requests to the Anthropic API with the
+// credentials of a chosen profile, and fails over to the next profile of a
+// pool when one is rate-limited.
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+)
+
+// oauthBeta is the anthropic-beta flag the API requires with claude.ai
+// OAuth tokens.
+const oauthBeta = "oauth-2025-04-20"
+
+// Auth is the credential the proxy puts on upstream requests.
+type Auth struct {
+	// APIKey is sent as x-api-key.
+	APIKey string
+	// BearerToken is sent as "Authorization: Bearer".
+	BearerToken string
+	// OAuth marks BearerToken as a claude.ai OAuth token.
+	OAuth bool
+}
+
+func (a Auth) apply(h http.Header) {
+	if a.APIKey != "" {
+		h.Set("X-Api-Key", a.APIKey)
+		return
+	}
+	h.Set("Authorization", "Bearer "+a.BearerToken)
+	if a.OAuth && !strings.Contains(h.Get("Anthropic-Beta"), oauthBeta) {
+		if beta := h.Get("Anthropic-Beta"); beta != "" {
+			h.Set("Anthropic-Beta", beta+","+oauthBeta)
+		} else {
+			h.Set("Anthropic-Beta", oauthBeta)
+		}
+	}
+}
+
+// Options configures a Proxy.
+type Options struct {
+	// Upstream is the API requests are forwarded to.
+	Upstream *url.URL
+	// Profile is the profile requests go out as at first.
+	Profile string
+	// Pool lists the profiles to fail over to, in order, when the current
+	// one answers 429. It may include Profile.
+	Pool []string
+	// Credentials returns the credential of a profile. It is called for
+	// every request, so refreshed tokens are picked up.
+	Credentials func(profile string) (Auth, error)
+	// Limited reports whether a profile is known to be limited; pool
+	// members that are get skipped. Optional.
+	Limited func(profile string, now time.Time) bool
+	// OnLimit is told about every 429 answered to a profile. Optional.
+	OnLimit func(profile string, ev claude.LimitEvent)
+	// Logf reports failovers and upstream errors. Optional.
+	Logf func(format string, args ...any)
+	// Client sends the upstream requests; nil means a default client.
+	Client *http.Client
+	// Listen is the address the proxy listens on. Its host is accepted in
+	// the Host header besides localhost, 127.0.0.1 and ::1. Optional.
+	Listen string
+}
+
+// Proxy forwards requests to the upstream API. It is an http.Handler.
+type Proxy struct {
+	opts Options
+
+	mu      sync.Mutex
+	current string
+}
+
+// New returns a proxy that sends requests as opts.Profile.
+func New(opts Options) (*Proxy, error) {
+	if opts.Upstream == nil || opts.Upstream.Scheme == "" || opts.Upstream.Host == "" {
+		return nil, fmt.Errorf("invalid upstream URL")
+	}
+	if opts.Credentials == nil {
+		return nil, fmt.Errorf("no credential source")
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+	if opts.Logf == nil {
+		opts.Logf = func(string, ...any) {}
+	}
+	if _, err := opts.Credentials(opts.Profile); err != nil {
+		return nil, err
+	}
+	return &Proxy{opts: opts, current: opts.Profile}, nil
+}
+
+// Current returns the profile requests currently go out as.
+func (p *Proxy) Current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+// Use makes later requests go out as profile, once its credentials are
+// found to be usable.
+func (p *Proxy) Use(profile string) error {
+	if _, err := p.opts.Credentials(profile); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.current = profile
+	p.mu.Unlock()
+	return nil
+}
+
+// candidates lists the profiles to try a request as: the current one, then
+// the rest of the pool after it, leaving out those known to be limited
+// unless all are.
+func (p *Proxy) candidates(now time.Time) []string {
+	cur := p.Current()
+	order := []string{cur}
+	start := -1
+	for i, name := range p.opts.Pool {
+		if name == cur {
+			start = i
+		}
+	}
+	for i := 1; i <= len(p.opts.Pool); i++ {
+		if name := p.opts.Pool[(start+i)%len(p.opts.Pool)]; name != cur {
+			order = append(order, name)
+		}
+	}
+
+	if p.opts.Limited == nil {
+		return order
+	}
+	var available []string
+	for _, name := range order {
+		if !p.opts.Limited(name, now) {
+			available = append(available, name)
+		}
+	}
+	if len(available) == 0 {
+		return order[:1]
+	}
+	return available
+}
+
+// ServeHTTP forwards r upstream and copies back the response. A 429 is
+// retried as the next candidate profile, which also becomes the current
+// one; the last candidate's answer is passed on whatever it is.
+//
+// Requests from browsers (those with an Origin header) and requests for
+// any host but the proxy's own are refused: a web page could otherwise
+// reach the proxy through a name it resolves to 127.0.0.1 (DNS rebinding)
+// and read answers sent with the user's credentials.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Origin") != "" || !p.allowedHost(r.Host) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "cannot read request body", http.StatusBadRequest)
+		return
+	}
+
+	candidates := p.candidates(time.Now())
+	for i, name := range candidates {
+		resp, err := p.forward(r, body, name)
+		if err != nil {
+			p.opts.Logf("Profile %q: %v", name, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			ev := limitEvent(resp.Header, data, time.Now())
+			if p.opts.OnLimit != nil {
+				p.opts.OnLimit(name, ev)
+			}
+			if i+1 < len(candidates) {
+				next := candidates[i+1]
+				p.opts.Logf("Profile %q hit its %s limit — failing over to %q", name, ev.Kind, next)
+				p.mu.Lock()
+				if p.current == name {
+					p.current = next
+				}
+				p.mu.Unlock()
+				continue
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+		}
+		copyResponse(w, resp)
+		return
+	}
+}
+
+// allowedHost reports whether a Host header names the proxy itself.
+func (p *Proxy) allowedHost(host string) bool {
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	name = strings.ToLower(strings.Trim(name, "[]"))
+	switch name {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	listen, _, err := net.SplitHostPort(p.opts.Listen)
+	return err == nil && listen != "" && name == strings.ToLower(listen)
+}
+
+// forward sends r upstream as profile.
+func (p *Proxy) forward(r *http.Request, body []byte, profile string) (*http.Response, error) {
+	auth, err := p.opts.Credentials(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	target := *p.opts.Upstream
+	target.Path = strings.TrimSuffix(target.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+
+	out, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	out.Header = r.Header.Clone()
+	removeHopHeaders(out.Header)
+	out.Header.Del("Authorization")
+	out.Header.Del("X-Api-Key")
+	auth.apply(out.Header)
+
+	resp, err := p.opts.Client.Do(out)
+	if err != nil {
+		return nil, fmt.Errorf("upstream request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// copyResponse writes resp to w, flushing as the body arrives so streamed
+// (server-sent event) responses reach the client as they are produced.
+func copyResponse(w http.ResponseWriter, resp *http.Response) {
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	removeHopHeaders(w.Header())
+	w.WriteHeader(resp.StatusCode)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// hopHeaders apply to a single connection and are not forwarded.
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, f := range h.Values("Connection") {
+		for _, name := range strings.Split(f, ",") {
+			h.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// limitEvent describes a 429 answer. The reset time comes from the
+// anthropic-ratelimit-unified-reset or retry-after header if present, or
+// else from the error message.
+func limitEvent(h http.Header, body []byte, now time.Time) claude.LimitEvent {
+	if h.Get("Content-Encoding") == "gzip" {
+		if zr, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+			if plain, err := io.ReadAll(zr); err == nil {
+				body = plain
+			}
+		}
+	}
+	ev, ok := claude.DetectLimit(body, now)
+	if !ok || ev.Kind == claude.LimitOverloaded {
+		ev = claude.LimitEvent{Kind: claude.LimitRate, HitAt: now, ResetAt: now.Add(time.Minute), Message: "429 Too Many Requests"}
+	}
+	if epoch, err := strconv.ParseInt(h.Get("Anthropic-Ratelimit-Unified-Reset"), 10, 64); err == nil && epoch > 0 {
+		ev.ResetAt = time.Unix(epoch, 0)
+	} else if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil && secs > 0 {
+		ev.ResetAt = now.Add(time.Duration(secs) * time.Second)
+	}
+	return ev
+}
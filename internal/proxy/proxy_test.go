@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+)
+
+var testAuth = map[string]Auth{
+	"a": {APIKey: "sk-a"},
+	"b": {BearerToken: "tok-b", OAuth: true},
+	"c": {APIKey: "sk-c"},
+}
+
+func testCredentials(profile string) (Auth, error) {
+	auth, ok := testAuth[profile]
+	if !ok {
+		return Auth{}, fmt.Errorf("profile %q not found", profile)
+	}
+	return auth, nil
+}
+
+// newTestProxy starts a stand-in API that rate-limits sk-a and a proxy in
+// front of it using pool a, b, c.
+func newTestProxy(t *testing.T, limited func(string, time.Time) bool) (*Proxy, *httptest.Server, *[]string) {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") == "sk-a" {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"Number of requests has exceeded your rate limit"}}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s?%s auth=%q key=%q beta=%q body=%s",
+			r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization"), r.Header.Get("X-Api-Key"), r.Header.Get("Anthropic-Beta"), body)
+	}))
+	t.Cleanup(upstream.Close)
+
+	u, _ := url.Parse(upstream.URL + "/base")
+	var limits []string
+	p, err := New(Options{
+		Upstream:    u,
+		Profile:     "a",
+		Pool:        []string{"a", "b", "c"},
+		Credentials: testCredentials,
+		Limited:     limited,
+		OnLimit: func(profile string, ev claude.LimitEvent) {
+			limits = append(limits, fmt.Sprintf("%s:%s:%d", profile, ev.Kind, ev.ResetAt.Sub(ev.HitAt)/time.Second))
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+	return p, srv, &limits
+}
+
+func TestProxyFailover(t *testing.T) {
+	p, srv, limits := newTestProxy(t, nil)
+
+	req, _ := http.NewRequest("POST", srv.URL+"/v1/messages?beta=true", strings.NewReader(`{"model":"x"}`))
+	req.Header.Set("Authorization", "Bearer client-token")
+	req.Header.Set("Anthropic-Beta", "tools-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	want := `POST /base/v1/messages?beta=true auth="Bearer tok-b" key="" beta="tools-1,oauth-2025-04-20" body={"model":"x"}`
+	if resp.StatusCode != http.StatusOK || string(body) != want {
+		t.Errorf("got %d %s\nwant 200 %s", resp.StatusCode, body, want)
+	}
+	if p.Current() != "b" {
+		t.Errorf("current profile = %q, want b after the failover", p.Current())
+	}
+	if len(*limits) != 1 || (*limits)[0] != "a:rate:120" {
+		t.Errorf("recorded limits = %v", *limits)
+	}
+}
+
+func TestProxySkipsLimitedAndPassesLast429(t *testing.T) {
+	// b and c are known to be limited, so a's 429 is the answer.
+	p, srv, _ := newTestProxy(t, func(profile string, _ time.Time) bool { return profile != "a" })
+
+	resp, err := http.Post(srv.URL+"/v1/messages", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || !strings.Contains(string(body), "rate_limit_error") {
+		t.Errorf("got %d %s, want the upstream 429", resp.StatusCode, body)
+	}
+	if p.Current() != "a" {
+		t.Errorf("current profile = %q, want a", p.Current())
+	}
+}
+
+func TestControlSocket(t *testing.T) {
+	p, _, _ := newTestProxy(t, nil)
+	path := filepath.Join(t.TempDir(), "proxy.sock")
+	ln, err := ListenControl(path)
+	if err != nil {
+		t.Fatalf("ListenControl failed: %v", err)
+	}
+	defer ln.Close()
+	go p.ServeControl(ln)
+
+	if _, err := ListenControl(path); err == nil {
+		t.Error("a second proxy should not take over a live control socket")
+	}
+	if got, err := Control(path, "USE c"); err != nil || got != "c" {
+		t.Errorf("USE c = %q, %v", got, err)
+	}
+	if got, err := Control(path, "STATUS"); err != nil || got != "c" {
+		t.Errorf("STATUS = %q, %v", got, err)
+	}
+	if _, err := Control(path, "USE nobody"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("USE of an unknown profile: %v", err)
+	}
+	if p.Current() != "c" {
+		t.Errorf("current profile = %q, want c", p.Current())
+	}
+}
+
+func TestProxyRefusesForeignHostsAndBrowsers(t *testing.T) {
+	_, srv, _ := newTestProxy(t, nil)
+	_, port, _ := strings.Cut(strings.TrimPrefix(srv.URL, "http://"), ":")
+
+	for _, tt := range []struct {
+		host, origin string
+		want         int
+	}{
+		{"localhost:" + port, "", http.StatusOK},
+		{"[::1]:" + port, "", http.StatusOK},
+		{"attacker.example:" + port, "", http.StatusForbidden},
+		{"127.0.0.1:" + port, "http://attacker.example", http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+"/v1/models", nil)
+		req.Host = tt.host
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("Host %q, Origin %q: status %d, want %d", tt.host, tt.origin, resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package server implements the plumbing of 'cs serve': the Unix socket the
+// local control API listens on, and the server-sent event stream that
+// tells editors and tools about profile switches and expiring tokens.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// serveSocket is the file name of the control API's socket in AppDataDir.
+const serveSocket = "serve.sock"
+
+// keepAlive is how often an idle event stream gets a comment line, so
+// clients and anything in between notice a dead connection.
+const keepAlive = 30 * time.Second
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further ones are dropped for it.
+const subscriberBuffer = 32
+
+// SocketPath returns the path of the control API's Unix socket.
+func SocketPath() (string, error) {
+	base, err := config.AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, serveSocket), nil
+}
+
+// Listen opens the socket at path (mode 0600). It fails if another server
+// is answering there, and replaces a stale socket otherwise.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, 500*time.Millisecond); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("cs serve is already running (socket %s)", path)
+	}
+	return fsutil.ListenUnix(path)
+}
+
+// Event is one server-sent event. Data is sent as JSON.
+type Event struct {
+	Type string
+	Data any
+}
+
+// Hub fans events out to every connected event stream. It is an
+// http.Handler serving the stream.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// NewHub returns a hub without subscribers.
+func NewHub() *Hub {
+	return &Hub{subs: map[chan Event]struct{}{}}
+}
+
+// Publish sends ev to every subscriber. It never blocks: a subscriber
+// whose buffer is full misses the event.
+func (h *Hub) Publish(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now on,
+// and a function that ends the subscription.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+// ServeHTTP streams events as text/event-stream until the client goes
+// away.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	events, cancel := h.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case ev := <-events:
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		flusher.Flush()
+	}
+}
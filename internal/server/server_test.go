@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix socket permissions")
+	}
+	path := filepath.Join(t.TempDir(), "serve.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket mode = %o, want 600", perm)
+	}
+	if _, err := Listen(path); err == nil {
+		t.Error("a second server should not take over a live socket")
+	}
+}
+
+func TestHubStream(t *testing.T) {
+	hub := NewHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	if line, _ := r.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("first line = %q", line)
+	}
+
+	hub.Publish(Event{Type: "switch", Data: SwitchEvent{From: "a", To: "b", At: time.Unix(0, 0).UTC()}})
+	var lines []string
+	for len(lines) < 2 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if lines[0] != "event: switch" {
+		t.Errorf("event line = %q", lines[0])
+	}
+	if want := `data: {"from":"a","to":"b","at":"1970-01-01T00:00:00Z"}`; lines[1] != want {
+		t.Errorf("data line = %q, want %q", lines[1], want)
+	}
+}
+
+func TestTokenState(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		expires time.Time
+		want    string
+	}{
+		{now.Add(time.Hour), TokenValid},
+		{now.Add(10 * time.Minute), TokenExpiring},
+		{now, TokenExpired},
+		{now.Add(-time.Minute), TokenExpired},
+	} {
+		if got := TokenState(tt.expires, now, 15*time.Minute); got != tt.want {
+			t.Errorf("TokenState(%s) = %q, want %q", tt.expires.Sub(now), got, tt.want)
+		}
+	}
+}
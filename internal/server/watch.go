@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/profile"
+)
+
+// Token states reported by token events.
+const (
+	TokenValid    = "valid"
+	TokenExpiring = "expiring"
+	TokenExpired  = "expired"
+)
+
+// SwitchEvent is sent as a "switch" event when the active profile changes,
+// whether through the API, another cs command or 'cs use' in a shell.
+type SwitchEvent struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// TokenEvent is sent as a "token" event when a profile's OAuth token
+// starts expiring, expires, or becomes valid again after a refresh.
+type TokenEvent struct {
+	Profile   string    `json:"profile"`
+	State     string    `json:"state"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ExpiresIn int64     `json:"expires_in_seconds"`
+	At        time.Time `json:"at"`
+}
+
+// Watcher polls config.json and the profiles' credentials and publishes
+// switch and token events to Hub.
+type Watcher struct {
+	Hub *Hub
+	// Warning is how long before its expiry a token counts as expiring.
+	Warning time.Duration
+	// TokenInterval is how often the credentials are read again; expiry
+	// states are recomputed on every poll. A switch also rereads them.
+	TokenInterval time.Duration
+
+	mu       sync.Mutex
+	started  bool
+	active   string
+	checked  time.Time
+	expiries map[string]time.Time
+	states   map[string]string
+}
+
+// Run polls every interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_ = w.Poll(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll publishes what changed since the previous poll. The first poll
+// only records the current state.
+func (w *Watcher) Poll(now time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	switched := w.started && cfg.ActiveProfile != w.active
+	if switched {
+		w.Hub.Publish(Event{Type: "switch", Data: SwitchEvent{From: w.active, To: cfg.ActiveProfile, At: now.UTC()}})
+	}
+	w.active = cfg.ActiveProfile
+
+	if !w.started || switched || now.Sub(w.checked) >= w.TokenInterval {
+		w.expiries = tokenExpiries(cfg)
+		w.checked = now
+	}
+
+	states := make(map[string]string, len(w.expiries))
+	for name, expires := range w.expiries {
+		state := TokenState(expires, now, w.Warning)
+		states[name] = state
+		prev, known := w.states[name]
+		// A newly added profile is only worth an event if it needs attention.
+		if w.started && state != prev && (known || state != TokenValid) {
+			w.Hub.Publish(Event{Type: "token", Data: TokenEvent{
+				Profile:   name,
+				State:     state,
+				ExpiresAt: expires.UTC(),
+				ExpiresIn: int64(expires.Sub(now).Seconds()),
+				At:        now.UTC(),
+			}})
+		}
+	}
+	w.states = states
+	w.started = true
+	return nil
+}
+
+// TokenState classifies a token expiring at expires.
+func TokenState(expires, now time.Time, warning time.Duration) string {
+	switch {
+	case !now.Before(expires):
+		return TokenExpired
+	case expires.Sub(now) <= warning:
+		return TokenExpiring
+	default:
+		return TokenValid
+	}
+}
+
+// tokenExpiries returns when the token of each profile expires, leaving
+// out profiles without an expiring token.
+func tokenExpiries(cfg *config.Config) map[string]time.Time {
+	expiries := map[string]time.Time{}
+	for name, entry := range cfg.Profiles {
+		if entry.IsEnv() {
+			continue
+		}
+		creds, err := profile.CurrentCredentials(cfg, name)
+		if err != nil || creds.ExpiresAt == nil {
+			continue
+		}
+		expiries[name] = *creds.ExpiresAt
+	}
+	return expiries
+}
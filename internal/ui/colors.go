@@ -2,8 +2,10 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"sync"
 )
 
 // Color codes for terminal output.
@@ -20,6 +22,11 @@ const (
 
 var colorEnabled = true
 
+var (
+	outputMu sync.Mutex
+	output   io.Writer // nil means os.Stdout
+)
+
 func init() {
 	// Disable colors on Windows (unless WT_SESSION is set indicating Windows Terminal)
 	if runtime.GOOS == "windows" {
@@ -37,6 +44,32 @@ func SetColorEnabled(enabled bool) {
 	colorEnabled = enabled
 }
 
+// SetOutput sends notices (Success, Warn, Info, Header) and prompts to w
+// instead of stdout, for commands whose stdout carries data, and returns a
+// function that restores the previous destination. Errors always go to
+// stderr.
+func SetOutput(w io.Writer) (restore func()) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	prev := output
+	output = w
+	return func() {
+		outputMu.Lock()
+		defer outputMu.Unlock()
+		output = prev
+	}
+}
+
+// out returns where notices and prompts go.
+func out() io.Writer {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if output == nil {
+		return os.Stdout
+	}
+	return output
+}
+
 // Colorize wraps text with the given color code.
 func Colorize(color, text string) string {
 	if !colorEnabled {
@@ -48,13 +81,13 @@ func Colorize(color, text string) string {
 // Success prints a green checkmark message.
 func Success(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Println(Colorize(Green, "✓ ") + msg)
+	fmt.Fprintln(out(), Colorize(Green, "✓ ")+msg)
 }
 
 // Warn prints a yellow warning message.
 func Warn(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Println(Colorize(Yellow, "⚠ ") + msg)
+	fmt.Fprintln(out(), Colorize(Yellow, "⚠ ")+msg)
 }
 
 // Error prints a red error message.
@@ -66,10 +99,10 @@ func Error(format string, args ...interface{}) {
 // Info prints a blue info message.
 func Info(format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
-	fmt.Println(Colorize(Blue, "ℹ ") + msg)
+	fmt.Fprintln(out(), Colorize(Blue, "ℹ ")+msg)
 }
 
 // Header prints a bold header.
 func Header(text string) {
-	fmt.Println(Colorize(Bold, text))
+	fmt.Fprintln(out(), Colorize(Bold, text))
 }
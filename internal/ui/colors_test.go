@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -36,3 +37,27 @@ func TestSetColorEnabled(t *testing.T) {
 		t.Error("expected colors enabled")
 	}
 }
+
+func TestSetOutput(t *testing.T) {
+	SetColorEnabled(false)
+	defer SetColorEnabled(true)
+
+	var outer, inner bytes.Buffer
+	restore := SetOutput(&outer)
+	Info("one")
+	restoreInner := SetOutput(&inner)
+	Warn("two")
+	restoreInner()
+	Success("three")
+	restore()
+
+	if got := outer.String(); got != "ℹ one\n✓ three\n" {
+		t.Errorf("outer output = %q", got)
+	}
+	if got := inner.String(); got != "⚠ two\n" {
+		t.Errorf("inner output = %q", got)
+	}
+	if out() == &outer {
+		t.Error("restore did not return notices to stdout")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"golang.org/x/term"
 )
 
 // SelectOption represents a choice in the interactive selector.
@@ -21,9 +23,9 @@ func Select(prompt string, options []SelectOption) (string, error) {
 		return "", fmt.Errorf("no options to select from")
 	}
 
-	fmt.Println()
+	fmt.Fprintln(out())
 	Header(prompt)
-	fmt.Println()
+	fmt.Fprintln(out())
 
 	for i, opt := range options {
 		num := fmt.Sprintf("  %d)", i+1)
@@ -31,11 +33,11 @@ func Select(prompt string, options []SelectOption) (string, error) {
 		if opt.Description != "" {
 			label += Colorize(Gray, "  "+opt.Description)
 		}
-		fmt.Printf("%s %s\n", Colorize(Cyan, num), label)
+		fmt.Fprintf(out(), "%s %s\n", Colorize(Cyan, num), label)
 	}
 
-	fmt.Println()
-	fmt.Print(Colorize(Cyan, "  Enter number (1-"+fmt.Sprintf("%d", len(options))+"): "))
+	fmt.Fprintln(out())
+	fmt.Fprint(out(), Colorize(Cyan, "  Enter number (1-"+fmt.Sprintf("%d", len(options))+"): "))
 
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
@@ -54,7 +56,7 @@ func Select(prompt string, options []SelectOption) (string, error) {
 
 // Confirm asks a yes/no question. Returns true for yes.
 func Confirm(prompt string) bool {
-	fmt.Printf("%s [y/N]: ", prompt)
+	fmt.Fprintf(out(), "%s [y/N]: ", prompt)
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
 	if err != nil {
@@ -64,9 +66,43 @@ func Confirm(prompt string) bool {
 	return input == "y" || input == "yes"
 }
 
-// ReadPassword reads a line from stdin (no echo masking in this simple version).
+// Prompt asks for a line of free-form input and returns it trimmed.
+func Prompt(prompt string) (string, error) {
+	fmt.Fprint(out(), prompt)
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("cannot read input: %w", err)
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// IsTerminal reports whether stdin is an interactive terminal.
+func IsTerminal() bool {
+	return IsTerminalFile(os.Stdin)
+}
+
+// IsTerminalFile reports whether f is a terminal.
+func IsTerminalFile(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// ReadPassword reads a secret from stdin. On a terminal the input is not
+// echoed; otherwise (e.g. piped input) a line is read.
 func ReadPassword(prompt string) (string, error) {
-	fmt.Print(prompt)
+	fmt.Fprint(out(), prompt)
+	if IsTerminal() {
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(out())
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
 	if err != nil {
@@ -0,0 +1,163 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// indexVersion changes when the index format does; an index of another
+// version is rebuilt from scratch.
+const indexVersion = 2
+
+// maxFileLimits caps the limit events kept per file; Claude Code retries a
+// rate-limited request many times, and only the latest events matter.
+const maxFileLimits = 20
+
+// Index caches the usage records and limit events read from transcripts
+// and debug logs, so later reads only parse what was appended since.
+// Transcripts that Claude Code has since deleted stay in the index.
+type Index struct {
+	Version int                   `json:"version"`
+	Files   map[string]*fileEntry `json:"files"`
+}
+
+type fileEntry struct {
+	// Profile owns every message in the file; empty for the live Claude
+	// directory, whose messages are attributed by time.
+	Profile string    `json:"profile,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Offset  int64     `json:"offset"`
+	Records []Record  `json:"records,omitempty"`
+
+	Limits []claude.LimitEvent `json:"limits,omitempty"`
+}
+
+// Source is a Claude config directory, whose transcripts are in projects/
+// and debug logs in debug/, and the profile it belongs to ("" for the live
+// one).
+type Source struct {
+	Dir     string
+	Profile string
+}
+
+// LoadIndex reads the index at path. A missing, unreadable or outdated
+// index yields an empty one.
+func LoadIndex(path string) *Index {
+	ix := &Index{Version: indexVersion, Files: map[string]*fileEntry{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ix
+	}
+	var loaded Index
+	if json.Unmarshal(data, &loaded) != nil || loaded.Version != indexVersion || loaded.Files == nil {
+		return ix
+	}
+	return &loaded
+}
+
+// Save writes the index to path.
+func (ix *Index) Save(path string) error {
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("cannot serialize usage index: %w", err)
+	}
+	return fsutil.WriteFile(path, data, 0600)
+}
+
+// Update reads the transcripts and debug logs of each source that are new
+// or have changed since the last update. It reports whether the index
+// changed. A directory reached from several sources, such as a session
+// sharing the live projects/, is read once for the first of them.
+func (ix *Index) Update(sources []Source) (bool, error) {
+	changed := false
+	walked := map[string]bool{}
+	for _, src := range sources {
+		for _, sub := range []struct {
+			dir, ext string
+			debug    bool
+		}{
+			{"projects", ".jsonl", false},
+			{"debug", ".txt", true},
+		} {
+			root, err := filepath.EvalSymlinks(filepath.Join(src.Dir, sub.dir))
+			if err != nil || walked[root] {
+				continue // nothing written yet, or read already
+			}
+			walked[root] = true
+			err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() || !strings.HasSuffix(path, sub.ext) {
+					return nil
+				}
+				info, err := d.Info()
+				if err != nil {
+					return nil
+				}
+				updated, err := ix.updateFile(path, info, src.Profile, sub.debug)
+				if err != nil {
+					return fmt.Errorf("cannot read %s: %w", path, err)
+				}
+				changed = changed || updated
+				return nil
+			})
+			if err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// updateFile brings the entry of one transcript or debug log up to date.
+// Both only grow; a file that shrank has been replaced and is read again.
+func (ix *Index) updateFile(path string, info fs.FileInfo, profile string, debug bool) (bool, error) {
+	entry, ok := ix.Files[path]
+	if ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) && entry.Profile == profile {
+		return false, nil
+	}
+	if !ok || info.Size() < entry.Offset {
+		entry = &fileEntry{}
+		ix.Files[path] = entry
+	}
+	entry.Profile = profile
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(entry.Offset, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	var n int64
+	if debug {
+		var limits []claude.LimitEvent
+		limits, n, err = ParseDebugLog(f, info.ModTime())
+		entry.Limits = append(entry.Limits, limits...)
+	} else {
+		var t Transcript
+		t, n, err = ParseTranscript(f)
+		entry.Records = append(entry.Records, t.Records...)
+		entry.Limits = append(entry.Limits, t.Limits...)
+	}
+	if err != nil {
+		return false, err
+	}
+	if over := len(entry.Limits) - maxFileLimits; over > 0 {
+		entry.Limits = entry.Limits[over:]
+	}
+	entry.Offset += n
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime()
+	return true, nil
+}
@@ -0,0 +1,315 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/claude"
+)
+
+// Ways to group usage in a report.
+const (
+	ByProfile = "profile"
+	ByProject = "project"
+	ByModel   = "model"
+	ByDay     = "day"
+)
+
+// GroupBys lists the supported groupings.
+func GroupBys() []string {
+	return []string{ByProfile, ByProject, ByModel, ByDay}
+}
+
+// Record is the token usage of one assistant message in a transcript.
+type Record struct {
+	ID          string    `json:"id,omitempty"`
+	Time        time.Time `json:"t"`
+	Model       string    `json:"m,omitempty"`
+	Project     string    `json:"p,omitempty"`
+	Input       int64     `json:"in,omitempty"`
+	Output      int64     `json:"out,omitempty"`
+	CacheCreate int64     `json:"cw,omitempty"`
+	CacheRead   int64     `json:"cr,omitempty"`
+}
+
+// transcriptLine is the part of a Claude Code transcript line that carries
+// token usage or an API error.
+type transcriptLine struct {
+	Type       string    `json:"type"`
+	Timestamp  time.Time `json:"timestamp"`
+	Cwd        string    `json:"cwd"`
+	RequestID  string    `json:"requestId"`
+	IsAPIError bool      `json:"isApiErrorMessage"`
+	Message    struct {
+		ID      string          `json:"id"`
+		Model   string          `json:"model"`
+		Content json.RawMessage `json:"content"`
+		Usage   *struct {
+			InputTokens              int64 `json:"input_tokens"`
+			OutputTokens             int64 `json:"output_tokens"`
+			CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+}
+
+// Transcript is what a Claude Code transcript records: token usage per
+// message, and the usage and rate limits Claude Code reported.
+type Transcript struct {
+	Records []Record
+	Limits  []claude.LimitEvent
+}
+
+// ParseTranscript reads a Claude Code transcript (one JSON object per
+// line). It stops before a final line without a newline, which may still be
+// being written, and returns the number of bytes consumed so a later call
+// can resume there.
+func ParseTranscript(r io.Reader) (Transcript, int64, error) {
+	var t Transcript
+	n, err := readLines(r, func(line []byte) {
+		var tl transcriptLine
+		if json.Unmarshal(line, &tl) != nil || tl.Type != "assistant" {
+			return
+		}
+		// Errors are written as messages from a "<synthetic>" model.
+		if tl.IsAPIError || tl.Message.Model == "<synthetic>" {
+			if ev, ok := claude.DetectLimit([]byte(contentText(tl.Message.Content)), tl.Timestamp); ok {
+				t.Limits = append(t.Limits, ev)
+			}
+			return
+		}
+		u := tl.Message.Usage
+		if u == nil {
+			return
+		}
+		rec := Record{
+			Time:        tl.Timestamp,
+			Model:       tl.Message.Model,
+			Project:     tl.Cwd,
+			Input:       u.InputTokens,
+			Output:      u.OutputTokens,
+			CacheCreate: u.CacheCreationInputTokens,
+			CacheRead:   u.CacheReadInputTokens,
+		}
+		if tl.Message.ID != "" || tl.RequestID != "" {
+			rec.ID = tl.Message.ID + ":" + tl.RequestID
+		}
+		t.Records = append(t.Records, rec)
+	})
+	return t, n, err
+}
+
+// ParseDebugLog reads the limit errors from a Claude Code debug log. Lines
+// are dated by their leading timestamp, or else by modTime. Like
+// ParseTranscript it returns the number of bytes consumed.
+func ParseDebugLog(r io.Reader, modTime time.Time) ([]claude.LimitEvent, int64, error) {
+	var limits []claude.LimitEvent
+	n, err := readLines(r, func(line []byte) {
+		text := string(line)
+		if !strings.Contains(strings.ToLower(text), "error") {
+			return
+		}
+		at := modTime
+		if fields := strings.Fields(text); len(fields) > 0 {
+			if t, err := time.Parse(time.RFC3339, fields[0]); err == nil {
+				at = t
+			}
+		}
+		if ev, ok := claude.DetectLimit(line, at); ok {
+			limits = append(limits, ev)
+		}
+	})
+	return limits, n, err
+}
+
+// readLines calls fn for each complete line of r and returns the number of
+// bytes in them.
+func readLines(r io.Reader, fn func(line []byte)) (int64, error) {
+	var consumed int64
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return consumed, nil
+		}
+		if err != nil {
+			return consumed, err
+		}
+		consumed += int64(len(line))
+		fn(line)
+	}
+}
+
+// contentText returns the text of a message's content, which is either a
+// string or a list of blocks.
+func contentText(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return text
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(raw, &blocks) != nil {
+		return ""
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Totals sums the usage of a set of messages.
+type Totals struct {
+	Messages    int   `json:"messages"`
+	Input       int64 `json:"input_tokens"`
+	Output      int64 `json:"output_tokens"`
+	CacheCreate int64 `json:"cache_creation_tokens"`
+	CacheRead   int64 `json:"cache_read_tokens"`
+}
+
+func (t *Totals) add(r Record) {
+	t.Messages++
+	t.Input += r.Input
+	t.Output += r.Output
+	t.CacheCreate += r.CacheCreate
+	t.CacheRead += r.CacheRead
+}
+
+// Group is the usage of the messages sharing a key (a profile, project,
+// model or day).
+type Group struct {
+	Key string `json:"key"`
+	Totals
+}
+
+// Query selects and groups the indexed usage.
+type Query struct {
+	// Since drops messages before it, unless it is zero.
+	Since time.Time
+	// Profile keeps only the messages of this profile, unless empty.
+	Profile string
+	// By is one of ByProfile (the default), ByProject, ByModel or ByDay.
+	By string
+	// Attribute names the profile that sent a message from the live
+	// Claude directory at the given time.
+	Attribute func(time.Time) string
+}
+
+// Report sums the indexed usage matching q per group, sorted by key, and
+// in total. A message found in more than one transcript, or on several
+// lines of one, counts once.
+func (ix *Index) Report(q Query) ([]Group, Totals) {
+	type attributed struct {
+		Record
+		profile string
+	}
+	seen := make(map[string]int)
+	var msgs []attributed
+
+	paths := make([]string, 0, len(ix.Files))
+	for path := range ix.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		entry := ix.Files[path]
+		for _, rec := range entry.Records {
+			if !q.Since.IsZero() && rec.Time.Before(q.Since) {
+				continue
+			}
+			profile := entry.Profile
+			if profile == "" && q.Attribute != nil {
+				profile = q.Attribute(rec.Time)
+			}
+			if q.Profile != "" && profile != q.Profile {
+				continue
+			}
+
+			m := attributed{rec, profile}
+			if rec.ID == "" {
+				msgs = append(msgs, m)
+				continue
+			}
+			// Streamed messages repeat on each content block's line;
+			// keep the most complete one.
+			if i, ok := seen[rec.ID]; ok {
+				if rec.Output > msgs[i].Output {
+					msgs[i] = m
+				}
+				continue
+			}
+			seen[rec.ID] = len(msgs)
+			msgs = append(msgs, m)
+		}
+	}
+
+	groups := make(map[string]*Group)
+	var total Totals
+	for _, m := range msgs {
+		var key string
+		switch q.By {
+		case ByProject:
+			key = m.Project
+		case ByModel:
+			key = m.Model
+		case ByDay:
+			key = m.Time.Local().Format("2006-01-02")
+		default:
+			key = m.profile
+		}
+		if key == "" {
+			key = "(unknown)"
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &Group{Key: key}
+			groups[key] = g
+		}
+		g.add(m.Record)
+		total.add(m.Record)
+	}
+
+	out := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, total
+}
+
+// Limit is a limit event attributed to a profile.
+type Limit struct {
+	Profile string
+	claude.LimitEvent
+}
+
+// Limits returns the limit events found in the indexed files, oldest
+// first. Events from the live Claude directory are attributed to a profile
+// by attribute; those it can't attribute are left out.
+func (ix *Index) Limits(attribute func(time.Time) string) []Limit {
+	var out []Limit
+	for _, entry := range ix.Files {
+		for _, ev := range entry.Limits {
+			profile := entry.Profile
+			if profile == "" && attribute != nil {
+				profile = attribute(ev.HitAt)
+			}
+			if profile != "" {
+				out = append(out, Limit{Profile: profile, LimitEvent: ev})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].HitAt.Before(out[j].HitAt) })
+	return out
+}
@@ -0,0 +1,175 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	lineSonnet = `{"type":"assistant","timestamp":"2026-03-01T10:00:00Z","cwd":"/src/app","requestId":"req_1","message":{"id":"msg_1","model":"claude-sonnet","usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":100}}}`
+	// The same message again, as Claude Code writes it once per content block.
+	lineSonnetAgain = `{"type":"assistant","timestamp":"2026-03-01T10:00:00Z","cwd":"/src/app","requestId":"req_1","message":{"id":"msg_1","model":"claude-sonnet","usage":{"input_tokens":10,"output_tokens":20,"cache_read_input_tokens":100}}}`
+	lineOpus        = `{"type":"assistant","timestamp":"2026-03-02T10:00:00Z","cwd":"/src/lib","requestId":"req_2","message":{"id":"msg_2","model":"claude-opus","usage":{"input_tokens":1000,"output_tokens":500}}}`
+	lineUser        = `{"type":"user","timestamp":"2026-03-01T09:59:00Z","message":{"role":"user","content":"hi"}}`
+	lineLimit       = `{"type":"assistant","timestamp":"2026-03-02T11:00:00Z","isApiErrorMessage":true,"message":{"id":"msg_4","model":"<synthetic>","content":[{"type":"text","text":"Claude AI usage limit reached|1772463600"}],"usage":{"input_tokens":0,"output_tokens":0}}}`
+)
+
+func TestParseTranscript(t *testing.T) {
+	input := lineUser + "\n" + lineSonnet + "\nnot json\n" + lineLimit + "\n" + lineOpus
+	parsed, n, err := ParseTranscript(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseTranscript failed: %v", err)
+	}
+	records := parsed.Records
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1 (the unterminated last line is left for later)", len(records))
+	}
+	if want := int64(len(input) - len(lineOpus)); n != want {
+		t.Errorf("consumed %d bytes, want %d", n, want)
+	}
+	r := records[0]
+	if r.ID != "msg_1:req_1" || r.Model != "claude-sonnet" || r.Project != "/src/app" || r.Input != 10 || r.CacheRead != 100 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if len(parsed.Limits) != 1 {
+		t.Fatalf("got %d limit events, want 1", len(parsed.Limits))
+	}
+	ev := parsed.Limits[0]
+	if ev.Kind != "usage" || !ev.ResetAt.Equal(time.Unix(1772463600, 0)) || !ev.HitAt.Equal(time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected limit event: %+v", ev)
+	}
+}
+
+func TestParseDebugLog(t *testing.T) {
+	modTime := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	input := "2026-03-02T11:30:00.123Z [DEBUG] Stream started\n" +
+		"2026-03-02T11:31:00.000Z [ERROR] API error (attempt 1): 429 {\"type\":\"error\",\"error\":{\"type\":\"rate_limit_error\"}}\n" +
+		"[ERROR] Error: 529 Overloaded\n"
+	limits, _, err := ParseDebugLog(strings.NewReader(input), modTime)
+	if err != nil {
+		t.Fatalf("ParseDebugLog failed: %v", err)
+	}
+	if len(limits) != 2 {
+		t.Fatalf("got %d limit events, want 2: %+v", len(limits), limits)
+	}
+	if limits[0].Kind != "rate" || !limits[0].HitAt.Equal(time.Date(2026, 3, 2, 11, 31, 0, 0, time.UTC)) {
+		t.Errorf("unexpected rate limit event: %+v", limits[0])
+	}
+	if limits[1].Kind != "overloaded" || !limits[1].HitAt.Equal(modTime) {
+		t.Errorf("undated line should be dated by the file: %+v", limits[1])
+	}
+}
+
+func TestIndexUpdateAndReport(t *testing.T) {
+	dir := t.TempDir()
+	live := filepath.Join(dir, "live")
+	session := filepath.Join(dir, "session")
+	for _, d := range []string{filepath.Join(live, "projects", "p"), filepath.Join(live, "debug"), filepath.Join(session, "projects", "p")} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transcript := filepath.Join(live, "projects", "p", "a.jsonl")
+	if err := os.WriteFile(transcript, []byte(lineSonnet+"\n"+lineSonnetAgain+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(session, "projects", "p", "b.jsonl"), []byte(lineOpus+"\n"+lineLimit+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	debugLog := "2026-03-02T10:30:00Z [ERROR] API error: 429 Too Many Requests\n"
+	if err := os.WriteFile(filepath.Join(live, "debug", "s.txt"), []byte(debugLog), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	indexPath := filepath.Join(dir, "index.json")
+	sources := []Source{{Dir: live}, {Dir: session, Profile: "bob"}}
+	ix := LoadIndex(indexPath)
+	if changed, err := ix.Update(sources); err != nil || !changed {
+		t.Fatalf("Update = %v, %v; want changed", changed, err)
+	}
+	if err := ix.Save(indexPath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Appending to a transcript reads only the new lines.
+	f, err := os.OpenFile(transcript, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(strings.Replace(lineOpus, "msg_2", "msg_3", 1) + "\n")
+	f.Close()
+	future := time.Now().Add(time.Minute)
+	_ = os.Chtimes(transcript, future, future)
+
+	ix = LoadIndex(indexPath)
+	if _, err := ix.Update(sources); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if got := len(ix.Files[transcript].Records); got != 3 {
+		t.Errorf("transcript has %d records after the append, want 3", got)
+	}
+
+	attribute := func(time.Time) string { return "alice" }
+	groups, total := ix.Report(Query{By: ByProfile, Attribute: attribute})
+	if len(groups) != 2 || groups[0].Key != "alice" || groups[1].Key != "bob" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if groups[0].Messages != 2 || groups[0].Output != 520 {
+		t.Errorf("alice: %+v, want 2 messages and 520 output tokens (duplicate counted once)", groups[0])
+	}
+	if total.Messages != 3 {
+		t.Errorf("total messages = %d, want 3", total.Messages)
+	}
+
+	since := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	groups, _ = ix.Report(Query{By: ByModel, Since: since, Profile: "bob", Attribute: attribute})
+	if len(groups) != 1 || groups[0].Key != "claude-opus" || groups[0].Messages != 1 {
+		t.Errorf("filtered report: %+v", groups)
+	}
+
+	limits := ix.Limits(attribute)
+	if len(limits) != 2 || limits[0].Profile != "alice" || limits[0].Kind != "rate" || limits[1].Profile != "bob" || limits[1].Kind != "usage" {
+		t.Errorf("unexpected limits: %+v", limits)
+	}
+}
+
+func TestIndexReadsSharedProjectsOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks")
+	}
+	dir := t.TempDir()
+	live := filepath.Join(dir, "live")
+	session := filepath.Join(dir, "session")
+	if err := os.MkdirAll(filepath.Join(live, "projects", "p"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(session, 0700); err != nil {
+		t.Fatal(err)
+	}
+	// The session shares the live transcripts, as the "shared" resource
+	// mode sets it up.
+	if err := os.Symlink(filepath.Join(live, "projects"), filepath.Join(session, "projects")); err != nil {
+		t.Fatal(err)
+	}
+	transcript := filepath.Join(live, "projects", "p", "a.jsonl")
+	if err := os.WriteFile(transcript, []byte(lineSonnet+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := []Source{{Dir: live}, {Dir: session, Profile: "bob"}}
+	ix := LoadIndex(filepath.Join(dir, "index.json"))
+	if _, err := ix.Update(sources); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if changed, err := ix.Update(sources); err != nil || changed {
+		t.Errorf("second Update = %v, %v; want nothing changed", changed, err)
+	}
+	resolved, _ := filepath.EvalSymlinks(transcript)
+	if entry := ix.Files[resolved]; entry == nil || entry.Profile != "" {
+		t.Errorf("shared transcript entry = %+v, want it attributed to the live directory", entry)
+	}
+}
@@ -0,0 +1,152 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/fsutil"
+)
+
+// agentSocket is the file name of the unlock agent's socket in AppDataDir.
+const agentSocket = "vault.sock"
+
+// SocketPath returns the path of the unlock agent's Unix socket.
+func SocketPath() (string, error) {
+	base, err := config.AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, agentSocket), nil
+}
+
+// ServeAgent holds key in memory and hands it to cs processes over a Unix
+// socket (mode 0600) until ttl elapses or a STOP request arrives.
+func ServeAgent(key []byte, ttl time.Duration) error {
+	path, err := SocketPath()
+	if err != nil {
+		return err
+	}
+	if err := config.EnsureDirs(); err != nil {
+		return err
+	}
+
+	ln, err := fsutil.ListenUnix(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	if ttl > 0 {
+		timer := time.AfterFunc(ttl, func() { ln.Close() })
+		defer timer.Stop()
+	}
+
+	encoded := hex.EncodeToString(key)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return nil // listener closed: TTL expired or stopped
+		}
+		if stop := handleAgentConn(conn, encoded); stop {
+			ln.Close()
+			return nil
+		}
+	}
+}
+
+// handleAgentConn answers one request and reports whether the agent should stop.
+func handleAgentConn(conn net.Conn, encodedKey string) bool {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	switch strings.TrimSpace(line) {
+	case "KEY":
+		fmt.Fprintln(conn, encodedKey)
+	case "STOP":
+		fmt.Fprintln(conn, "OK")
+		return true
+	}
+	return false
+}
+
+// agentRequest sends one command to the agent and returns its reply.
+func agentRequest(command string) (string, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return "", err
+	}
+	conn, err := net.DialTimeout("unix", path, 500*time.Millisecond)
+	if err != nil {
+		return "", fmt.Errorf("vault agent is not running")
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("no reply from vault agent: %w", err)
+	}
+	return strings.TrimSpace(reply), nil
+}
+
+// AgentKey fetches the cached key from a running unlock agent.
+func AgentKey() ([]byte, error) {
+	reply, err := agentRequest("KEY")
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(reply)
+}
+
+// AgentRunning reports whether an unlock agent is answering on the socket.
+func AgentRunning() bool {
+	_, err := AgentKey()
+	return err == nil
+}
+
+// StopAgent asks a running unlock agent to exit.
+func StopAgent() error {
+	_, err := agentRequest("STOP")
+	return err
+}
+
+// SpawnAgent starts a detached 'cs vault agent' process holding key and
+// waits until it is ready to answer requests.
+func SpawnAgent(key []byte, ttl time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot determine executable path: %w", err)
+	}
+
+	cmd := exec.Command(exe, "vault", "agent", "--ttl", ttl.String())
+	cmd.Stdin = strings.NewReader(hex.EncodeToString(key) + "\n")
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("cannot start vault agent: %w", err)
+	}
+	_ = cmd.Process.Release()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if AgentRunning() {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("vault agent did not start")
+}
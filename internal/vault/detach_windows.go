@@ -0,0 +1,8 @@
+//go:build windows
+
+package vault
+
+import "os/exec"
+
+// detach is a no-op on Windows, where child processes are not tied to the console session.
+func detach(cmd *exec.Cmd) {}
@@ -0,0 +1,187 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/caeser1996/claude-switch/internal/config"
+	"github.com/caeser1996/claude-switch/internal/crypto"
+)
+
+// PassphraseEnvVar supplies the vault passphrase non-interactively.
+const PassphraseEnvVar = "CS_VAULT_PASSPHRASE"
+
+// ErrLocked is returned when the vault key is needed but cannot be obtained.
+var ErrLocked = errors.New("vault is locked — run 'cs vault unlock' or set " + PassphraseEnvVar)
+
+// verifierPlaintext is encrypted with the vault key to check passphrases.
+var verifierPlaintext = []byte("claude-switch vault v1")
+
+var (
+	mu        sync.Mutex
+	cacheSalt []byte
+	cacheKey  []byte
+	prompt    func() (string, error)
+	promptErr error // first prompt failure; later lookups don't ask again
+)
+
+// SetPrompt installs the function used to ask for the passphrase when no
+// other key source is available. Passing nil disables prompting.
+func SetPrompt(fn func() (string, error)) {
+	mu.Lock()
+	defer mu.Unlock()
+	prompt = fn
+	promptErr = nil
+}
+
+// Create initialises new vault settings protected by passphrase and
+// returns them together with the derived key.
+func Create(passphrase string) (config.VaultSettings, []byte, error) {
+	if passphrase == "" {
+		return config.VaultSettings{}, nil, fmt.Errorf("vault passphrase cannot be empty")
+	}
+
+	salt, err := crypto.NewSalt()
+	if err != nil {
+		return config.VaultSettings{}, nil, err
+	}
+	key, err := crypto.DeriveKey(passphrase, salt)
+	if err != nil {
+		return config.VaultSettings{}, nil, err
+	}
+	verifier, err := crypto.EncryptWithKey(verifierPlaintext, key)
+	if err != nil {
+		return config.VaultSettings{}, nil, err
+	}
+
+	settings := config.VaultSettings{Enabled: true, Salt: salt, Verifier: verifier}
+	remember(settings, key)
+	return settings, key, nil
+}
+
+// Unlock derives the key for passphrase and checks it against the vault's
+// verifier. On success the key is cached for the rest of the process.
+func Unlock(settings config.VaultSettings, passphrase string) ([]byte, error) {
+	if len(settings.Salt) == 0 {
+		return nil, fmt.Errorf("vault is not set up — run 'cs vault enable'")
+	}
+	key, err := crypto.DeriveKey(passphrase, settings.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if !Verify(settings, key) {
+		return nil, fmt.Errorf("wrong vault passphrase")
+	}
+	remember(settings, key)
+	return key, nil
+}
+
+// Verify reports whether key opens the vault described by settings.
+func Verify(settings config.VaultSettings, key []byte) bool {
+	plain, err := crypto.DecryptWithKey(settings.Verifier, key)
+	return err == nil && bytes.Equal(plain, verifierPlaintext)
+}
+
+// Key returns the vault key, trying in order: the in-process cache, the
+// CS_VAULT_PASSPHRASE environment variable, the configured key file, the
+// unlock agent, and finally the interactive prompt.
+func Key(settings config.VaultSettings) ([]byte, error) {
+	if len(settings.Salt) == 0 {
+		return nil, fmt.Errorf("vault is not set up — run 'cs vault enable'")
+	}
+
+	mu.Lock()
+	if bytes.Equal(cacheSalt, settings.Salt) && cacheKey != nil {
+		key := cacheKey
+		mu.Unlock()
+		return key, nil
+	}
+	ask, askErr := prompt, promptErr
+	mu.Unlock()
+
+	if pass := os.Getenv(PassphraseEnvVar); pass != "" {
+		return Unlock(settings, pass)
+	}
+
+	if settings.KeyFile != "" {
+		pass, err := ReadKeyFile(settings.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return Unlock(settings, pass)
+	}
+
+	if key, err := AgentKey(); err == nil && Verify(settings, key) {
+		remember(settings, key)
+		return key, nil
+	}
+
+	if askErr != nil {
+		return nil, askErr
+	}
+	if ask != nil {
+		key, err := promptKey(settings, ask)
+		if err != nil {
+			mu.Lock()
+			promptErr = err
+			mu.Unlock()
+		}
+		return key, err
+	}
+
+	return nil, ErrLocked
+}
+
+func promptKey(settings config.VaultSettings, ask func() (string, error)) ([]byte, error) {
+	pass, err := ask()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read vault passphrase: %w", err)
+	}
+	return Unlock(settings, pass)
+}
+
+// Forget drops the cached key for this process.
+func Forget() {
+	mu.Lock()
+	defer mu.Unlock()
+	cacheSalt, cacheKey = nil, nil
+}
+
+// ReadKeyFile reads a passphrase from a key file, ignoring surrounding whitespace.
+func ReadKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read vault key file: %w", err)
+	}
+	pass := strings.TrimSpace(string(data))
+	if pass == "" {
+		return "", fmt.Errorf("vault key file %s is empty", path)
+	}
+	return pass, nil
+}
+
+// Seal encrypts data with the vault key.
+func Seal(data, key []byte) ([]byte, error) {
+	return crypto.EncryptWithKey(data, key)
+}
+
+// Open decrypts data sealed with the vault key.
+func Open(data, key []byte) ([]byte, error) {
+	return crypto.DecryptWithKey(data, key)
+}
+
+// IsSealed reports whether data was produced by Seal.
+func IsSealed(data []byte) bool {
+	return crypto.IsEncrypted(data)
+}
+
+func remember(settings config.VaultSettings, key []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	cacheSalt = append([]byte(nil), settings.Salt...)
+	cacheKey = key
+}
@@ -0,0 +1,142 @@
+package vault
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCreateAndUnlock(t *testing.T) {
+	defer Forget()
+
+	settings, key, err := Create("correct horse")
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	if !settings.Enabled || len(settings.Salt) == 0 || len(settings.Verifier) == 0 {
+		t.Fatalf("Create() returned incomplete settings: %+v", settings)
+	}
+	if !Verify(settings, key) {
+		t.Error("Verify() rejected the key returned by Create()")
+	}
+
+	Forget()
+	got, err := Unlock(settings, "correct horse")
+	if err != nil {
+		t.Fatalf("Unlock() error: %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Error("Unlock() derived a different key")
+	}
+
+	if _, err := Unlock(settings, "wrong horse"); err == nil {
+		t.Error("Unlock() should reject a wrong passphrase")
+	}
+}
+
+func TestKeySources(t *testing.T) {
+	defer Forget()
+	t.Setenv("HOME", t.TempDir())
+
+	settings, key, err := Create("from-env-pass")
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+
+	t.Run("env", func(t *testing.T) {
+		Forget()
+		t.Setenv(PassphraseEnvVar, "from-env-pass")
+		got, err := Key(settings)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Fatalf("Key() = %x, %v; want env-derived key", got, err)
+		}
+	})
+
+	t.Run("key file", func(t *testing.T) {
+		Forget()
+		path := filepath.Join(t.TempDir(), "vault.key")
+		if err := os.WriteFile(path, []byte("from-env-pass\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		withFile := settings
+		withFile.KeyFile = path
+		got, err := Key(withFile)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Fatalf("Key() = %x, %v; want key-file-derived key", got, err)
+		}
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		Forget()
+		SetPrompt(func() (string, error) { return "from-env-pass", nil })
+		defer SetPrompt(nil)
+		got, err := Key(settings)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Fatalf("Key() = %x, %v; want prompted key", got, err)
+		}
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		Forget()
+		if _, err := Key(settings); err != ErrLocked {
+			t.Fatalf("Key() error = %v, want ErrLocked", err)
+		}
+	})
+}
+
+func TestAgent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not used on windows")
+	}
+	// Keep the socket path short enough for sun_path limits.
+	home, err := os.MkdirTemp("/tmp", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	t.Setenv("HOME", home)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	done := make(chan error, 1)
+	go func() { done <- ServeAgent(key, time.Minute) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !AgentRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("agent did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	path, _ := SocketPath()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("socket missing: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket mode = %o, want 0600", perm)
+	}
+
+	got, err := AgentKey()
+	if err != nil || !bytes.Equal(got, key) {
+		t.Fatalf("AgentKey() = %x, %v", got, err)
+	}
+
+	if err := StopAgent(); err != nil {
+		t.Fatalf("StopAgent() error: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeAgent() error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("agent did not stop")
+	}
+	if AgentRunning() {
+		t.Error("agent still answering after STOP")
+	}
+}